@@ -0,0 +1,45 @@
+package audit
+
+import "context"
+
+// Actor types
+const (
+	ActorUser      = "user"
+	ActorAPIKey    = "api_key"
+	ActorAnonymous = "anonymous"
+	// ActorSystem is recorded for changes made outside a request, such as by background jobs
+	// and CLI commands
+	ActorSystem = "system"
+)
+
+// contextKey is the request context key the actor is stored under
+type contextKey struct{}
+
+// Actor is who made a change, as recorded in the audit trail. It is derived from the
+// caller in middleware and read by the database layer when a change is recorded.
+type Actor struct {
+	// Type is user, api_key or anonymous
+	Type string
+	// ID is the user's or API key's ID
+	ID uint
+	// Email is the user's email, if known
+	Email string
+	// IP is the client IP the request came from
+	IP string
+}
+
+// WithActor returns a copy of ctx carrying the actor
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, or the system actor for contexts
+// without one
+func ActorFromContext(ctx context.Context) *Actor {
+	if ctx != nil {
+		if actor, ok := ctx.Value(contextKey{}).(*Actor); ok && actor != nil {
+			return actor
+		}
+	}
+	return &Actor{Type: ActorSystem}
+}
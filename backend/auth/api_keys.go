@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from access tokens
+const APIKeyPrefix = "jb_"
+
+// apiKeyDisplayLength is how many characters of a key are stored in the clear to identify it
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// lastUsedResolution limits how often a key's last-used time is written
+const lastUsedResolution = time.Minute
+
+// IsAPIKey reports whether a bearer token is an API key rather than an access token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateAPIKey creates a company-scoped API key and returns it with the plaintext key,
+// which is not stored and can't be retrieved again
+func (s *Service) CreateAPIKey(company, name string, scopes []string, expiresAt *time.Time, createdByID uint) (*database.APIKey, string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := APIKeyPrefix + secret
+
+	key := &database.APIKey{
+		Company:     company,
+		Name:        name,
+		Prefix:      plaintext[:apiKeyDisplayLength],
+		KeyHash:     hashToken(plaintext),
+		Scopes:      strings.Join(scopes, " "),
+		ExpiresAt:   expiresAt,
+		CreatedByID: createdByID,
+	}
+	if err := s.apiKeys.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+// authenticateAPIKey verifies an API key and returns a principal acting for its company
+func (s *Service) authenticateAPIKey(plaintext string) (*Principal, error) {
+	key, err := s.apiKeys.GetAPIKeyByHash(hashToken(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidToken
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		if err := s.apiKeys.TouchAPIKey(key.ID, now); err != nil {
+			logger.Warn("Failed to record API key use", "api_key_id", key.ID, "error", err)
+		}
+	}
+
+	return &Principal{
+		Company:  key.Company,
+		APIKeyID: key.ID,
+		Scopes:   key.ScopeList(),
+	}, nil
+}
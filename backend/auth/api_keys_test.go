@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+)
+
+func TestIsAPIKey(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"jb_abc", true},
+		{"eyJhbGciOiJIUzI1NiJ9.e30.sig", false},
+		{"JB_abc", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsAPIKey(tt.token); got != tt.want {
+			t.Errorf("IsAPIKey(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	principal := &Principal{APIKeyID: 1, Scopes: (&database.APIKey{Scopes: "jobs:read  jobs:write"}).ScopeList()}
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{"jobs:read", true},
+		{"jobs:write", true},
+		{"jobs", false},
+		{"videos:write", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := principal.HasScope(tt.scope); got != tt.want {
+			t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+	if (&Principal{UserID: 1}).IsAPIKey() || !principal.IsAPIKey() {
+		t.Error("IsAPIKey doesn't tell users and API keys apart")
+	}
+}
+
+// TestAPIKeyLifecycle creates, uses and revokes an API key. It needs a PostgreSQL
+// database in TEST_DATABASE_URL.
+func TestAPIKeyLifecycle(t *testing.T) {
+	testDatabase(t)
+	service := testService()
+
+	company := fmt.Sprintf("Key Test %d", time.Now().UnixNano())
+	t.Cleanup(func() { database.DB.Where("company = ?", company).Delete(&database.APIKey{}) })
+
+	key, plaintext, err := service.CreateAPIKey(company, "ATS sync", []string{"jobs:read", "jobs:write"}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAPIKey(plaintext) || !strings.HasPrefix(plaintext, key.Prefix) || len(key.Prefix) != apiKeyDisplayLength {
+		t.Errorf("key %q with prefix %q, want a jb_ key starting with its prefix", plaintext, key.Prefix)
+	}
+
+	// Only the hash is stored, and the JSON form carries neither
+	var stored database.APIKey
+	if err := database.DB.First(&stored, key.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.KeyHash != hashToken(plaintext) {
+		t.Errorf("stored hash = %q, want the SHA-256 of the key", stored.KeyHash)
+	}
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), stored.KeyHash) || !strings.Contains(string(encoded), `"scopes":["jobs:read","jobs:write"]`) {
+		t.Errorf("JSON = %s, want the scopes without the hash", encoded)
+	}
+
+	principal, err := service.Authenticate(plaintext)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.APIKeyID != key.ID || principal.Company != company || principal.UserID != 0 ||
+		!principal.HasScope("jobs:write") || principal.HasScope("videos:write") {
+		t.Errorf("principal = %+v, want key %d of %s with its scopes", principal, key.ID, company)
+	}
+	if err := database.DB.First(&stored, key.ID).Error; err != nil || stored.LastUsedAt == nil {
+		t.Errorf("last used = %v, %v; want the time of use", stored.LastUsedAt, err)
+	}
+
+	tampered := plaintext[:len(plaintext)-1] + "x"
+	if strings.HasSuffix(plaintext, "x") {
+		tampered = plaintext[:len(plaintext)-1] + "y"
+	}
+	for _, token := range []string{tampered, APIKeyPrefix + "unknown"} {
+		if _, err := service.Authenticate(token); err != ErrInvalidToken {
+			t.Errorf("Authenticate(%q) = %v, want ErrInvalidToken", token, err)
+		}
+	}
+
+	// Another company can't revoke the key; its own can, under any spelling of its name
+	keys := database.NewAPIKeyService(database.DB)
+	if err := keys.RevokeAPIKey("Other "+company, key.ID); err == nil {
+		t.Error("another company revoked the key")
+	}
+	if _, err := service.Authenticate(plaintext); err != nil {
+		t.Errorf("Authenticate after a refused revocation = %v", err)
+	}
+	if err := keys.RevokeAPIKey(strings.ToUpper(company), key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if _, err := service.Authenticate(plaintext); err != ErrTokenRevoked {
+		t.Errorf("Authenticate after revocation = %v, want ErrTokenRevoked", err)
+	}
+	if err := keys.RevokeAPIKey(company, key.ID); err == nil {
+		t.Error("revoking a revoked key succeeded")
+	}
+
+	// Expired keys stop working
+	expiresAt := time.Now().Add(time.Hour)
+	_, expiring, err := service.CreateAPIKey(company, "Script", []string{"jobs:read"}, &expiresAt, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(expiring); err != nil {
+		t.Errorf("Authenticate before expiry = %v", err)
+	}
+	if err := database.DB.Model(&database.APIKey{}).Where("key_hash = ?", hashToken(expiring)).
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(expiring); err != ErrInvalidToken {
+		t.Errorf("Authenticate after expiry = %v, want ErrInvalidToken", err)
+	}
+}
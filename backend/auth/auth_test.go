@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+)
+
+// testDatabase connects to and migrates the PostgreSQL database in TEST_DATABASE_URL,
+// skipping the test when it isn't set
+func testDatabase(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := database.ConnectDatabase(dsn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.CloseDatabase() })
+	if err := database.MigrateDatabase(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testService returns a Service on the test database
+func testService() *Service {
+	return NewService(database.NewUserService(database.DB), database.NewTokenService(database.DB), database.NewAPIKeyService(database.DB),
+		database.NewCompanyService(database.DB), NewTokenManager([]byte("secret"), "job-board", time.Minute), time.Hour)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("HashPassword = %q, want argon2id with the default parameters", hash)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"correct horse", true},
+		{"correct horse ", false},
+		{"Correct horse", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if ok, err := VerifyPassword(tt.password, hash); ok != tt.want || err != nil {
+			t.Errorf("VerifyPassword(%q) = %v, %v; want %v", tt.password, ok, err, tt.want)
+		}
+	}
+
+	// Every hash gets its own salt
+	again, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == hash {
+		t.Error("two hashes of the same password are equal")
+	}
+}
+
+func TestVerifyPasswordStoredParameters(t *testing.T) {
+	// A hash made with other parameters, as older accounts may have
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("secret"), salt, 1, 8*1024, 1, 16)
+	hash := fmt.Sprintf("$argon2id$v=%d$m=8192,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	if ok, err := VerifyPassword("secret", hash); !ok || err != nil {
+		t.Errorf("VerifyPassword with stored parameters = %v, %v; want true", ok, err)
+	}
+	if ok, _ := VerifyPassword("other", hash); ok {
+		t.Error("wrong password verified against a hash with stored parameters")
+	}
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	tests := []string{
+		"",
+		"plaintext",
+		"$2a$10$abcdefghijklmnopqrstuv",
+		"$argon2i$v=19$m=65536,t=3,p=2$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=18$m=65536,t=3,p=2$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=x,t=3,p=2$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$not base64!$a2V5a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHQ$not base64!",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHQ",
+	}
+	for _, hash := range tests {
+		if ok, err := VerifyPassword("secret", hash); ok || err != errInvalidHash {
+			t.Errorf("VerifyPassword(%q) = %v, %v; want errInvalidHash", hash, ok, err)
+		}
+	}
+}
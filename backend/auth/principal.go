@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"job-board/backend/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalKey is the gin context key the authenticated principal is stored under
+const PrincipalKey = "principal"
+
+// principalContextKey is the request context key the authenticated principal is stored under
+type principalContextKey struct{}
+
+// Principal is the authenticated caller of a request: either a user authenticated with
+// an access token or an integration authenticated with a company's API key
+type Principal struct {
+	UserID   uint     `json:"userId"`
+	Email    string   `json:"email"`
+	Role     string   `json:"role"`
+	Company  string   `json:"company,omitempty"` // company the user recruits for, if any
+	APIKeyID uint     `json:"apiKeyId,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"` // scopes of the API key
+	// EnrollTwoFactor is set when the user's company requires two-factor authentication
+	// and they haven't enrolled yet; such principals can only enroll
+	EnrollTwoFactor bool      `json:"enrollTwoFactor,omitempty"`
+	TokenID         string    `json:"-"` // jti of the access token
+	ExpiresAt       time.Time `json:"-"` // expiry of the access token
+}
+
+// IsAPIKey reports whether the principal authenticated with an API key
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != 0
+}
+
+// HasScope reports whether the principal's API key was granted a scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal, whose log lines name the
+// user or API key
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	if principal.IsAPIKey() {
+		ctx = logger.WithFields(ctx, "api_key_id", principal.APIKeyID)
+	} else {
+		ctx = logger.WithFields(ctx, "user_id", principal.UserID)
+	}
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, or nil for anonymous requests.
+// This is how GraphQL resolvers access the caller.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// GetPrincipal returns the principal of a gin request, or nil for anonymous requests
+func GetPrincipal(c *gin.Context) *Principal {
+	if value, exists := c.Get(PrincipalKey); exists {
+		if principal, ok := value.(*Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// SetPrincipal stores the principal in both the gin context and the request context
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(PrincipalKey, principal)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+)
+
+var (
+	// ErrInvalidCredentials is returned when an email/password pair doesn't match an account
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrInvalidToken is returned for malformed, expired or unknown tokens
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrTokenRevoked is returned for tokens that have been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+)
+
+// TokenPair is the result of a successful login or refresh
+type TokenPair struct {
+	AccessToken      string `json:"accessToken"`
+	RefreshToken     string `json:"refreshToken"`
+	TokenType        string `json:"tokenType"`
+	ExpiresIn        int64  `json:"expiresIn"`        // access token lifetime in seconds
+	RefreshExpiresIn int64  `json:"refreshExpiresIn"` // refresh token lifetime in seconds
+}
+
+// Service implements password login, token refresh and logout
+type Service struct {
+	users      *database.UserService
+	tokens     *database.TokenService
+	apiKeys    *database.APIKeyService
+	companies  *database.CompanyService
+	manager    *TokenManager
+	refreshTTL time.Duration
+}
+
+// NewService creates a new authentication service
+func NewService(users *database.UserService, tokens *database.TokenService, apiKeys *database.APIKeyService, companies *database.CompanyService, manager *TokenManager, refreshTTL time.Duration) *Service {
+	return &Service{
+		users:      users,
+		tokens:     tokens,
+		apiKeys:    apiKeys,
+		companies:  companies,
+		manager:    manager,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Register creates a new user account
+func (s *Service) Register(email, password, name string) (*database.User, error) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &database.User{Email: email, Name: name, PasswordHash: hash}
+	if err := s.users.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login verifies a user's password and issues a new token pair. Users with two-factor
+// authentication get a challenge instead, to be completed with VerifyTwoFactor.
+func (s *Service) Login(email, password string) (*TokenPair, *TwoFactorChallenge, error) {
+	user, err := s.users.GetUserByEmail(email)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || user.PasswordHash == "" {
+		// Hash anyway so response timing doesn't reveal which emails have accounts.
+		// Accounts provisioned through single sign-on have no password.
+		_, _ = HashPassword(password)
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	ok, err := VerifyPassword(password, user.PasswordHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := s.manager.IssueChallengeToken(user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &TwoFactorChallenge{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresIn:         int64(challengeTTL.Seconds()),
+		}, nil
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.issueTokenPair(user, familyID, nil)
+	return tokens, nil, err
+}
+
+// Refresh exchanges a refresh token for a new token pair. Refresh tokens are single use:
+// presenting one that was already rotated is treated as theft and revokes its whole family.
+func (s *Service) Refresh(refreshToken string) (*TokenPair, error) {
+	stored, err := s.tokens.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	if stored.RevokedAt != nil {
+		logger.Warn("Refresh token reuse detected, revoking token family", "user_id", stored.UserID)
+		if err := s.tokens.RevokeRefreshTokenFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenRevoked
+	}
+
+	user, err := s.users.GetUserByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return s.issueTokenPair(user, stored.FamilyID, stored)
+}
+
+// Authenticate verifies a bearer token, either an access token or an API key, and returns its principal
+func (s *Service) Authenticate(accessToken string) (*Principal, error) {
+	if IsAPIKey(accessToken) {
+		return s.authenticateAPIKey(accessToken)
+	}
+
+	claims, err := s.manager.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.tokens.IsAccessTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{
+		UserID:          uint(userID),
+		Email:           claims.Email,
+		Role:            claims.Role,
+		Company:         claims.Company,
+		EnrollTwoFactor: claims.EnrollTwoFactor,
+		TokenID:         claims.ID,
+		ExpiresAt:       claims.ExpiresAt.Time,
+	}, nil
+}
+
+// Logout revokes the principal's access token and, when given, the refresh token family
+func (s *Service) Logout(principal *Principal, refreshToken string) error {
+	if err := s.tokens.RevokeAccessToken(principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
+	}
+
+	if refreshToken != "" {
+		stored, err := s.tokens.GetRefreshTokenByHash(hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored != nil && stored.UserID == principal.UserID {
+			if err := s.tokens.RevokeRefreshTokenFamily(stored.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Logouts are infrequent, so this is a convenient time to drop expired rows
+	if err := s.tokens.DeleteExpiredTokens(); err != nil {
+		logger.Warn("Failed to delete expired tokens", "error", err)
+	}
+	return nil
+}
+
+// LogoutAll revokes the principal's access token and every refresh token of the user
+func (s *Service) LogoutAll(principal *Principal) error {
+	if err := s.tokens.RevokeAccessToken(principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
+	}
+	return s.tokens.RevokeUserRefreshTokens(principal.UserID)
+}
+
+// issueTokenPair issues an access token and a refresh token in the given family,
+// rotating previous out if it is set
+func (s *Service) issueTokenPair(user *database.User, familyID string, previous *database.RefreshToken) (*TokenPair, error) {
+	enroll, err := s.twoFactorEnrollmentRequired(user)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, _, err := s.manager.IssueAccessToken(user, enroll)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	stored := &database.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+
+	if previous != nil {
+		if err := s.tokens.RotateRefreshToken(previous, stored); err != nil {
+			// Another request rotated the token first
+			return nil, ErrInvalidToken
+		}
+	} else if err := s.tokens.CreateRefreshToken(stored); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(s.manager.TTL().Seconds()),
+		RefreshExpiresIn: int64(s.refreshTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+)
+
+// TestLoginRefreshLogout follows a session from login through refresh token rotation to
+// logout. It needs a PostgreSQL database in TEST_DATABASE_URL.
+func TestLoginRefreshLogout(t *testing.T) {
+	testDatabase(t)
+	service := testService()
+
+	email := fmt.Sprintf("session.%d@example.com", time.Now().UnixNano())
+	user, err := service.Register(email, "correct horse", "Jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cleanups run last first, so the refresh tokens go before their user
+	t.Cleanup(func() { database.DB.Unscoped().Delete(user) })
+	t.Cleanup(func() { database.DB.Unscoped().Delete(&database.RefreshToken{}, "user_id = ?", user.ID) })
+
+	if _, _, err := service.Login(email, "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("Login with a wrong password = %v, want ErrInvalidCredentials", err)
+	}
+	if _, _, err := service.Login("nobody."+email, "correct horse"); err != ErrInvalidCredentials {
+		t.Errorf("Login of an unknown email = %v, want ErrInvalidCredentials", err)
+	}
+	first, challenge, err := service.Login(email, "correct horse")
+	if err != nil || challenge != nil {
+		t.Fatalf("Login = %v, %v; want a token pair", challenge, err)
+	}
+	principal, err := service.Authenticate(first.AccessToken)
+	if err != nil || principal.UserID != user.ID || principal.Role != database.RoleCandidate {
+		t.Fatalf("Authenticate = %+v, %v; want user %d", principal, err, user.ID)
+	}
+
+	// Refreshing rotates the refresh token
+	second, err := service.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh returned the same refresh token")
+	}
+	if _, err := service.Refresh("unknown"); err != ErrInvalidToken {
+		t.Errorf("Refresh of an unknown token = %v, want ErrInvalidToken", err)
+	}
+
+	// Reusing the rotated token revokes its whole family, including the newer token
+	if _, err := service.Refresh(first.RefreshToken); err != ErrTokenRevoked {
+		t.Errorf("reused refresh token = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := service.Refresh(second.RefreshToken); err != ErrTokenRevoked {
+		t.Errorf("refresh token of a revoked family = %v, want ErrTokenRevoked", err)
+	}
+
+	// Logging out revokes the access token and the refresh token's family
+	third, _, err := service.Login(email, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err = service.Authenticate(third.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Logout(principal, third.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := service.Authenticate(third.AccessToken); err != ErrTokenRevoked {
+		t.Errorf("Authenticate after logout = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := service.Refresh(third.RefreshToken); err != ErrTokenRevoked {
+		t.Errorf("Refresh after logout = %v, want ErrTokenRevoked", err)
+	}
+}
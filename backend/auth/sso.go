@@ -0,0 +1,410 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrSSONotConfigured is returned when a company has no enabled SSO provider
+	ErrSSONotConfigured = errors.New("single sign-on is not configured for this company")
+	// ErrSSOInvalidState is returned when a callback doesn't match a sign-in started by this browser
+	ErrSSOInvalidState = errors.New("invalid or expired single sign-on state")
+	// ErrSSOEmailNotAllowed is returned when the identity provider asserts an unverified or disallowed email
+	ErrSSOEmailNotAllowed = errors.New("email is not allowed to sign in to this company")
+	// ErrSSOAccountConflict is returned when the email belongs to an account outside the company
+	ErrSSOAccountConflict = errors.New("an account with this email already exists outside this company")
+	// ErrSSOAccountNotAllowed is returned for existing accounts that may not sign in through
+	// single sign-on: site admins, candidates, and accounts with two-factor authentication
+	// unless the provider is trusted to enforce a second factor
+	ErrSSOAccountNotAllowed = errors.New("this account must sign in with its password")
+	// ErrSSOIssuerNotAllowed is returned for issuer URLs that aren't https or point to a loopback or private address
+	ErrSSOIssuerNotAllowed = errors.New("issuer URL must be an https URL on a public address")
+)
+
+// ssoStateTTL bounds how long a user may take to sign in at the identity provider
+const ssoStateTTL = 10 * time.Minute
+
+// ssoHTTPTimeout bounds requests to identity providers
+const ssoHTTPTimeout = 10 * time.Second
+
+// ssoState is carried through the sign-in in a signed cookie
+type ssoState struct {
+	Company  string `json:"company"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+	jwt.RegisteredClaims
+}
+
+// ssoClaims are the ID token claims used to sign a recruiter in
+type ssoClaims struct {
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// SSOLogin is the start of a sign-in: the browser is sent to AuthURL and must
+// present StateCookie again at the callback
+type SSOLogin struct {
+	AuthURL     string
+	StateCookie string
+}
+
+// SSO signs recruiters in through their company's OpenID Connect identity provider,
+// provisioning their account into the company on first sign-in
+type SSO struct {
+	service     *Service
+	providers   *database.SSOService
+	stateKey    []byte
+	callbackURL string
+	successURL  string
+	client      *http.Client
+	// allowPrivateIssuers lets issuers use http and loopback or private addresses, for development
+	allowPrivateIssuers bool
+
+	mu         sync.Mutex
+	discovered map[string]*oidc.Provider // keyed by issuer URL
+}
+
+// NewSSO creates a new SSO service. callbackURL is the public base URL of the API;
+// each company's redirect URI is <callbackURL>/api/auth/sso/<company>/callback.
+// successURL is where browsers are sent once signed in, if anywhere. Unless
+// allowPrivateIssuers is set, identity providers must be served over https from public
+// addresses, so company admins can't point the server at internal services.
+func NewSSO(service *Service, providers *database.SSOService, secret []byte, callbackURL, successURL string, allowPrivateIssuers bool) *SSO {
+	// Derive a separate key so state cookies can never be mistaken for access tokens
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("sso-state"))
+
+	client := &http.Client{Timeout: ssoHTTPTimeout}
+	if !allowPrivateIssuers {
+		// Checked when connecting, so host names resolving to private addresses are refused too
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{Timeout: ssoHTTPTimeout, Control: refusePrivateAddress}).DialContext
+		client.Transport = transport
+	}
+
+	return &SSO{
+		service:             service,
+		providers:           providers,
+		stateKey:            mac.Sum(nil),
+		callbackURL:         strings.TrimRight(callbackURL, "/"),
+		successURL:          successURL,
+		client:              client,
+		allowPrivateIssuers: allowPrivateIssuers,
+		discovered:          make(map[string]*oidc.Provider),
+	}
+}
+
+// CheckIssuer validates an identity provider's issuer URL and returns it normalized
+func (s *SSO) CheckIssuer(issuerURL string) (string, error) {
+	issuer, err := url.Parse(strings.TrimSpace(issuerURL))
+	if err != nil || issuer.Host == "" {
+		return "", ErrSSOIssuerNotAllowed
+	}
+	if s.allowPrivateIssuers {
+		if issuer.Scheme != "https" && issuer.Scheme != "http" {
+			return "", ErrSSOIssuerNotAllowed
+		}
+		return issuer.String(), nil
+	}
+	if issuer.Scheme != "https" || privateHost(issuer.Hostname()) {
+		return "", ErrSSOIssuerNotAllowed
+	}
+	return issuer.String(), nil
+}
+
+// privateHost reports whether a host name or address is loopback, private or otherwise
+// not reachable on the internet
+func privateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && privateAddr(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't routable on the internet
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func privateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || sharedAddressSpace.Contains(addr)
+}
+
+// refusePrivateAddress stops connections to identity providers on private addresses
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid identity provider address %s: %w", address, err)
+	}
+	if privateAddr(addrPort.Addr()) {
+		return fmt.Errorf("identity provider address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+// StateTTL returns how long a sign-in may take
+func (s *SSO) StateTTL() time.Duration {
+	return ssoStateTTL
+}
+
+// RedirectURI returns the callback URL registered with a company's identity provider
+func (s *SSO) RedirectURI(company string) string {
+	return s.callbackURL + "/api/auth/sso/" + url.PathEscape(company) + "/callback"
+}
+
+// SuccessRedirect returns where to send the browser after signing in, carrying the
+// tokens in the URL fragment so they never reach server logs. It returns false when
+// no success URL is configured.
+func (s *SSO) SuccessRedirect(tokens *TokenPair) (string, bool) {
+	if s.successURL == "" {
+		return "", false
+	}
+	fragment := url.Values{
+		"access_token":  {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"token_type":    {tokens.TokenType},
+		"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+	}
+	return s.successURL + "#" + fragment.Encode(), true
+}
+
+// BeginLogin starts an authorization code flow with PKCE against the company's identity provider
+func (s *SSO) BeginLogin(company string) (*SSOLogin, error) {
+	settings, provider, err := s.provider(company)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginLogin(settings, provider)
+}
+
+func (s *SSO) beginLogin(settings *database.SSOProvider, provider *oidc.Provider) (*SSOLogin, error) {
+	state, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &ssoState{
+		Company:  settings.Company,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ssoStateTTL)),
+		},
+	}).SignedString(s.stateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign SSO state: %w", err)
+	}
+
+	authURL := s.oauthConfig(settings, provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return &SSOLogin{AuthURL: authURL, StateCookie: cookie}, nil
+}
+
+// CompleteLogin exchanges the authorization code, verifies the ID token against the provider's
+// keys and signs the user in, creating a recruiter account for them on first sign-in
+func (s *SSO) CompleteLogin(ctx context.Context, company, code, state, stateCookie string) (*TokenPair, *database.User, error) {
+	saved, err := s.parseState(stateCookie, company, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settings, provider, err := s.provider(company)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims, err := s.exchange(ctx, settings, provider, code, saved)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.provision(settings, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.service.issueTokenPair(user, familyID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// parseState checks that a callback belongs to the sign-in started with the state cookie
+func (s *SSO) parseState(stateCookie, company, state string) (*ssoState, error) {
+	saved := &ssoState{}
+	_, err := jwt.ParseWithClaims(stateCookie, saved, func(*jwt.Token) (interface{}, error) {
+		return s.stateKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || saved.State == "" || !hmac.Equal([]byte(saved.State), []byte(state)) || !strings.EqualFold(saved.Company, company) {
+		return nil, ErrSSOInvalidState
+	}
+	return saved, nil
+}
+
+// exchange redeems the authorization code with the PKCE verifier and returns the claims
+// of the verified ID token
+func (s *SSO) exchange(ctx context.Context, settings *database.SSOProvider, provider *oidc.Provider, code string, saved *ssoState) (*ssoClaims, error) {
+	ctx = oidc.ClientContext(ctx, s.client)
+	token, err := s.oauthConfig(settings, provider).Exchange(ctx, code, oauth2.VerifierOption(saved.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("identity provider did not return an ID token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: settings.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+	if !hmac.Equal([]byte(idToken.Nonce), []byte(saved.Nonce)) {
+		return nil, ErrSSOInvalidState
+	}
+
+	var claims ssoClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to read ID token claims: %w", err)
+	}
+	return &claims, nil
+}
+
+// provision returns the account of the signed-in user, creating a recruiter for the
+// company if there is none. Existing accounts are only signed in if they already
+// belong to the company and ssoAccountAllowed accepts them, so an identity provider
+// can't take over other accounts, and only emails the provider asserts are verified
+// are accepted.
+func (s *SSO) provision(settings *database.SSOProvider, claims *ssoClaims) (*database.User, error) {
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	at := strings.LastIndex(email, "@")
+	if at < 1 || claims.EmailVerified == nil || !*claims.EmailVerified || !settings.DomainAllowed(email[at+1:]) {
+		return nil, ErrSSOEmailNotAllowed
+	}
+
+	user, err := s.service.users.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if !strings.EqualFold(user.Company, settings.Company) {
+			return nil, ErrSSOAccountConflict
+		}
+		if err := ssoAccountAllowed(settings, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = email[:at]
+	}
+	user = &database.User{
+		Email:   email,
+		Name:    name,
+		Role:    database.RoleRecruiter,
+		Company: settings.Company,
+	}
+	if err := s.service.users.CreateUser(user); err != nil {
+		return nil, err
+	}
+	logger.Info("Provisioned recruiter through single sign-on", "user_id", user.ID, "company", user.Company)
+	return user, nil
+}
+
+// ssoAccountAllowed checks that an existing account of the company may sign in through its
+// provider. Only recruiters and company admins may, as whoever controls the provider
+// could otherwise sign in as a site admin tied to the company, and an account with
+// two-factor authentication only may if the provider is trusted to ask for a second factor.
+func ssoAccountAllowed(settings *database.SSOProvider, user *database.User) error {
+	if user.Role != database.RoleRecruiter && user.Role != database.RoleCompanyAdmin {
+		return ErrSSOAccountNotAllowed
+	}
+	if user.TOTPEnabled && !settings.TrustedForMFA {
+		return ErrSSOAccountNotAllowed
+	}
+	return nil
+}
+
+// provider returns a company's enabled SSO settings and its discovered OpenID provider
+func (s *SSO) provider(company string) (*database.SSOProvider, *oidc.Provider, error) {
+	settings, err := s.providers.GetSSOProvider(company)
+	if err != nil {
+		return nil, nil, err
+	}
+	if settings == nil || !settings.Enabled {
+		return nil, nil, ErrSSONotConfigured
+	}
+	provider, err := s.discover(settings.IssuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return settings, provider, nil
+}
+
+// discover returns the OpenID provider of an issuer, fetching its discovery document the
+// first time
+func (s *SSO) discover(issuerURL string) (*oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if provider, ok := s.discovered[issuerURL]; ok {
+		return provider, nil
+	}
+
+	// The provider keeps the context to fetch signing keys later, so it must outlive the request
+	discoveryCtx := oidc.ClientContext(context.Background(), s.client)
+	provider, err := oidc.NewProvider(discoveryCtx, issuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+	s.discovered[issuerURL] = provider
+	return provider, nil
+}
+
+// oauthConfig returns the OAuth 2.0 client configuration for a company's provider
+func (s *SSO) oauthConfig(settings *database.SSOProvider, provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  s.RedirectURI(settings.Company),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+}
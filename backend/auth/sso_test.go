@@ -0,0 +1,436 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID     = "job-board"
+	testClientSecret = "client-secret"
+)
+
+// testIdP is an OpenID Connect provider serving discovery, JWKS, an authorization
+// endpoint that approves every request and a token endpoint that checks the PKCE verifier
+type testIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	// email and emailVerified are the claims put in ID tokens; a nil emailVerified leaves the claim out
+	email         string
+	emailVerified *bool
+
+	mu    sync.Mutex
+	codes map[string]authorizationRequest
+}
+
+type authorizationRequest struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+func newTestIdP(t *testing.T, email string) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := true
+	idp := &testIdP{key: key, email: email, emailVerified: &verified, codes: make(map[string]authorizationRequest)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *testIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	code, _ := randomToken(16)
+	idp.mu.Lock()
+	idp.codes[code] = authorizationRequest{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	idp.mu.Unlock()
+
+	redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	request, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != request.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != request.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   idp.URL,
+		"sub":   "user-1",
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": request.nonce,
+		"email": idp.email,
+		"name":  "Test Recruiter",
+	}
+	if idp.emailVerified != nil {
+		claims["email_verified"] = *idp.emailVerified
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "idp-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// signIn follows a login's redirect to the identity provider and returns the code and
+// state it sends back to the callback
+func (idp *testIdP) signIn(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func testProvider(idp *testIdP, company string) *database.SSOProvider {
+	return &database.SSOProvider{
+		Company:      company,
+		IssuerURL:    idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		Enabled:      true,
+	}
+}
+
+func TestSSOExchangeChecksPKCEVerifier(t *testing.T) {
+	idp := newTestIdP(t, "Jane@Acme.test")
+	sso := NewSSO(nil, nil, []byte("secret"), "http://app.test", "", true)
+	settings := testProvider(idp, "Acme")
+	provider, err := sso.discover(idp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := sso.beginLogin(settings, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.signIn(t, login.AuthURL)
+	saved, err := sso.parseState(login.StateCookie, "acme", state)
+	if err != nil {
+		t.Fatalf("parseState: %v", err)
+	}
+	claims, err := sso.exchange(context.Background(), settings, provider, code, saved)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if claims.Email != "Jane@Acme.test" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// A code redeemed with another sign-in's verifier is refused by the provider
+	login, err = sso.beginLogin(settings, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state = idp.signIn(t, login.AuthURL)
+	saved, err = sso.parseState(login.StateCookie, "acme", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved.Verifier = oauth2.GenerateVerifier()
+	if _, err := sso.exchange(context.Background(), settings, provider, code, saved); err == nil {
+		t.Error("exchange with the wrong verifier succeeded")
+	}
+
+	// Providers that leave out email_verified aren't trusted with the email
+	idp.emailVerified = nil
+	login, err = sso.beginLogin(settings, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state = idp.signIn(t, login.AuthURL)
+	saved, err = sso.parseState(login.StateCookie, "acme", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = sso.exchange(context.Background(), settings, provider, code, saved)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if _, err := sso.provision(settings, claims); err != ErrSSOEmailNotAllowed {
+		t.Errorf("provision without email_verified = %v, want ErrSSOEmailNotAllowed", err)
+	}
+
+	if _, err := sso.parseState(login.StateCookie, "acme", "forged"); err != ErrSSOInvalidState {
+		t.Errorf("parseState with another state = %v, want ErrSSOInvalidState", err)
+	}
+	if _, err := sso.parseState(login.StateCookie, "other", state); err != ErrSSOInvalidState {
+		t.Errorf("parseState for another company = %v, want ErrSSOInvalidState", err)
+	}
+}
+
+func TestSSOProvisionRequiresVerifiedEmail(t *testing.T) {
+	verified, unverified := true, false
+	settings := &database.SSOProvider{Company: "Acme", AllowedDomains: "acme.test"}
+	sso := NewSSO(nil, nil, []byte("secret"), "http://app.test", "", false)
+
+	tests := []struct {
+		name   string
+		claims ssoClaims
+	}{
+		{"missing email_verified", ssoClaims{Email: "jane@acme.test"}},
+		{"unverified email", ssoClaims{Email: "jane@acme.test", EmailVerified: &unverified}},
+		{"domain not allowed", ssoClaims{Email: "jane@other.test", EmailVerified: &verified}},
+		{"no email", ssoClaims{EmailVerified: &verified}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sso.provision(settings, &tt.claims); err != ErrSSOEmailNotAllowed {
+				t.Errorf("provision = %v, want ErrSSOEmailNotAllowed", err)
+			}
+		})
+	}
+
+	// A provider without allowed domains accepts nobody
+	open := &database.SSOProvider{Company: "Acme"}
+	if _, err := sso.provision(open, &ssoClaims{Email: "jane@acme.test", EmailVerified: &verified}); err != ErrSSOEmailNotAllowed {
+		t.Errorf("provision without allowed domains = %v, want ErrSSOEmailNotAllowed", err)
+	}
+}
+
+func TestSSOAccountAllowed(t *testing.T) {
+	tests := []struct {
+		role          string
+		totpEnabled   bool
+		trustedForMFA bool
+		allowed       bool
+	}{
+		{database.RoleRecruiter, false, false, true},
+		{database.RoleCompanyAdmin, false, false, true},
+		{database.RoleSiteAdmin, false, false, false},
+		{database.RoleSiteAdmin, false, true, false},
+		{database.RoleCandidate, false, false, false},
+		{database.RoleRecruiter, true, false, false},
+		{database.RoleCompanyAdmin, true, false, false},
+		{database.RoleRecruiter, true, true, true},
+		{database.RoleSiteAdmin, true, true, false},
+	}
+	for _, tt := range tests {
+		settings := &database.SSOProvider{Company: "Acme", TrustedForMFA: tt.trustedForMFA}
+		user := &database.User{Role: tt.role, Company: "Acme", TOTPEnabled: tt.totpEnabled}
+		err := ssoAccountAllowed(settings, user)
+		if tt.allowed && err != nil {
+			t.Errorf("%s (2FA %v, trusted %v): %v, want allowed", tt.role, tt.totpEnabled, tt.trustedForMFA, err)
+		}
+		if !tt.allowed && err != ErrSSOAccountNotAllowed {
+			t.Errorf("%s (2FA %v, trusted %v): %v, want ErrSSOAccountNotAllowed", tt.role, tt.totpEnabled, tt.trustedForMFA, err)
+		}
+	}
+}
+
+func TestCheckIssuer(t *testing.T) {
+	tests := []struct {
+		issuer       string
+		allowPrivate bool
+		want         string
+	}{
+		{"https://idp.example.com", false, "https://idp.example.com"},
+		{" https://idp.example.com/realms/acme ", false, "https://idp.example.com/realms/acme"},
+		{"http://idp.example.com", false, ""},
+		{"https://localhost:8443", false, ""},
+		{"https://sso.localhost", false, ""},
+		{"https://127.0.0.1", false, ""},
+		{"https://10.0.0.5", false, ""},
+		{"https://192.168.1.10:8443", false, ""},
+		{"https://169.254.169.254", false, ""},
+		{"https://100.64.0.1", false, ""},
+		{"https://[::1]", false, ""},
+		{"https://[fd00::1]", false, ""},
+		{"https://[::ffff:127.0.0.1]", false, ""},
+		{"idp.example.com", false, ""},
+		{"http://localhost:8080", true, "http://localhost:8080"},
+		{"ftp://idp.example.com", true, ""},
+	}
+	for _, tt := range tests {
+		sso := NewSSO(nil, nil, []byte("secret"), "http://app.test", "", tt.allowPrivate)
+		got, err := sso.CheckIssuer(tt.issuer)
+		if tt.want == "" {
+			if err != ErrSSOIssuerNotAllowed {
+				t.Errorf("CheckIssuer(%q, private=%v) = %q, %v; want ErrSSOIssuerNotAllowed", tt.issuer, tt.allowPrivate, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("CheckIssuer(%q, private=%v) = %q, %v; want %q", tt.issuer, tt.allowPrivate, got, err, tt.want)
+		}
+	}
+}
+
+func TestSSORefusesPrivateAddresses(t *testing.T) {
+	idp := newTestIdP(t, "jane@acme.test")
+	sso := NewSSO(nil, nil, []byte("secret"), "http://app.test", "", false)
+	if _, err := sso.discover(idp.URL); err == nil {
+		t.Error("discovery of an identity provider on a loopback address succeeded")
+	}
+}
+
+// TestSSOLoginCallbackSession signs a recruiter in from login through the callback and
+// checks the issued access token authenticates them. It needs a PostgreSQL database in
+// TEST_DATABASE_URL.
+func TestSSOLoginCallbackSession(t *testing.T) {
+	testDatabase(t)
+
+	suffix := time.Now().UnixNano()
+	company := fmt.Sprintf("SSO Test %d", suffix)
+	email := fmt.Sprintf("jane.%d@acme.test", suffix)
+	idp := newTestIdP(t, email)
+
+	service := testService()
+	providers := database.NewSSOService(database.DB)
+	settings := testProvider(idp, company)
+	settings.AllowedDomains = "acme.test"
+	if err := providers.SaveSSOProvider(settings); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = providers.DeleteSSOProvider(company) })
+	sso := NewSSO(service, providers, []byte("secret"), "http://app.test", "", true)
+
+	login, err := sso.BeginLogin(company)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code, state := idp.signIn(t, login.AuthURL)
+	tokens, user, err := sso.CompleteLogin(context.Background(), company, code, state, login.StateCookie)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	// Cleanups run last first, so the refresh tokens go before their user
+	t.Cleanup(func() { database.DB.Unscoped().Delete(&database.User{}, user.ID) })
+	t.Cleanup(func() { database.DB.Unscoped().Delete(&database.RefreshToken{}, "user_id = ?", user.ID) })
+
+	if user.Email != email || user.Company != company || user.Role != database.RoleRecruiter {
+		t.Errorf("provisioned user = %+v", user)
+	}
+	principal, err := service.Authenticate(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.UserID != user.ID || principal.Company != company {
+		t.Errorf("principal = %+v, want user %d of %s", principal, user.ID, company)
+	}
+
+	// The callback can't be replayed with the same code
+	if _, _, err := sso.CompleteLogin(context.Background(), company, code, state, login.StateCookie); err == nil {
+		t.Error("replayed callback succeeded")
+	}
+
+	// Once the account is a site admin, the provider can't sign it in any more
+	if err := database.DB.Model(user).Update("role", database.RoleSiteAdmin).Error; err != nil {
+		t.Fatal(err)
+	}
+	login, err = sso.BeginLogin(company)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code, state = idp.signIn(t, login.AuthURL)
+	if _, _, err := sso.CompleteLogin(context.Background(), company, code, state, login.StateCookie); err != ErrSSOAccountNotAllowed {
+		t.Errorf("CompleteLogin for a site admin = %v, want ErrSSOAccountNotAllowed", err)
+	}
+}
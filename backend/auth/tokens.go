@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"job-board/backend/database"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessClaims are the claims of an access token
+type AccessClaims struct {
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	Company string `json:"company,omitempty"`
+	// EnrollTwoFactor restricts the token to enrolling in two-factor authentication,
+	// which the user's company requires
+	EnrollTwoFactor bool `json:"enroll_2fa,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// challengeClaims are the claims of a two-factor challenge token
+type challengeClaims struct {
+	jwt.RegisteredClaims
+}
+
+// challengeTTL bounds how long a user may take to enter their second factor
+const challengeTTL = 5 * time.Minute
+
+// TokenManager issues and verifies HS256-signed access tokens and two-factor challenge tokens
+type TokenManager struct {
+	secret       []byte
+	challengeKey []byte
+	issuer       string
+	ttl          time.Duration
+}
+
+// NewTokenManager creates a new token manager
+func NewTokenManager(secret []byte, issuer string, ttl time.Duration) *TokenManager {
+	// Challenge tokens use a derived key so they can never pass as access tokens
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("2fa-challenge"))
+	return &TokenManager{secret: secret, challengeKey: mac.Sum(nil), issuer: issuer, ttl: ttl}
+}
+
+// IssueAccessToken issues a signed access token for a user. The user's role and company
+// are embedded so authorization doesn't need a database lookup; role changes take
+// effect when the token is refreshed.
+func (tm *TokenManager) IssueAccessToken(user *database.User, enrollTwoFactor bool) (string, *AccessClaims, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &AccessClaims{
+		Email:           user.Email,
+		Role:            user.Role,
+		Company:         user.Company,
+		EnrollTwoFactor: enrollTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    tm.issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tm.ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(tm.secret)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return signed, claims, nil
+}
+
+// ParseAccessToken verifies an access token's signature, issuer and expiry
+func (tm *TokenManager) ParseAccessToken(token string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return tm.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tm.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// IssueChallengeToken issues a short-lived token proving that a user passed the first
+// factor, to be exchanged for a token pair along with their second factor
+func (tm *TokenManager) IssueChallengeToken(userID uint) (string, error) {
+	now := time.Now()
+	claims := &challengeClaims{jwt.RegisteredClaims{
+		Issuer:    tm.issuer,
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(challengeTTL)),
+	}}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(tm.challengeKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign challenge token: %w", err)
+	}
+	return signed, nil
+}
+
+// ParseChallengeToken verifies a challenge token and returns the user it was issued to
+func (tm *TokenManager) ParseChallengeToken(token string) (uint, error) {
+	claims := &challengeClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return tm.challengeKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tm.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// TTL returns the lifetime of issued access tokens
+func (tm *TokenManager) TTL() time.Duration {
+	return tm.ttl
+}
+
+// randomToken returns n random bytes encoded as URL-safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 of an opaque token, as stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
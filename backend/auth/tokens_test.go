@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAccessToken(t *testing.T) {
+	manager := NewTokenManager([]byte("secret"), "job-board", time.Minute)
+	user := &database.User{ID: 42, Email: "jane@acme.test", Role: database.RoleRecruiter, Company: "Acme"}
+
+	token, issued, err := manager.IssueAccessToken(user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := manager.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken: %v", err)
+	}
+	if claims.Subject != "42" || claims.Email != user.Email || claims.Role != user.Role ||
+		claims.Company != user.Company || !claims.EnrollTwoFactor || claims.ID != issued.ID {
+		t.Errorf("claims = %+v, want those of user 42", claims)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != time.Minute {
+		t.Errorf("token lifetime = %v, want 1m", ttl)
+	}
+
+	// Each token gets its own ID, so it can be revoked on its own
+	other, _, err := manager.IssueAccessToken(user, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherClaims, _ := manager.ParseAccessToken(other); otherClaims == nil || otherClaims.ID == claims.ID {
+		t.Error("two access tokens share an ID")
+	}
+}
+
+func TestParseAccessTokenRejects(t *testing.T) {
+	manager := NewTokenManager([]byte("secret"), "job-board", time.Minute)
+	user := &database.User{ID: 42, Email: "jane@acme.test", Role: database.RoleRecruiter}
+	issue := func(manager *TokenManager) string {
+		token, _, err := manager.IssueAccessToken(user, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	challenge, err := manager.IssueChallengeToken(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same token, with the role in its claims raised to site admin
+	parts := strings.Split(issue(manager), ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload = []byte(strings.Replace(string(payload), `"role":"recruiter"`, `"role":"site_admin"`, 1))
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"other secret", issue(NewTokenManager([]byte("other"), "job-board", time.Minute))},
+		{"other issuer", issue(NewTokenManager([]byte("secret"), "other", time.Minute))},
+		{"expired", issue(NewTokenManager([]byte("secret"), "job-board", -time.Minute))},
+		{"without expiry", sign(jwt.SigningMethodHS256, []byte("secret"), &AccessClaims{
+			Role:             database.RoleSiteAdmin,
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: "job-board", Subject: "42"},
+		})},
+		{"other algorithm", sign(jwt.SigningMethodHS512, []byte("secret"), &AccessClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: "job-board", Subject: "42", ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))},
+		})},
+		{"unsigned", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, &AccessClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: "job-board", Subject: "42", ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))},
+		})},
+		{"tampered claims", tampered},
+		{"challenge token", challenge},
+		{"garbage", "not a token"},
+	}
+	for _, tt := range tests {
+		if claims, err := manager.ParseAccessToken(tt.token); err == nil {
+			t.Errorf("%s: ParseAccessToken = %+v, want an error", tt.name, claims)
+		}
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	manager := NewTokenManager([]byte("secret"), "job-board", time.Minute)
+	token, err := manager.IssueChallengeToken(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := manager.ParseChallengeToken(token); userID != 42 || err != nil {
+		t.Errorf("ParseChallengeToken = %d, %v; want 42", userID, err)
+	}
+
+	// Access tokens and challenges of other secrets don't pass as challenges
+	access, _, err := manager.IssueAccessToken(&database.User{ID: 42}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewTokenManager([]byte("other"), "job-board", time.Minute).IssueChallengeToken(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{access, other} {
+		if _, err := manager.ParseChallengeToken(token); err == nil {
+			t.Errorf("ParseChallengeToken(%q) succeeded", token)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"job-board/backend/database"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code doesn't match
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorNotEnrolled is returned when confirming or using two-factor authentication that wasn't set up
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not set up")
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who already uses two-factor authentication
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorRequired is returned when disabling two-factor authentication the user's company requires
+	ErrTwoFactorRequired = errors.New("two-factor authentication is required by your company")
+	// ErrTwoFactorLocked is returned when too many two-factor codes failed in a row
+	ErrTwoFactorLocked = errors.New("too many failed two-factor codes")
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+// Two-factor codes only have a million values, so a user's verification is locked for
+// twoFactorLockout after maxTwoFactorAttempts codes fail in a row, whatever the challenge
+// or address they come from
+const (
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+// TwoFactorChallenge is returned by Login instead of tokens when the user has two-factor
+// authentication; the challenge token is exchanged for tokens with VerifyTwoFactor
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	ExpiresIn         int64  `json:"expiresIn"` // challenge lifetime in seconds
+}
+
+// TwoFactorEnrollment is a pending TOTP enrollment
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"` // otpauth:// URI to render as a QR code
+}
+
+// EnrollTwoFactor starts TOTP enrollment by generating a new secret. Two-factor authentication
+// is only turned on once a code from the secret is confirmed with ConfirmTwoFactor.
+func (s *Service) EnrollTwoFactor(userID uint) (*TwoFactorEnrollment, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: TOTPProvisioningURI(s.manager.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor turns on two-factor authentication once the user proves their
+// authenticator works, returning their recovery codes
+func (s *Service) ConfirmTwoFactor(userID uint, code string) ([]string, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.EnableTOTP(user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication after verifying a current code
+func (s *Service) DisableTwoFactor(userID uint, code string) error {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	required, err := s.companyRequiresTwoFactor(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return err
+	}
+	return s.users.DisableTOTP(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after verifying a current code
+func (s *Service) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyTwoFactor completes a login challenge with a TOTP or recovery code and issues a new token pair
+func (s *Service) VerifyTwoFactor(challengeToken, code string) (*TokenPair, error) {
+	userID, err := s.manager.ParseChallengeToken(challengeToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.users.GetUserByID(userID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return nil, err
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokenPair(user, familyID, nil)
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code. Each TOTP
+// code and each recovery code is only accepted once, and ErrTwoFactorLocked is returned
+// once too many codes failed in a row.
+func (s *Service) verifySecondFactor(user *database.User, code string) error {
+	claimed, err := s.users.ClaimTOTPAttempt(user.ID, time.Now(), maxTwoFactorAttempts, twoFactorLockout)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrTwoFactorLocked
+	}
+	if err := s.checkSecondFactor(user, code); err != nil {
+		return err
+	}
+	return s.users.ResetTOTPAttempts(user.ID)
+}
+
+// checkSecondFactor checks and uses up a TOTP or recovery code
+func (s *Service) checkSecondFactor(user *database.User, code string) error {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if strings.Contains(code, "-") {
+		used, err := s.users.UseRecoveryCode(user.ID, hashToken(code))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	step, ok := ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	used, err := s.users.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// twoFactorEnrollmentRequired reports whether a user must enroll before doing anything else.
+// Accounts without a password sign in only through their company's identity provider,
+// which is responsible for their second factor.
+func (s *Service) twoFactorEnrollmentRequired(user *database.User) (bool, error) {
+	if user.TOTPEnabled || user.PasswordHash == "" {
+		return false, nil
+	}
+	return s.companyRequiresTwoFactor(user)
+}
+
+// companyRequiresTwoFactor reports whether the user is a member of a company that requires two-factor authentication
+func (s *Service) companyRequiresTwoFactor(user *database.User) (bool, error) {
+	if user.Company == "" || (user.Role != database.RoleRecruiter && user.Role != database.RoleCompanyAdmin) {
+		return false, nil
+	}
+	company, err := s.companies.GetCompanyByName(user.Company)
+	if err != nil {
+		return false, err
+	}
+	return company != nil && company.RequireTwoFactor, nil
+}
+
+// generateRecoveryCodes returns new recovery codes formatted as xxxxx-xxxxx, and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = hashToken(codes[i])
+	}
+	return codes, hashes, nil
+}
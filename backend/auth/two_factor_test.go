@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+)
+
+// totpCodes returns the code currently valid for secret and one that isn't
+func totpCodes(t *testing.T, secret string) (valid, invalid string) {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := time.Now().Unix() / int64(totpPeriod.Seconds())
+	valid = totpCode(key, step)
+	for i := 0; ; i++ {
+		invalid = fmt.Sprintf("%06d", i)
+		if _, ok := ValidateTOTP(secret, invalid, time.Now(), 0); !ok {
+			return valid, invalid
+		}
+	}
+}
+
+// TestVerifyTwoFactorLocksOut checks that failed codes are counted per user across
+// challenges and lock verification. It needs a PostgreSQL database in TEST_DATABASE_URL.
+func TestVerifyTwoFactorLocksOut(t *testing.T) {
+	testDatabase(t)
+	service := testService()
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &database.User{
+		Email:        fmt.Sprintf("totp.%d@example.com", time.Now().UnixNano()),
+		Role:         database.RoleRecruiter,
+		PasswordHash: "unused",
+		TOTPSecret:   secret,
+		TOTPEnabled:  true,
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Cleanups run last first, so the refresh tokens go before their user
+	t.Cleanup(func() { database.DB.Unscoped().Delete(user) })
+	t.Cleanup(func() { database.DB.Unscoped().Delete(&database.RefreshToken{}, "user_id = ?", user.ID) })
+
+	challenge := func() string {
+		token, err := service.manager.IssueChallengeToken(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid, invalid := totpCodes(t, secret)
+
+	// Each guess gets its own challenge, as a distributed guesser's would
+	for i := 0; i < maxTwoFactorAttempts; i++ {
+		if _, err := service.VerifyTwoFactor(challenge(), invalid); err != ErrInvalidTwoFactorCode {
+			t.Fatalf("attempt %d = %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+	if _, err := service.VerifyTwoFactor(challenge(), valid); err != ErrTwoFactorLocked {
+		t.Fatalf("valid code after %d failures = %v, want ErrTwoFactorLocked", maxTwoFactorAttempts, err)
+	}
+	var locked database.User
+	if err := database.DB.First(&locked, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if locked.TOTPLockedUntil == nil || time.Until(*locked.TOTPLockedUntil) < twoFactorLockout-time.Minute {
+		t.Errorf("locked until %v, want about %v from now", locked.TOTPLockedUntil, twoFactorLockout)
+	}
+
+	// Once the lock expires, a valid code is accepted and the count starts over
+	past := time.Now().Add(-time.Second)
+	if err := database.DB.Model(user).Update("totp_locked_until", past).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.VerifyTwoFactor(challenge(), valid); err != nil {
+		t.Fatalf("valid code after the lock expired = %v", err)
+	}
+	var reset database.User
+	if err := database.DB.First(&reset, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reset.TOTPAttempts != 0 || reset.TOTPLockedUntil != nil {
+		t.Errorf("after an accepted code attempts = %d, locked until %v; want both cleared", reset.TOTPAttempts, reset.TOTPLockedUntil)
+	}
+	for i := 0; i < maxTwoFactorAttempts-1; i++ {
+		if _, err := service.VerifyTwoFactor(challenge(), invalid); err != ErrInvalidTwoFactorCode {
+			t.Fatalf("attempt %d after reset = %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+}
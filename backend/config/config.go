@@ -1,22 +1,75 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"time"
+
+	"job-board/backend/features"
+	"job-board/backend/logger"
 )
 
 // Config holds all configuration for our application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	CORS     CORSConfig
-	Video    VideoConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	CORS        CORSConfig
+	Video       VideoConfig
+	Trash       TrashConfig
+	Idempotency IdempotencyConfig
+	Auth        AuthConfig
+	RateLimit   RateLimitConfig
+	Log         LogConfig
+	Features    FeaturesConfig
+	Health      HealthConfig
+	Tracing     TracingConfig
+
+	// file is the config file that was loaded, if any
+	file string
+	// sources records where each setting that isn't a default came from
+	sources map[string]string
+	// args are the command-line flags the configuration was loaded with
+	args []string
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
 	Port string
 	Host string
+	// ReadTimeout limits reading a whole request, including uploaded video files
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout limits reading request headers
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout limits writing a response; 0 means none, which long video streams need
+	WriteTimeout time.Duration
+	// IdleTimeout is how long keep-alive connections wait for the next request
+	IdleTimeout time.Duration
+	// MaxHeaderBytes limits the size of request headers
+	MaxHeaderBytes int
+	// DrainDelay is how long the server keeps serving after reporting it isn't ready, so load
+	// balancers stop sending it requests before it drains
+	DrainDelay time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish before connections are closed
+	ShutdownTimeout time.Duration
+	// TLSCertFile and TLSKeyFile enable HTTPS; the files are reloaded when they change
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSMinVersion is the oldest TLS version accepted: "1.2" or "1.3"
+	TLSMinVersion string
+	// TLSCipherSuites restricts the TLS 1.2 cipher suites, by name; empty means Go's defaults
+	TLSCipherSuites []string
+	// HTTP2 enables HTTP/2 over TLS
+	HTTP2 bool
+	// H2C enables cleartext HTTP/2 when TLS is off, for use behind a proxy that speaks it
+	H2C bool
+	// RedirectHTTPPort, when set, serves redirects from HTTP on this port to HTTPS
+	RedirectHTTPPort string
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose X-Forwarded-For
+	// and X-Real-IP headers name the client; empty trusts none and uses the peer address
+	TrustedProxies []string
+}
+
+// TLSEnabled reports whether the server serves HTTPS
+func (s ServerConfig) TLSEnabled() bool {
+	return s.TLSCertFile != ""
 }
 
 // DatabaseConfig holds database-related configuration
@@ -24,66 +77,232 @@ type DatabaseConfig struct {
 	URL string
 }
 
-// CORSConfig holds CORS-related configuration
+// CORSConfig holds CORS-related configuration. AllowOrigins entries are exact origins,
+// wildcard subdomains such as "https://*.example.com", or "*" for any origin.
 type CORSConfig struct {
 	AllowOrigins     []string
 	AllowMethods     []string
 	AllowHeaders     []string
 	ExposeHeaders    []string
 	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration
 }
 
 // VideoConfig holds video-related configuration
 type VideoConfig struct {
 	Directory string
+	// RetentionPeriod is how long files of soft-deleted videos are kept before being purged
+	RetentionPeriod time.Duration
+	// ReconcileInterval is how often the video reconciler runs (0 disables the periodic job)
+	ReconcileInterval time.Duration
+	// ReconcileFix makes the periodic reconciler repair the problems it finds instead of only reporting them
+	ReconcileFix bool
+	// StorageQuotaBytes is the default per-company storage quota (0 means unlimited)
+	StorageQuotaBytes int64
+	// StorageSoftLimitPercent is the share of the quota after which uploads carry a warning
+	StorageSoftLimitPercent int
+	// UsageFlushInterval is how often metered bandwidth is written to the database
+	UsageFlushInterval time.Duration
+	// StreamRateLimitBytes is the per-connection streaming rate in bytes per second (0 means unlimited)
+	StreamRateLimitBytes int64
+	// StreamBurstBytes is how many bytes a connection may send at once (defaults to the rate)
+	StreamBurstBytes int64
+	// MaxConcurrentStreams caps the number of videos streamed at the same time (0 means unlimited)
+	MaxConcurrentStreams int
+	// StreamRetryAfter is the Retry-After sent when the concurrent stream cap is reached
+	StreamRetryAfter time.Duration
+}
+
+// TrashConfig holds the retention policy of soft-deleted jobs and videos
+type TrashConfig struct {
+	// RetentionPeriod is how long deleted jobs and videos stay in the trash before they're
+	// purged along with their video files (0 keeps them until an admin purges them)
+	RetentionPeriod time.Duration
+	// PurgeInterval is how often jobs and videos past the retention period are purged
+	PurgeInterval time.Duration
+}
+
+// IdempotencyConfig holds the handling of requests made with an Idempotency-Key header
+type IdempotencyConfig struct {
+	// KeyTTL is how long responses are kept for retries with the same key
+	KeyTTL time.Duration
+	// CleanupInterval is how often expired keys are deleted
+	CleanupInterval time.Duration
+}
+
+// AuthConfig holds authentication-related configuration
+type AuthConfig struct {
+	// JWTSecret signs access tokens. When empty a random secret is generated at startup,
+	// which invalidates all access tokens on restart.
+	JWTSecret       string
+	JWTIssuer       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// SSOCallbackURL is the public base URL identity providers redirect back to
+	SSOCallbackURL string
+	// SSOSuccessURL is where browsers are sent after single sign-on, with the tokens in the
+	// URL fragment. When empty the tokens are returned as JSON.
+	SSOSuccessURL string
+	// SSOAllowPrivateIssuers accepts identity providers served over http or from loopback
+	// and private addresses, for trying single sign-on against a local provider
+	SSOAllowPrivateIssuers bool
+}
+
+// RateLimitConfig holds request rate limiting configuration
+type RateLimitConfig struct {
+	Enabled bool
+	// Global applies to every request, per client IP
+	Global RateLimitPolicy
+	// Read applies to API reads, per user, API key or client IP
+	Read RateLimitPolicy
+	// Write applies to API writes, per user, API key or client IP
+	Write RateLimitPolicy
+	// JobPosting applies to creating jobs, on top of Write
+	JobPosting RateLimitPolicy
+	// Auth applies to login, registration, token refresh and 2FA verification, per client IP
+	Auth RateLimitPolicy
+}
+
+// RateLimitPolicy allows Requests per Period with bursts of up to Burst requests
+// (0 means Requests). A policy with no requests doesn't limit anything.
+type RateLimitPolicy struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string
+	// Format is the output format: json or text
+	Format string
+}
+
+// FeaturesConfig holds feature flags
+type FeaturesConfig struct {
+	// Enabled lists the feature flags that are on
+	Enabled []string
+}
+
+// HealthConfig holds readiness check configuration
+type HealthConfig struct {
+	// CacheTTL is how long readiness results are reused, so frequent probes don't hammer the database
+	CacheTTL time.Duration
+	// CheckTimeout is how long each readiness check may take before it fails
+	CheckTimeout time.Duration
+}
+
+// Trace exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// TracingExporters lists the trace exporters that can be configured
+var TracingExporters = []string{TracingExporterNone, TracingExporterOTLP, TracingExporterStdout, TracingExporterFile}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Exporter is where spans are sent: none, otlp, stdout or file
+	Exporter string
+	// OTLPEndpoint is the OTLP/HTTP collector URL, e.g. http://collector:4318; empty uses
+	// the OTEL_EXPORTER_OTLP_* environment variables or the SDK's default
+	OTLPEndpoint string
+	// File is where the file exporter writes spans, one JSON object per line
+	File string
+	// SampleRatio is the fraction of new traces recorded; traces started by a caller
+	// follow the caller's sampling decision
+	SampleRatio float64
+	// ServiceName identifies this service in traces
+	ServiceName string
 }
 
-// LoadConfig loads configuration from environment variables with defaults
-func LoadConfig() *Config {
+// Default returns the built-in configuration that the config file, environment variables
+// and flags are layered on
+func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Host: getEnv("HOST", "localhost"),
+			Port:              "8080",
+			Host:              "localhost",
+			ReadTimeout:       15 * time.Minute,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      0,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			DrainDelay:        0,
+			ShutdownTimeout:   30 * time.Second,
+			TLSMinVersion:     "1.2",
+			HTTP2:             true,
 		},
 		Database: DatabaseConfig{
-			URL: getEnv("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=jobboard port=5432 sslmode=disable TimeZone=America/New_York"),
+			URL: "host=localhost user=postgres dbname=jobboard port=5432 sslmode=disable TimeZone=America/New_York",
 		},
 		CORS: CORSConfig{
-			AllowOrigins:     getEnvSlice("CORS_ALLOW_ORIGINS", []string{"http://localhost:3000"}),
-			AllowMethods:     getEnvSlice("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			AllowHeaders:     getEnvSlice("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization"}),
-			ExposeHeaders:    getEnvSlice("CORS_EXPOSE_HEADERS", []string{"Content-Length"}),
-			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+			AllowOrigins:     []string{"http://localhost:3000"},
+			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "traceparent", "tracestate", "If-Match", "If-None-Match", "Idempotency-Key"},
+			ExposeHeaders:    []string{"Content-Length", "X-Request-ID", "ETag", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "Idempotent-Replayed"},
+			AllowCredentials: true,
+			MaxAge:           12 * time.Hour,
 		},
 		Video: VideoConfig{
-			Directory: getEnv("VIDEO_DIRECTORY", "videos"),
+			Directory:               "videos",
+			RetentionPeriod:         30 * 24 * time.Hour,
+			ReconcileInterval:       24 * time.Hour,
+			ReconcileFix:            false,
+			StorageQuotaBytes:       5 << 30,
+			StorageSoftLimitPercent: 80,
+			UsageFlushInterval:      30 * time.Second,
+			StreamRateLimitBytes:    0,
+			StreamBurstBytes:        0,
+			MaxConcurrentStreams:    0,
+			StreamRetryAfter:        10 * time.Second,
+		},
+		Trash: TrashConfig{
+			RetentionPeriod: 30 * 24 * time.Hour,
+			PurgeInterval:   time.Hour,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:          24 * time.Hour,
+			CleanupInterval: time.Hour,
+		},
+		Auth: AuthConfig{
+			JWTSecret:              "",
+			JWTIssuer:              "job-board",
+			AccessTokenTTL:         15 * time.Minute,
+			RefreshTokenTTL:        30 * 24 * time.Hour,
+			SSOCallbackURL:         "http://localhost:8080",
+			SSOSuccessURL:          "",
+			SSOAllowPrivateIssuers: false,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:    true,
+			Global:     RateLimitPolicy{Requests: 300, Period: time.Minute},
+			Read:       RateLimitPolicy{Requests: 120, Period: time.Minute},
+			Write:      RateLimitPolicy{Requests: 60, Period: time.Minute},
+			JobPosting: RateLimitPolicy{Requests: 10, Period: time.Minute},
+			Auth:       RateLimitPolicy{Requests: 10, Period: time.Minute},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logger.FormatJSON,
+		},
+		Features: FeaturesConfig{
+			Enabled: []string{features.Registration},
+		},
+		Health: HealthConfig{
+			CacheTTL:     5 * time.Second,
+			CheckTimeout: 2 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			File:        "traces.json",
+			SampleRatio: 1,
+			ServiceName: "job-board",
 		},
 	}
 }
-
-// getEnv gets an environment variable with a fallback default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-// getEnvSlice gets an environment variable as a slice with a fallback default value
-func getEnvSlice(key string, defaultValue []string) []string {
-	if value := os.Getenv(key); value != "" {
-		// Simple comma-separated parsing - in production you might want more sophisticated parsing
-		return []string{value}
-	}
-	return defaultValue
-}
-
-// getEnvBool gets an environment variable as a boolean with a fallback default value
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if parsed, err := strconv.ParseBool(value); err == nil {
-			return parsed
-		}
-	}
-	return defaultValue
-}
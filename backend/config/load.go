@@ -0,0 +1,216 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of a setting's value, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Load builds the configuration from the built-in defaults, then the config file named by
+// -config or CONFIG_FILE, then environment variables, then command-line flags. Flags are
+// read from the start of args; the remaining arguments are returned.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	cfg.sources = make(map[string]string)
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("job-board", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	var flagValues []flagValue
+	for _, s := range settings {
+		flags.Var(&recordedFlag{setting: s, values: &flagValues}, s.key, s.usage+" (env "+s.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *file != "" {
+		if err := cfg.loadFile(settings, *file); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cfg.loadEnv(settings); err != nil {
+		return nil, nil, err
+	}
+	for _, fv := range flagValues {
+		if err := fv.setting.value.Set(fv.value); err != nil {
+			return nil, nil, fmt.Errorf("flag -%s: %w", fv.setting.key, err)
+		}
+		cfg.sources[fv.setting.key] = SourceFlag
+	}
+	cfg.args = args[:len(args)-flags.NArg()]
+	return cfg, flags.Args(), nil
+}
+
+// File returns the config file that was loaded, or "" if there was none
+func (c *Config) File() string {
+	return c.file
+}
+
+// Source returns where the setting with the given key got its value
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// flagValue is a flag given on the command line, applied after the other layers
+type flagValue struct {
+	setting setting
+	value   string
+}
+
+// recordedFlag collects a flag's values so they can be applied last
+type recordedFlag struct {
+	setting setting
+	values  *[]flagValue
+}
+
+func (f *recordedFlag) String() string {
+	if f.setting.value == nil {
+		return ""
+	}
+	return f.setting.value.String()
+}
+
+func (f *recordedFlag) Set(s string) error {
+	*f.values = append(*f.values, flagValue{setting: f.setting, value: s})
+	return nil
+}
+
+func (f *recordedFlag) IsBoolFlag() bool {
+	_, ok := f.setting.value.(*boolValue)
+	return ok
+}
+
+// loadFile applies the settings in a YAML or TOML file, chosen by its extension
+func (c *Config) loadFile(settings []setting, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	tree := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]interface{})
+	flatten("", tree, values)
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := setFileValue(s, values[key]); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+		c.sources[key] = SourceFile
+	}
+	c.file = path
+	return nil
+}
+
+// flatten turns nested tables into dotted keys
+func flatten(prefix string, tree map[string]interface{}, values map[string]interface{}) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(key, nested, values)
+			continue
+		}
+		values[key] = value
+	}
+}
+
+// setFileValue sets a setting from a decoded YAML or TOML value
+func setFileValue(s setting, raw interface{}) error {
+	switch v := raw.(type) {
+	case nil:
+		return s.value.Set("")
+	case string:
+		return s.value.Set(v)
+	case float64:
+		return s.value.Set(strconv.FormatFloat(v, 'f', -1, 64))
+	case []interface{}:
+		list, ok := s.value.(*listValue)
+		if !ok {
+			return fmt.Errorf("must not be a list")
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		*list = items
+		return nil
+	default:
+		return s.value.Set(fmt.Sprint(v))
+	}
+}
+
+// loadEnv applies the environment variables that are set. A variable with the _FILE
+// suffix names a file holding the value, which keeps secrets out of the environment.
+func (c *Config) loadEnv(settings []setting) error {
+	for _, s := range settings {
+		value := os.Getenv(s.env)
+		if path := os.Getenv(s.env + "_FILE"); path != "" {
+			if value != "" {
+				return fmt.Errorf("only one of %s and %s_FILE may be set", s.env, s.env)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s_FILE: %w", s.env, err)
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+			if value == "" {
+				return fmt.Errorf("%s_FILE: %s is empty", s.env, path)
+			}
+		}
+		if value == "" {
+			continue
+		}
+
+		if err := s.value.Set(value); err != nil {
+			return fmt.Errorf("environment variable %s: %w", s.env, err)
+		}
+		c.sources[s.key] = SourceEnv
+	}
+	return nil
+}
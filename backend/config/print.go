@@ -0,0 +1,68 @@
+package config
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WriteYAML writes the effective configuration as a YAML config file with secrets redacted.
+// Settings that don't have their default value are commented with where they came from.
+func (c *Config) WriteYAML(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		parent := root
+		parts := strings.Split(s.key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			parent = mappingChild(parent, part)
+		}
+
+		var node *yaml.Node
+		if list, ok := s.value.(*listValue); ok && s.redact == nil {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, item := range *list {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+			}
+		} else {
+			value := s.value.String()
+			if s.redact != nil {
+				value = s.redact(value)
+			}
+			node = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		}
+
+		switch source := c.Source(s.key); source {
+		case SourceEnv:
+			node.LineComment = "from " + s.env
+			if os.Getenv(s.env+"_FILE") != "" {
+				node.LineComment += "_FILE"
+			}
+		case SourceFile:
+			node.LineComment = "from " + c.file
+		case SourceFlag:
+			node.LineComment = "from -" + s.key
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]}, node)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// mappingChild returns the mapping under key in parent, adding it if needed
+func mappingChild(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"job-board/backend/features"
+	"job-board/backend/logger"
+)
+
+// setting is a configuration value that can be set from the config file, an environment
+// variable or a command-line flag
+type setting struct {
+	// key names the setting in config files and as a flag, e.g. "server.port"
+	key string
+	// env is the environment variable, e.g. "PORT"; env+"_FILE" names a file holding the value
+	env   string
+	usage string
+	value value
+	// redact hides secrets when the configuration is printed; nil for settings that aren't secret
+	redact func(string) string
+	// reloadable settings take effect when the configuration is reloaded; others need a restart
+	reloadable bool
+}
+
+// value is a typed configuration field that can be set from text
+type value interface {
+	String() string
+	Set(string) error
+}
+
+// settings lists every setting, bound to the fields of c
+func (c *Config) settings() []setting {
+	settings := []setting{
+		{key: "server.port", env: "PORT", usage: "port to listen on", value: (*stringValue)(&c.Server.Port)},
+		{key: "server.host", env: "HOST", usage: "host to listen on", value: (*stringValue)(&c.Server.Host)},
+		{key: "server.read_timeout", env: "SERVER_READ_TIMEOUT", usage: "time allowed to read a whole request, including uploads", value: (*durationValue)(&c.Server.ReadTimeout)},
+		{key: "server.read_header_timeout", env: "SERVER_READ_HEADER_TIMEOUT", usage: "time allowed to read request headers", value: (*durationValue)(&c.Server.ReadHeaderTimeout)},
+		{key: "server.write_timeout", env: "SERVER_WRITE_TIMEOUT", usage: "time allowed to write a response (0 is unlimited, as video streams need)", value: (*durationValue)(&c.Server.WriteTimeout)},
+		{key: "server.idle_timeout", env: "SERVER_IDLE_TIMEOUT", usage: "how long keep-alive connections wait for the next request", value: (*durationValue)(&c.Server.IdleTimeout)},
+		{key: "server.max_header_bytes", env: "SERVER_MAX_HEADER_BYTES", usage: "maximum size of request headers", value: (*intValue)(&c.Server.MaxHeaderBytes)},
+		{key: "server.drain_delay", env: "SERVER_DRAIN_DELAY", usage: "how long to keep serving after reporting not ready on shutdown", value: (*durationValue)(&c.Server.DrainDelay)},
+		{key: "server.tls_cert_file", env: "TLS_CERT_FILE", usage: "certificate file, enabling HTTPS; reloaded when it changes", value: (*stringValue)(&c.Server.TLSCertFile)},
+		{key: "server.tls_key_file", env: "TLS_KEY_FILE", usage: "private key file of the certificate", value: (*stringValue)(&c.Server.TLSKeyFile)},
+		{key: "server.tls_min_version", env: "TLS_MIN_VERSION", usage: "oldest TLS version accepted: 1.2 or 1.3", value: (*stringValue)(&c.Server.TLSMinVersion)},
+		{key: "server.tls_cipher_suites", env: "TLS_CIPHER_SUITES", usage: "TLS 1.2 cipher suites allowed, by name (Go's defaults when empty)", value: (*listValue)(&c.Server.TLSCipherSuites)},
+		{key: "server.http2", env: "HTTP2", usage: "serve HTTP/2 over TLS", value: (*boolValue)(&c.Server.HTTP2)},
+		{key: "server.h2c", env: "H2C", usage: "serve cleartext HTTP/2 when TLS is off, behind a proxy that speaks it", value: (*boolValue)(&c.Server.H2C)},
+		{key: "server.redirect_http_port", env: "REDIRECT_HTTP_PORT", usage: "port to redirect HTTP requests to HTTPS from (off when empty)", value: (*stringValue)(&c.Server.RedirectHTTPPort)},
+		{key: "server.trusted_proxies", env: "TRUSTED_PROXIES", usage: "addresses or CIDR ranges of proxies trusted to report the client IP (none when empty)", value: (*listValue)(&c.Server.TrustedProxies)},
+		{key: "server.shutdown_timeout", env: "SERVER_SHUTDOWN_TIMEOUT", usage: "time in-flight requests get to finish on shutdown", value: (*durationValue)(&c.Server.ShutdownTimeout)},
+
+		{key: "database.url", env: "DATABASE_URL", usage: "PostgreSQL connection string", value: (*stringValue)(&c.Database.URL), redact: redactDSN},
+
+		{key: "cors.allow_origins", env: "CORS_ALLOW_ORIGINS", usage: "origins allowed to make cross-origin requests", value: (*listValue)(&c.CORS.AllowOrigins), reloadable: true},
+		{key: "cors.allow_methods", env: "CORS_ALLOW_METHODS", usage: "methods allowed in preflight responses", value: (*listValue)(&c.CORS.AllowMethods), reloadable: true},
+		{key: "cors.allow_headers", env: "CORS_ALLOW_HEADERS", usage: "request headers allowed in preflight responses", value: (*listValue)(&c.CORS.AllowHeaders), reloadable: true},
+		{key: "cors.expose_headers", env: "CORS_EXPOSE_HEADERS", usage: "response headers scripts may read", value: (*listValue)(&c.CORS.ExposeHeaders), reloadable: true},
+		{key: "cors.allow_credentials", env: "CORS_ALLOW_CREDENTIALS", usage: "allow cookies and authorization headers", value: (*boolValue)(&c.CORS.AllowCredentials), reloadable: true},
+		{key: "cors.max_age", env: "CORS_MAX_AGE", usage: "how long browsers cache preflight responses", value: (*durationValue)(&c.CORS.MaxAge), reloadable: true},
+
+		{key: "video.directory", env: "VIDEO_DIRECTORY", usage: "directory video files are stored in", value: (*stringValue)(&c.Video.Directory)},
+		{key: "video.retention_days", env: "VIDEO_RETENTION_DAYS", usage: "days files of soft-deleted videos are kept", value: (*daysValue)(&c.Video.RetentionPeriod)},
+		{key: "video.reconcile_interval", env: "VIDEO_RECONCILE_INTERVAL", usage: "how often the video reconciler runs (0 disables it)", value: (*durationValue)(&c.Video.ReconcileInterval)},
+		{key: "video.reconcile_fix", env: "VIDEO_RECONCILE_FIX", usage: "repair problems the reconciler finds", value: (*boolValue)(&c.Video.ReconcileFix)},
+		{key: "video.storage_quota_bytes", env: "VIDEO_STORAGE_QUOTA_BYTES", usage: "default per-company storage quota (0 is unlimited)", value: (*int64Value)(&c.Video.StorageQuotaBytes)},
+		{key: "video.storage_soft_limit_percent", env: "VIDEO_STORAGE_SOFT_LIMIT_PERCENT", usage: "share of the quota that triggers warnings", value: (*intValue)(&c.Video.StorageSoftLimitPercent)},
+		{key: "video.usage_flush_interval", env: "VIDEO_USAGE_FLUSH_INTERVAL", usage: "how often metered bandwidth is written to the database", value: (*durationValue)(&c.Video.UsageFlushInterval)},
+		{key: "video.stream_rate_limit_bytes", env: "VIDEO_STREAM_RATE_LIMIT_BYTES", usage: "per-connection streaming rate in bytes per second (0 is unlimited)", value: (*int64Value)(&c.Video.StreamRateLimitBytes)},
+		{key: "video.stream_burst_bytes", env: "VIDEO_STREAM_BURST_BYTES", usage: "bytes a connection may send at once", value: (*int64Value)(&c.Video.StreamBurstBytes)},
+		{key: "video.max_concurrent_streams", env: "VIDEO_MAX_CONCURRENT_STREAMS", usage: "maximum concurrent streams (0 is unlimited)", value: (*intValue)(&c.Video.MaxConcurrentStreams)},
+		{key: "video.stream_retry_after", env: "VIDEO_STREAM_RETRY_AFTER", usage: "Retry-After sent when the stream cap is reached", value: (*durationValue)(&c.Video.StreamRetryAfter)},
+
+		{key: "trash.retention_days", env: "TRASH_RETENTION_DAYS", usage: "days deleted jobs and videos are kept before being purged (0 keeps them)", value: (*daysValue)(&c.Trash.RetentionPeriod)},
+		{key: "trash.purge_interval", env: "TRASH_PURGE_INTERVAL", usage: "how often expired jobs and videos are purged", value: (*durationValue)(&c.Trash.PurgeInterval)},
+
+		{key: "idempotency.key_ttl", env: "IDEMPOTENCY_KEY_TTL", usage: "how long responses are kept for retries with the same Idempotency-Key", value: (*durationValue)(&c.Idempotency.KeyTTL)},
+		{key: "idempotency.cleanup_interval", env: "IDEMPOTENCY_CLEANUP_INTERVAL", usage: "how often expired Idempotency-Key responses are deleted", value: (*durationValue)(&c.Idempotency.CleanupInterval)},
+
+		{key: "auth.jwt_secret", env: "JWT_SECRET", usage: "secret signing access tokens (random when empty)", value: (*stringValue)(&c.Auth.JWTSecret), redact: redactAll},
+		{key: "auth.jwt_issuer", env: "JWT_ISSUER", usage: "issuer of access tokens", value: (*stringValue)(&c.Auth.JWTIssuer)},
+		{key: "auth.access_token_ttl", env: "ACCESS_TOKEN_TTL", usage: "lifetime of access tokens", value: (*durationValue)(&c.Auth.AccessTokenTTL)},
+		{key: "auth.refresh_token_ttl", env: "REFRESH_TOKEN_TTL", usage: "lifetime of refresh tokens", value: (*durationValue)(&c.Auth.RefreshTokenTTL)},
+		{key: "auth.sso_callback_url", env: "SSO_CALLBACK_URL", usage: "public base URL used in SSO redirect URIs", value: (*stringValue)(&c.Auth.SSOCallbackURL)},
+		{key: "auth.sso_success_url", env: "SSO_SUCCESS_URL", usage: "frontend URL browsers are sent to after SSO", value: (*stringValue)(&c.Auth.SSOSuccessURL)},
+		{key: "auth.sso_allow_private_issuers", env: "SSO_ALLOW_PRIVATE_ISSUERS", usage: "accept http and loopback or private SSO issuers (development only)", value: (*boolValue)(&c.Auth.SSOAllowPrivateIssuers)},
+
+		{key: "rate_limit.enabled", env: "RATE_LIMIT_ENABLED", usage: "limit request rates", value: (*boolValue)(&c.RateLimit.Enabled), reloadable: true},
+
+		{key: "log.level", env: "LOG_LEVEL", usage: "minimum level logged: debug, info, warn or error", value: (*stringValue)(&c.Log.Level), reloadable: true},
+		{key: "log.format", env: "LOG_FORMAT", usage: "log output format: " + strings.Join(logger.Formats, " or "), value: (*stringValue)(&c.Log.Format)},
+
+		{key: "features.enabled", env: "FEATURES_ENABLED", usage: "feature flags that are on: " + strings.Join(features.Known, ", "), value: (*listValue)(&c.Features.Enabled), reloadable: true},
+
+		{key: "health.cache_ttl", env: "HEALTH_CACHE_TTL", usage: "how long readiness results are reused", value: (*durationValue)(&c.Health.CacheTTL)},
+		{key: "health.check_timeout", env: "HEALTH_CHECK_TIMEOUT", usage: "time each readiness check may take", value: (*durationValue)(&c.Health.CheckTimeout)},
+
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", usage: "where spans are sent: " + strings.Join(TracingExporters, ", "), value: (*stringValue)(&c.Tracing.Exporter)},
+		{key: "tracing.otlp_endpoint", env: "TRACING_OTLP_ENDPOINT", usage: "OTLP/HTTP collector URL, e.g. http://collector:4318", value: (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{key: "tracing.file", env: "TRACING_FILE", usage: "file the file exporter writes spans to", value: (*stringValue)(&c.Tracing.File)},
+		{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", usage: "fraction of new traces recorded, from 0 to 1", value: (*floatValue)(&c.Tracing.SampleRatio)},
+		{key: "tracing.service_name", env: "TRACING_SERVICE_NAME", usage: "service name reported in traces", value: (*stringValue)(&c.Tracing.ServiceName)},
+	}
+	settings = append(settings, policySettings("global", &c.RateLimit.Global)...)
+	settings = append(settings, policySettings("read", &c.RateLimit.Read)...)
+	settings = append(settings, policySettings("write", &c.RateLimit.Write)...)
+	settings = append(settings, policySettings("job_posting", &c.RateLimit.JobPosting)...)
+	settings = append(settings, policySettings("auth", &c.RateLimit.Auth)...)
+	return settings
+}
+
+// policySettings returns the settings of a rate limit policy
+func policySettings(name string, p *RateLimitPolicy) []setting {
+	key := "rate_limit." + name + "."
+	env := "RATE_LIMIT_" + strings.ToUpper(name) + "_"
+	return []setting{
+		{key: key + "requests", env: env + "REQUESTS", usage: "requests allowed per period by the " + name + " policy (0 disables it)", value: (*intValue)(&p.Requests), reloadable: true},
+		{key: key + "period", env: env + "PERIOD", usage: "period of the " + name + " policy", value: (*durationValue)(&p.Period), reloadable: true},
+		{key: key + "burst", env: env + "BURST", usage: "requests the " + name + " policy allows at once (0 means requests)", value: (*intValue)(&p.Burst), reloadable: true},
+	}
+}
+
+// redactAll hides a secret entirely
+func redactAll(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+// dsnPassword matches the password in a key/value connection string
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// redactDSN hides the password in a URL or key/value connection string
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}REDACTED")
+}
+
+type stringValue string
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) Set(s string) error {
+	parsed, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", s)
+	}
+	*v = boolValue(parsed)
+	return nil
+}
+
+// IsBoolFlag lets boolean flags be given without a value
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(s string) error {
+	parsed, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = intValue(parsed)
+	return nil
+}
+
+type int64Value int64
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+
+func (v *int64Value) Set(s string) error {
+	parsed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = int64Value(parsed)
+	return nil
+}
+
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'f', -1, 64) }
+
+func (v *floatValue) Set(s string) error {
+	parsed, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v = floatValue(parsed)
+	return nil
+}
+
+type durationValue time.Duration
+
+// String formats the duration without zero trailing units, e.g. "12h" rather than "12h0m0s"
+func (v *durationValue) String() string {
+	s := time.Duration(*v).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func (v *durationValue) Set(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 30s or 24h", s)
+	}
+	*v = durationValue(parsed)
+	return nil
+}
+
+// daysValue is a duration given in whole days
+type daysValue time.Duration
+
+func (v *daysValue) String() string { return strconv.Itoa(int(time.Duration(*v) / (24 * time.Hour))) }
+
+func (v *daysValue) Set(s string) error {
+	parsed, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a number of days", s)
+	}
+	*v = daysValue(time.Duration(parsed) * 24 * time.Hour)
+	return nil
+}
+
+// listValue is a comma-separated list
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+func (v *listValue) Set(s string) error {
+	var values []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*v = values
+	return nil
+}
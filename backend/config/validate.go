@@ -0,0 +1,257 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"job-board/backend/features"
+	"job-board/backend/logger"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks for settings the server can't run with and reports all problems at once.
+func (c *Config) Validate() error {
+	var problems []string
+	problem := func(key, format string, args ...interface{}) {
+		problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		problem("server.port", "must be a number between 1 and 65535, got %q", c.Server.Port)
+	}
+	timeouts := []struct {
+		key     string
+		timeout time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.drain_delay", c.Server.DrainDelay},
+	}
+	for _, t := range timeouts {
+		if t.timeout < 0 {
+			problem(t.key, "must not be negative")
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		problem("server.max_header_bytes", "must be positive")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problem("server.shutdown_timeout", "must be positive")
+	}
+	problems = append(problems, c.Server.tlsProblems()...)
+	if c.Database.URL == "" {
+		problem("database.url", "is required")
+	}
+
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1)); err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			problem("cors.allow_origins", "%q is not an origin such as https://example.com", origin)
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		problem("cors.max_age", "must not be negative")
+	}
+
+	if c.Video.Directory == "" {
+		problem("video.directory", "is required")
+	} else if info, err := os.Stat(c.Video.Directory); err == nil && !info.IsDir() {
+		problem("video.directory", "%s is not a directory", c.Video.Directory)
+	}
+	if c.Video.RetentionPeriod < 0 {
+		problem("video.retention_days", "must not be negative")
+	}
+	if c.Video.ReconcileInterval < 0 {
+		problem("video.reconcile_interval", "must not be negative")
+	}
+	if c.Video.StorageQuotaBytes < 0 {
+		problem("video.storage_quota_bytes", "must not be negative")
+	}
+	if c.Video.StorageSoftLimitPercent < 0 || c.Video.StorageSoftLimitPercent > 100 {
+		problem("video.storage_soft_limit_percent", "must be between 0 and 100")
+	}
+	if c.Video.UsageFlushInterval <= 0 {
+		problem("video.usage_flush_interval", "must be positive")
+	}
+	if c.Video.StreamRateLimitBytes < 0 {
+		problem("video.stream_rate_limit_bytes", "must not be negative")
+	}
+	if c.Video.StreamBurstBytes < 0 {
+		problem("video.stream_burst_bytes", "must not be negative")
+	}
+	if c.Video.MaxConcurrentStreams < 0 {
+		problem("video.max_concurrent_streams", "must not be negative")
+	}
+	if c.Video.StreamRetryAfter < 0 {
+		problem("video.stream_retry_after", "must not be negative")
+	}
+
+	if c.Trash.RetentionPeriod < 0 {
+		problem("trash.retention_days", "must not be negative")
+	}
+	if c.Trash.PurgeInterval <= 0 {
+		problem("trash.purge_interval", "must be positive")
+	}
+
+	if c.Idempotency.KeyTTL <= 0 {
+		problem("idempotency.key_ttl", "must be positive")
+	}
+	if c.Idempotency.CleanupInterval <= 0 {
+		problem("idempotency.cleanup_interval", "must be positive")
+	}
+
+	if c.Auth.AccessTokenTTL <= 0 {
+		problem("auth.access_token_ttl", "must be positive")
+	}
+	if c.Auth.RefreshTokenTTL <= 0 {
+		problem("auth.refresh_token_ttl", "must be positive")
+	}
+	if !absoluteURL(c.Auth.SSOCallbackURL) {
+		problem("auth.sso_callback_url", "must be an absolute http or https URL")
+	}
+	if c.Auth.SSOSuccessURL != "" && !absoluteURL(c.Auth.SSOSuccessURL) {
+		problem("auth.sso_success_url", "must be an absolute http or https URL")
+	}
+
+	policies := []struct {
+		name string
+		p    RateLimitPolicy
+	}{
+		{"global", c.RateLimit.Global},
+		{"read", c.RateLimit.Read},
+		{"write", c.RateLimit.Write},
+		{"job_posting", c.RateLimit.JobPosting},
+		{"auth", c.RateLimit.Auth},
+	}
+	for _, policy := range policies {
+		p := policy.p
+		key := "rate_limit." + policy.name
+		if p.Requests < 0 || p.Burst < 0 {
+			problem(key, "requests and burst must not be negative")
+		}
+		if p.Requests > 0 && p.Period <= 0 {
+			problem(key+".period", "must be positive")
+		}
+	}
+
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		problem("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if !slices.Contains(logger.Formats, c.Log.Format) {
+		problem("log.format", "must be %s, got %q", strings.Join(logger.Formats, " or "), c.Log.Format)
+	}
+	for _, name := range c.Features.Enabled {
+		if !features.IsKnown(name) {
+			problem("features.enabled", "unknown feature flag %q; known flags are %s", name, strings.Join(features.Known, ", "))
+		}
+	}
+
+	if c.Health.CacheTTL < 0 {
+		problem("health.cache_ttl", "must not be negative")
+	}
+	if c.Health.CheckTimeout <= 0 {
+		problem("health.check_timeout", "must be positive")
+	}
+
+	if !slices.Contains(TracingExporters, c.Tracing.Exporter) {
+		problem("tracing.exporter", "must be one of %s, got %q", strings.Join(TracingExporters, ", "), c.Tracing.Exporter)
+	}
+	if c.Tracing.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problem("tracing.otlp_endpoint", "%q is not a URL such as http://collector:4318", c.Tracing.OTLPEndpoint)
+		}
+	}
+	if c.Tracing.Exporter == TracingExporterFile && c.Tracing.File == "" {
+		problem("tracing.file", "is required by the file exporter")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problem("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	if c.Tracing.ServiceName == "" {
+		problem("tracing.service_name", "is required")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// absoluteURL reports whether s is an absolute http or https URL
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// TLSVersions maps the accepted TLSMinVersion values to TLS versions
+var TLSVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// CipherSuite returns the ID of the secure TLS cipher suite with the given name
+func CipherSuite(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// tlsProblems checks the TLS settings
+func (s ServerConfig) tlsProblems() []string {
+	var problems []string
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		problems = append(problems, "server.tls_cert_file: must be set together with server.tls_key_file")
+	} else if s.TLSEnabled() {
+		if _, err := tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile); err != nil {
+			problems = append(problems, fmt.Sprintf("server.tls_cert_file: failed to load certificate: %v", err))
+		}
+	}
+	if _, ok := TLSVersions[s.TLSMinVersion]; !ok {
+		problems = append(problems, fmt.Sprintf("server.tls_min_version: must be 1.2 or 1.3, got %q", s.TLSMinVersion))
+	}
+	for _, name := range s.TLSCipherSuites {
+		if _, ok := CipherSuite(name); !ok {
+			problems = append(problems, fmt.Sprintf("server.tls_cipher_suites: %q is not a secure cipher suite", name))
+		}
+	}
+	for _, proxy := range s.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("server.trusted_proxies: %q is not an IP address or CIDR range", proxy))
+			}
+		}
+	}
+	if s.H2C && s.TLSEnabled() {
+		problems = append(problems, "server.h2c: only applies when TLS is off")
+	}
+	if s.RedirectHTTPPort != "" {
+		if !s.TLSEnabled() {
+			problems = append(problems, "server.redirect_http_port: needs TLS to redirect to")
+		} else if port, err := strconv.Atoi(s.RedirectHTTPPort); err != nil || port < 1 || port > 65535 || s.RedirectHTTPPort == s.Port {
+			problems = append(problems, fmt.Sprintf("server.redirect_http_port: must be a number between 1 and 65535 other than server.port, got %q", s.RedirectHTTPPort))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"job-board/backend/logger"
+)
+
+// Reload loads and validates the configuration again from the same config file,
+// environment and flags
+func (c *Config) Reload() (*Config, error) {
+	cfg, _, err := Load(c.args)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Changes lists the keys of the settings whose values differ in other, split into those
+// that can be reloaded and those that need a restart
+func (c *Config) Changes(other *Config) (reloadable, restart []string) {
+	otherSettings := other.settings()
+	for i, s := range c.settings() {
+		if s.value.String() == otherSettings[i].value.String() {
+			continue
+		}
+		if s.reloadable {
+			reloadable = append(reloadable, s.key)
+		} else {
+			restart = append(restart, s.key)
+		}
+	}
+	return reloadable, restart
+}
+
+// Watcher reloads the configuration when its file changes or the process receives SIGHUP,
+// and hands the new configuration to apply when reloadable settings changed. Changes to
+// other settings are logged as needing a restart.
+type Watcher struct {
+	started *Config
+	current *Config
+	apply   func(*Config)
+	modTime time.Time
+	size    int64
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewWatcher creates a watcher for the configuration the server was started with
+func NewWatcher(cfg *Config, apply func(*Config)) *Watcher {
+	return &Watcher{started: cfg, current: cfg, apply: apply}
+}
+
+// Start checks the config file for changes every interval and listens for SIGHUP
+func (w *Watcher) Start(interval time.Duration) {
+	w.stop = make(chan struct{})
+	w.stopped = make(chan struct{})
+	w.modTime, w.size = fileStamp(w.started.File())
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer close(w.stopped)
+		defer signal.Stop(hangup)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if w.started.File() == "" {
+					continue
+				}
+				modTime, size := fileStamp(w.started.File())
+				if modTime.Equal(w.modTime) && size == w.size {
+					continue
+				}
+				w.modTime, w.size = modTime, size
+				w.reload("config file changed")
+			case <-hangup:
+				w.reload("SIGHUP")
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	logger.Info("Configuration watcher started", "file", w.started.File(), "interval", interval)
+}
+
+// Stop stops watching
+func (w *Watcher) Stop() {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	<-w.stopped
+	w.stop = nil
+}
+
+// reload loads the configuration and applies it, keeping the current one if it's invalid
+func (w *Watcher) reload(reason string) {
+	cfg, err := w.current.Reload()
+	if err != nil {
+		logger.Error("Failed to reload configuration, keeping the current one", "reason", reason, "error", err)
+		return
+	}
+
+	// Compare against the configuration the server started with, so settings that need a
+	// restart keep being reported until it happens
+	if _, restart := w.started.Changes(cfg); len(restart) > 0 {
+		logger.Warn("Configuration changes need a restart to take effect", "settings", restart)
+	}
+
+	reloadable, _ := w.current.Changes(cfg)
+	if len(reloadable) == 0 {
+		logger.Info("Configuration reloaded without changes", "reason", reason)
+		return
+	}
+	w.apply(cfg)
+	w.current = cfg
+	logger.Info("Configuration reloaded", "reason", reason, "settings", reloadable)
+}
+
+// fileStamp returns the modification time and size of a file, or zero values if it can't be read
+func fileStamp(path string) (time.Time, int64) {
+	if path == "" {
+		return time.Time{}, 0
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
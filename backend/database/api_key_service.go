@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyService handles API key database operations
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// CreateAPIKey stores a new API key
+func (s *APIKeyService) CreateAPIKey(key *APIKey) error {
+	if err := s.db.Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeysByCompany retrieves the API keys of a company, including revoked ones
+func (s *APIKeyService) GetAPIKeysByCompany(company string) ([]APIKey, error) {
+	var keys []APIKey
+	err := s.db.Where("LOWER(company) = LOWER(?)", company).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// GetAPIKeyByHash retrieves an API key by its hash, returning nil if there is none
+func (s *APIKeyService) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	var key APIKey
+	err := s.db.Where("key_hash = ?", hash).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey revokes a company's API key
+func (s *APIKeyService) RevokeAPIKey(company string, id uint) error {
+	result := s.db.Model(&APIKey{}).
+		Where("id = ? AND LOWER(company) = LOWER(?) AND revoked_at IS NULL", id, company).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("API key with ID %d not found", id)
+	}
+	return nil
+}
+
+// TouchAPIKey records that an API key was used
+func (s *APIKeyService) TouchAPIKey(id uint, usedAt time.Time) error {
+	if err := s.db.Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error; err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ApplicationService handles job application database operations
+type ApplicationService struct {
+	db *gorm.DB
+}
+
+// NewApplicationService creates a new ApplicationService
+func NewApplicationService(db *gorm.DB) *ApplicationService {
+	return &ApplicationService{db: db}
+}
+
+// GetAllApplications retrieves every application
+func (s *ApplicationService) GetAllApplications(ctx context.Context) ([]Application, error) {
+	var applications []Application
+	err := s.db.WithContext(ctx).Preload("Job").Preload("Candidate").Order("created_at DESC").Find(&applications).Error
+	return applications, err
+}
+
+// GetApplicationsByCandidate retrieves a candidate's applications
+func (s *ApplicationService) GetApplicationsByCandidate(ctx context.Context, candidateID uint) ([]Application, error) {
+	var applications []Application
+	err := s.db.WithContext(ctx).Preload("Job").
+		Where("candidate_id = ?", candidateID).
+		Order("created_at DESC").
+		Find(&applications).Error
+	return applications, err
+}
+
+// GetApplicationsByCompany retrieves the applications to a company's jobs
+func (s *ApplicationService) GetApplicationsByCompany(ctx context.Context, company string) ([]Application, error) {
+	var applications []Application
+	err := s.db.WithContext(ctx).Preload("Job").Preload("Candidate").
+		Joins("JOIN jobs ON jobs.id = applications.job_id AND jobs.deleted_at IS NULL").
+		Where("LOWER(jobs.company) = LOWER(?)", company).
+		Order("applications.created_at DESC").
+		Find(&applications).Error
+	return applications, err
+}
+
+// GetApplicationsByJob retrieves the applications to a job
+func (s *ApplicationService) GetApplicationsByJob(ctx context.Context, jobID uint) ([]Application, error) {
+	var applications []Application
+	err := s.db.WithContext(ctx).Preload("Candidate").
+		Where("job_id = ?", jobID).
+		Order("created_at DESC").
+		Find(&applications).Error
+	return applications, err
+}
+
+// GetApplicationByID retrieves an application with its job and candidate
+func (s *ApplicationService) GetApplicationByID(ctx context.Context, id uint) (*Application, error) {
+	var application Application
+	err := s.db.WithContext(ctx).Preload("Job").Preload("Candidate").First(&application, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("application with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to retrieve application: %w", err)
+	}
+	return &application, nil
+}
+
+// CreateApplication creates a new application
+func (s *ApplicationService) CreateApplication(ctx context.Context, application *Application) error {
+	application.Status = ApplicationSubmitted
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(application).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditActionCreate, AuditEntityApplication, application.ID, nil, application)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create application: %w", err)
+	}
+	return nil
+}
+
+// UpdateApplicationStatus changes the status of an application
+func (s *ApplicationService) UpdateApplicationStatus(ctx context.Context, id uint, status string) error {
+	err := audited(ctx, s.db, AuditActionUpdate, AuditEntityApplication, id, func(tx *gorm.DB, _ *Application) (int64, error) {
+		result := tx.Model(&Application{}).Where("id = ?", id).Update("status", status)
+		return result.RowsAffected, result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("application with ID %d not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update application: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"job-board/backend/audit"
+	"job-board/backend/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	// AuditActionRestore takes a job or video out of the trash
+	AuditActionRestore = "restore"
+	// AuditActionPurge permanently deletes a record in the trash
+	AuditActionPurge = "purge"
+)
+
+// Audited entity types
+const (
+	AuditEntityJob         = "job"
+	AuditEntityVideo       = "video"
+	AuditEntityApplication = "application"
+)
+
+// auditIgnoredFields change on every update, so they're left out of the diff
+var auditIgnoredFields = map[string]bool{"updatedAt": true, "version": true}
+
+// JSONDocument is a JSON value stored in a jsonb column
+type JSONDocument json.RawMessage
+
+// Value stores the document, or NULL if it's empty
+func (d JSONDocument) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+// Scan reads a document from the database
+func (d *JSONDocument) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = nil
+	case []byte:
+		*d = append((*d)[:0], v...)
+	case string:
+		*d = JSONDocument(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON document", value)
+	}
+	return nil
+}
+
+// MarshalJSON writes the document as is, or null if it's empty
+func (d JSONDocument) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
+
+// UnmarshalJSON keeps a copy of the document
+func (d *JSONDocument) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*d = nil
+		return nil
+	}
+	*d = append((*d)[:0], data...)
+	return nil
+}
+
+// auditFields returns an entity's fields as JSON values. Related records, which are JSON
+// objects or lists of objects, are left out since their changes have entries of their own.
+func auditFields(entity interface{}) (map[string]json.RawMessage, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		trimmed := bytes.TrimLeft(value, " \t\r\n")
+		if len(trimmed) > 0 && (trimmed[0] == '{' || bytes.HasPrefix(trimmed, []byte("[{"))) {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// FieldChange is a field's value before and after a change
+type FieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// auditChanges returns the fields that differ between before and after
+func auditChanges(before, after map[string]json.RawMessage) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	null := json.RawMessage("null")
+	compare := func(name string) {
+		if auditIgnoredFields[name] {
+			return
+		}
+		if _, seen := changes[name]; seen {
+			return
+		}
+		from, ok := before[name]
+		if !ok {
+			from = null
+		}
+		to, ok := after[name]
+		if !ok {
+			to = null
+		}
+		if !jsonEqual(from, to) {
+			changes[name] = FieldChange{From: from, To: to}
+		}
+	}
+	for name := range before {
+		compare(name)
+	}
+	for name := range after {
+		compare(name)
+	}
+	return changes
+}
+
+// jsonEqual reports whether two JSON values are equal, regardless of formatting
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// recordAudit appends an entry for a change to the audit trail within tx, naming the actor
+// and request carried by ctx. before is nil for creations and after is nil for deletions.
+func recordAudit(ctx context.Context, tx *gorm.DB, action, entityType string, entityID uint, before, after interface{}) error {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	actor := audit.ActorFromContext(ctx)
+	entry := AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		ActorEmail: actor.Email,
+		RequestID:  logger.RequestID(ctx),
+		IP:         actor.IP,
+		CreatedAt:  time.Now(),
+	}
+	if beforeFields != nil {
+		entry.Before, _ = json.Marshal(beforeFields)
+	}
+	if afterFields != nil {
+		entry.After, _ = json.Marshal(afterFields)
+	}
+	entry.Changes, _ = json.Marshal(auditChanges(beforeFields, afterFields))
+
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// errUnchanged is returned by an audited change that found nothing to change, so
+// there's nothing to record
+var errUnchanged = errors.New("nothing changed")
+
+// audited runs change on the record of type T with the given ID in a transaction and
+// records the record as it was before and after in the audit trail. change gets the
+// record as it was, locked until the transaction ends, and returns the number of rows
+// it affected. gorm.ErrRecordNotFound is returned if the record doesn't exist or change
+// affected no rows. Nothing is recorded if change returns errUnchanged.
+func audited[T any](ctx context.Context, db *gorm.DB, action, entityType string, id uint, change func(tx *gorm.DB, before *T) (int64, error)) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before T
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before, id).Error; err != nil {
+			return err
+		}
+		affected, err := change(tx, &before)
+		if err == errUnchanged {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var after interface{}
+		if action != AuditActionDelete {
+			var current T
+			if err := tx.First(&current, id).Error; err != nil {
+				return err
+			}
+			after = &current
+		}
+		return recordAudit(ctx, tx, action, entityType, id, &before, after)
+	})
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Action     string
+	EntityType string
+	EntityID   uint
+	ActorType  string
+	ActorID    uint
+	RequestID  string
+	// Since and Until bound when the changes were made
+	Since time.Time
+	Until time.Time
+}
+
+// AuditService reads the audit trail
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// query returns a query for the entries matching filter
+func (s *AuditService) query(ctx context.Context, filter AuditFilter) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&AuditEntry{})
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	return query
+}
+
+// GetEntries returns a page of the entries matching filter, newest first, and how many match
+func (s *AuditService) GetEntries(ctx context.Context, filter AuditFilter, page, pageSize int) ([]AuditEntry, int64, error) {
+	var total int64
+	if err := s.query(ctx, filter).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	var entries []AuditEntry
+	err := s.query(ctx, filter).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve audit entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// EachEntry calls fn with every entry matching filter, oldest first, reading them in
+// batches so exports of the whole trail don't load it into memory. It stops at the first
+// error fn returns.
+func (s *AuditService) EachEntry(ctx context.Context, filter AuditFilter, fn func(*AuditEntry) error) error {
+	const batchSize = 500
+	var fnErr error
+	var entries []AuditEntry
+	result := s.query(ctx, filter).Order("id").FindInBatches(&entries, batchSize, func(tx *gorm.DB, batch int) error {
+		for i := range entries {
+			if fnErr = fn(&entries[i]); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to retrieve audit entries: %w", result.Error)
+	}
+	return nil
+}
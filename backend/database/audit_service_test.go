@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"job-board/backend/audit"
+
+	"gorm.io/gorm"
+)
+
+func TestAuditChanges(t *testing.T) {
+	fields := func(doc string) map[string]json.RawMessage {
+		if doc == "" {
+			return nil
+		}
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(doc), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"no change", `{"title":"a","salary":null}`, `{"title":"a","salary":null}`, `{}`},
+		{"changed field", `{"title":"a"}`, `{"title":"b"}`, `{"title":{"from":"a","to":"b"}}`},
+		{"formatting only", `{"tags":["a","b"],"n":1.0}`, `{"tags":[ "a", "b" ],"n":1}`, `{}`},
+		{"ignored fields", `{"version":1,"updatedAt":"x"}`, `{"version":2,"updatedAt":"y"}`, `{}`},
+		{"added field", `{}`, `{"salary":"100"}`, `{"salary":{"from":null,"to":"100"}}`},
+		{"created", ``, `{"title":"a","version":1}`, `{"title":{"from":null,"to":"a"}}`},
+		{"deleted", `{"title":"a"}`, ``, `{"title":{"from":"a","to":null}}`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(auditChanges(fields(tt.before), fields(tt.after)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !jsonEqual(got, json.RawMessage(tt.want)) {
+			t.Errorf("%s: auditChanges = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuditFields(t *testing.T) {
+	salary := "100"
+	job := &Job{ID: 1, Title: "Engineer", Salary: &salary, Requirements: []string{"Go"}, Videos: []Video{{ID: 2}}}
+	fields, err := auditFields(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fields["title"]) != `"Engineer"` || string(fields["salary"]) != `"100"` || string(fields["requirements"]) != `["Go"]` {
+		t.Errorf("fields = %s", fields)
+	}
+	// Videos have entries of their own
+	if _, ok := fields["videos"]; ok {
+		t.Error("related videos included in the job's fields")
+	}
+	if fields, err := auditFields(nil); fields != nil || err != nil {
+		t.Errorf("auditFields(nil) = %v, %v", fields, err)
+	}
+}
+
+func TestJSONDocument(t *testing.T) {
+	if value, err := JSONDocument(nil).Value(); value != nil || err != nil {
+		t.Errorf("empty Value = %v, %v; want NULL", value, err)
+	}
+	if data, _ := json.Marshal(JSONDocument(nil)); string(data) != "null" {
+		t.Errorf("empty document marshals to %s", data)
+	}
+
+	var doc JSONDocument
+	for _, value := range []interface{}{[]byte(`{"a":1}`), `{"a":1}`} {
+		if err := doc.Scan(value); err != nil || string(doc) != `{"a":1}` {
+			t.Errorf("Scan(%T) = %s, %v", value, doc, err)
+		}
+	}
+	if err := doc.Scan(nil); err != nil || doc != nil {
+		t.Errorf("Scan(nil) = %s, %v", doc, err)
+	}
+	if err := doc.Scan(1); err == nil {
+		t.Error("Scan of an int succeeded")
+	}
+
+	var entry struct{ Doc JSONDocument }
+	if err := json.Unmarshal([]byte(`{"Doc":{"b":[1,2]}}`), &entry); err != nil || string(entry.Doc) != `{"b":[1,2]}` {
+		t.Errorf("Unmarshal = %s, %v", entry.Doc, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Doc":null}`), &entry); err != nil || entry.Doc != nil {
+		t.Errorf("Unmarshal of null = %s, %v", entry.Doc, err)
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	db := dryRunDB(t)
+	statements := recordSQL(t, db)
+	filter := AuditFilter{
+		Action:     AuditActionUpdate,
+		EntityType: AuditEntityJob,
+		EntityID:   7,
+		ActorType:  audit.ActorUser,
+		ActorID:    3,
+		RequestID:  "req",
+		Since:      time.Unix(1700000000, 0),
+		Until:      time.Unix(1800000000, 0),
+	}
+	var entries []AuditEntry
+	err := NewAuditService(db).query(context.Background(), filter).Find(&entries).Error
+	if err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatal(err)
+	}
+	want := "WHERE action = $1 AND entity_type = $2 AND entity_id = $3 AND actor_type = $4 AND actor_id = $5 AND request_id = $6 AND created_at >= $7 AND created_at < $8"
+	if len(*statements) != 1 || !strings.Contains((*statements)[0], want) {
+		t.Errorf("query ran %q, want %s", *statements, want)
+	}
+
+	*statements = nil
+	err = NewAuditService(db).query(context.Background(), AuditFilter{}).Find(&entries).Error
+	if err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatal(err)
+	}
+	if len(*statements) != 1 || strings.Contains((*statements)[0], "WHERE") {
+		t.Errorf("query without a filter ran %q", *statements)
+	}
+}
+
+// TestAuditTrail records a job's creation, update and deletion and checks the trail is
+// append-only. It needs a PostgreSQL database in TEST_DATABASE_URL.
+func TestAuditTrail(t *testing.T) {
+	testDatabase(t)
+	jobs := NewJobService(DB)
+	actor := &audit.Actor{Type: audit.ActorUser, ID: 3, Email: "jane@acme.test", IP: "192.0.2.1"}
+	ctx := audit.WithActor(context.Background(), actor)
+
+	job := &Job{Title: "Engineer", Company: fmt.Sprintf("Audit %d", time.Now().UnixNano()), Location: "Remote"}
+	if err := jobs.CreateJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Unscoped().Delete(&Job{}, job.ID) })
+	t.Cleanup(func() { DB.Where("job_id = ?", job.ID).Delete(&JobRevision{}) })
+
+	update := *job
+	update.Title = "Senior Engineer"
+	if err := jobs.UpdateJob(ctx, job.ID, &update); err != nil {
+		t.Fatal(err)
+	}
+	if err := jobs.DeleteJob(context.Background(), job.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*AuditEntry
+	service := NewAuditService(DB)
+	filter := AuditFilter{EntityType: AuditEntityJob, EntityID: job.ID}
+	err := service.EachEntry(context.Background(), filter, func(entry *AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("%d audit entries, want 3", len(entries))
+	}
+	created, updated, deleted := entries[0], entries[1], entries[2]
+	if created.Action != AuditActionCreate || created.Before != nil || created.After == nil {
+		t.Errorf("creation entry = %+v", created)
+	}
+	if updated.Action != AuditActionUpdate || updated.ActorType != audit.ActorUser || updated.ActorID != 3 ||
+		updated.ActorEmail != actor.Email || updated.IP != actor.IP {
+		t.Errorf("update entry = %+v, want it made by %+v", updated, actor)
+	}
+	if !jsonEqual(json.RawMessage(updated.Changes), json.RawMessage(`{"title":{"from":"Engineer","to":"Senior Engineer"}}`)) {
+		t.Errorf("update changes = %s, want only the title", updated.Changes)
+	}
+	if deleted.Action != AuditActionDelete || deleted.After != nil || deleted.ActorType != audit.ActorSystem {
+		t.Errorf("deletion entry = %+v, want a system deletion without an after state", deleted)
+	}
+
+	// Newest first when paged
+	page, total, err := service.GetEntries(context.Background(), filter, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(page) != 2 || page[0].ID != deleted.ID || page[1].ID != updated.ID {
+		t.Errorf("GetEntries = %d entries of %d, want the two newest of 3", len(page), total)
+	}
+
+	// The database refuses to change or remove entries
+	if err := DB.Model(updated).Update("actor_email", "someone@else.test").Error; err == nil {
+		t.Error("audit entry updated")
+	}
+	if err := DB.Delete(updated).Error; err == nil {
+		t.Error("audit entry deleted")
+	}
+}
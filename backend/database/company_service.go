@@ -0,0 +1,67 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// CompanyService handles company-related database operations
+type CompanyService struct {
+	db *gorm.DB
+}
+
+// NewCompanyService creates a new CompanyService
+func NewCompanyService(db *gorm.DB) *CompanyService {
+	return &CompanyService{db: db}
+}
+
+// GetCompanyByName retrieves a company by name, ignoring case as authorization does, returning
+// nil if it has no settings yet
+func (s *CompanyService) GetCompanyByName(name string) (*Company, error) {
+	var company Company
+	err := s.db.Where("LOWER(name) = LOWER(?)", name).First(&company).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retrieve company: %w", err)
+	}
+	return &company, nil
+}
+
+// GetOrCreateCompany retrieves a company by name, ignoring case, creating it if it doesn't exist
+func (s *CompanyService) GetOrCreateCompany(name string) (*Company, error) {
+	company := Company{Name: name}
+	if err := s.db.Where("LOWER(name) = LOWER(?)", name).FirstOrCreate(&company).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve company: %w", err)
+	}
+	return &company, nil
+}
+
+// SetStorageQuota sets the video storage quota of a company (nil restores the default)
+func (s *CompanyService) SetStorageQuota(name string, quotaBytes *int64) (*Company, error) {
+	company, err := s.GetOrCreateCompany(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(company).Update("storage_quota_bytes", quotaBytes).Error; err != nil {
+		return nil, fmt.Errorf("failed to update storage quota: %w", err)
+	}
+	company.StorageQuotaBytes = quotaBytes
+	return company, nil
+}
+
+// SetRequireTwoFactor sets whether a company's members must use two-factor authentication
+func (s *CompanyService) SetRequireTwoFactor(name string, required bool) (*Company, error) {
+	company, err := s.GetOrCreateCompany(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(company).Update("require_two_factor", required).Error; err != nil {
+		return nil, fmt.Errorf("failed to update two-factor policy: %w", err)
+	}
+	company.RequireTwoFactor = required
+	return company, nil
+}
@@ -1,38 +1,106 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"time"
+
+	"job-board/backend/logger"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
 // ConnectDatabase establishes connection to PostgreSQL database
 func ConnectDatabase(dsn string) error {
-	newLogger := logger.New(
-		log.New(log.Writer(), "\r\n", log.LstdFlags), // io writer
-		logger.Config{
-			SlowThreshold:             time.Second,   // Slow SQL threshold
-			LogLevel:                  logger.Silent, // Log level
-			IgnoreRecordNotFoundError: true,          // Ignore ErrRecordNotFound error for logger
-			Colorful:                  true,          // Disable color
-		},
-	)
-
 	var err error
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: newLogger,
+		Logger: gormLogger{},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Database connected successfully!")
+	if err := RegisterTenantScopes(DB); err != nil {
+		return fmt.Errorf("failed to register tenant scopes: %w", err)
+	}
+	if err := RegisterQueryMetrics(DB); err != nil {
+		return fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	if err := RegisterQueryTracing(DB); err != nil {
+		return fmt.Errorf("failed to register query tracing: %w", err)
+	}
+
+	logger.Info("Database connected")
+	return nil
+}
+
+// CloseDatabase closes the connection pool
+func CloseDatabase() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database pool: %w", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	return nil
+}
+
+// models lists the models whose tables are migrated
+func models() []interface{} {
+	return []interface{}{&Job{}, &Video{}, &Company{}, &VideoUsage{}, &User{}, &RefreshToken{}, &RevokedAccessToken{}, &Application{}, &APIKey{}, &SSOProvider{}, &RecoveryCode{}, &AuditEntry{}, &JobRevision{}, &IdempotencyKey{}}
+}
+
+// auditTrailTrigger creates the trigger rejecting updates and deletes of audit entries
+var auditTrailTrigger = []string{
+	`CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit entries are append-only';
+END;
+$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS audit_entries_append_only ON audit_entries`,
+	`CREATE TRIGGER audit_entries_append_only BEFORE UPDATE OR DELETE ON audit_entries
+	FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only()`,
+}
+
+// PingDatabase checks that the database answers
+func PingDatabase(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database connection not established")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database pool: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// MigrationStatus checks that every table and column of the models exists, i.e. that
+// the schema has been migrated
+func MigrationStatus(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database connection not established")
+	}
+	migrator := DB.WithContext(ctx).Migrator()
+	for _, model := range models() {
+		stmt := &gorm.Statement{DB: DB}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model: %w", err)
+		}
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table %s is missing", stmt.Schema.Table)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				return fmt.Errorf("column %s.%s is missing", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
 	return nil
 }
 
@@ -43,12 +111,19 @@ func MigrateDatabase() error {
 	}
 
 	// Auto-migrate the schema
-	err := DB.AutoMigrate(&Job{}, &Video{})
+	err := DB.AutoMigrate(models()...)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	log.Println("Database migration completed successfully")
+	// The audit trail is append-only: the database refuses to change or remove entries
+	for _, statement := range auditTrailTrigger {
+		if err := DB.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to protect audit trail: %w", err)
+		}
+	}
+
+	logger.Info("Database migration completed")
 	return nil
 }
 
@@ -62,7 +137,7 @@ func SeedDatabase() error {
 	var count int64
 	DB.Model(&Job{}).Count(&count)
 	if count > 0 {
-		log.Println("Database already seeded, skipping...")
+		logger.Info("Database already seeded, skipping")
 		return nil
 	}
 
@@ -167,7 +242,7 @@ func SeedDatabase() error {
 		}
 	}
 
-	log.Println("Database seeded with sample data successfully")
+	logger.Info("Database seeded with sample data")
 	return nil
 }
 
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"os"
+	"testing"
+)
+
+// testDatabase connects to and migrates the PostgreSQL database in TEST_DATABASE_URL,
+// skipping the test when it isn't set
+func testDatabase(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := ConnectDatabase(dsn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = CloseDatabase() })
+	if err := MigrateDatabase(); err != nil {
+		t.Fatal(err)
+	}
+}
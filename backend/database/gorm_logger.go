@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"job-board/backend/logger"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is how long a query may take before it's logged as slow
+const slowQueryThreshold = time.Second
+
+// gormLogger sends GORM's logs through the application logger, with the request ID, user
+// and trace ID of the query's context. Failed and slow queries are logged as warnings or
+// errors; every query is logged at debug level.
+type gormLogger struct{}
+
+var _ gormlogger.Interface = gormLogger{}
+
+// LogMode is a no-op; the application log level decides what's logged
+func (l gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Trace logs a finished query
+func (gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := elapsed > slowQueryThreshold
+	if !failed && !slow && !logger.Enabled(logger.DEBUG) {
+		return
+	}
+
+	sql, rows := fc()
+	fields := []interface{}{"sql", sql, "rows", rows, "elapsed_ms", float64(elapsed.Microseconds()) / 1000}
+	switch {
+	case failed:
+		logger.ErrorContext(ctx, "Query failed", append(fields, "error", err)...)
+	case slow:
+		logger.WarnContext(ctx, "Slow query", append(fields, "threshold", slowQueryThreshold)...)
+	default:
+		logger.DebugContext(ctx, "Query", fields...)
+	}
+}
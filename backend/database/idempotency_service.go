@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyPendingTimeout is how long a key stays claimed by a request that never
+// completed, such as one cut short by a crash, before it can be claimed again
+const idempotencyPendingTimeout = 5 * time.Minute
+
+// maxIdempotencyClaimAttempts bounds how often Begin tries to claim a key that keeps
+// going away while it's read back
+const maxIdempotencyClaimAttempts = 3
+
+// ErrIdempotencyKeyBusy is returned by Begin when other requests kept releasing or taking
+// over the key while it was being claimed; the request can be retried
+var ErrIdempotencyKeyBusy = errors.New("idempotency key is busy")
+
+// IdempotencyService stores the responses to requests made with an Idempotency-Key header
+type IdempotencyService struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyService creates a new IdempotencyService
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// Begin claims a key for a request. It returns nil if the key is new, in which case the
+// request should be handled and its response stored with Complete, or the key's existing
+// record, which is still pending while its StatusCode is 0. An expired or abandoned key
+// is claimed again. ErrIdempotencyKeyBusy is returned if the claim doesn't settle.
+func (s *IdempotencyService) Begin(ctx context.Context, key *IdempotencyKey) (*IdempotencyKey, error) {
+	// The existing key can go away between the insert and reading it back, when its
+	// request fails and releases it or it's taken over as stale, so the claim is tried again
+	for attempt := 0; attempt < maxIdempotencyClaimAttempts; attempt++ {
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to store idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing IdempotencyKey
+		err := s.db.WithContext(ctx).Where("scope = ? AND key = ?", key.Scope, key.Key).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve idempotency key: %w", err)
+		}
+
+		// The cleaner drops stale keys only periodically, so one still waiting for it is
+		// taken over here
+		now := time.Now()
+		if (existing.ExpiresAt.Before(now) || (existing.StatusCode == 0 && existing.CreatedAt.Before(now.Add(-idempotencyPendingTimeout)))) {
+			err := s.db.WithContext(ctx).Where(staleIdempotencyKeys(now)).Delete(&IdempotencyKey{}, existing.ID).Error
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+			}
+			continue
+		}
+		return &existing, nil
+	}
+	return nil, ErrIdempotencyKeyBusy
+}
+
+// DeleteExpired deletes the expired keys and those claimed by requests that never
+// completed, and returns how many were deleted
+func (s *IdempotencyService) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where(staleIdempotencyKeys(time.Now())).Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// staleIdempotencyKeys matches the keys that are expired or were abandoned while pending
+func staleIdempotencyKeys(now time.Time) clause.Expr {
+	return gorm.Expr("expires_at < ? OR (status_code = 0 AND created_at < ?)", now, now.Add(-idempotencyPendingTimeout))
+}
+
+// Complete stores the response to the request that claimed a key
+func (s *IdempotencyService) Complete(ctx context.Context, id uint, statusCode int, header JSONDocument, body []byte) error {
+	err := s.db.WithContext(ctx).Model(&IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code": statusCode,
+		"header":      header,
+		"body":        body,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release gives up a key whose request failed, so it can be retried
+func (s *IdempotencyService) Release(ctx context.Context, id uint) error {
+	if err := s.db.WithContext(ctx).Delete(&IdempotencyKey{}, id).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
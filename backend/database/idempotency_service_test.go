@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyBegin needs a PostgreSQL database in TEST_DATABASE_URL
+func TestIdempotencyBegin(t *testing.T) {
+	testDatabase(t)
+	service := NewIdempotencyService(DB)
+	ctx := context.Background()
+	scope := fmt.Sprintf("test:%d", time.Now().UnixNano())
+	t.Cleanup(func() { DB.Where("scope = ?", scope).Delete(&IdempotencyKey{}) })
+
+	newKey := func(key string, created time.Time, ttl time.Duration) *IdempotencyKey {
+		return &IdempotencyKey{Scope: scope, Key: key, Fingerprint: "f", CreatedAt: created, ExpiresAt: created.Add(ttl)}
+	}
+	now := time.Now()
+
+	// A new key is claimed, then its pending and completed record is returned
+	claimed := newKey("new", now, time.Hour)
+	if stored, err := service.Begin(ctx, claimed); err != nil || stored != nil {
+		t.Fatalf("Begin of a new key = %v, %v, want a claim", stored, err)
+	}
+	stored, err := service.Begin(ctx, newKey("new", now, time.Hour))
+	if err != nil || stored == nil || stored.ID != claimed.ID || stored.StatusCode != 0 {
+		t.Fatalf("Begin of a pending key = %+v, %v, want the pending record", stored, err)
+	}
+	if err := service.Complete(ctx, claimed.ID, 201, JSONDocument(`{}`), []byte("created")); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = service.Begin(ctx, newKey("new", now, time.Hour))
+	if err != nil || stored == nil || stored.StatusCode != 201 || string(stored.Body) != "created" {
+		t.Fatalf("Begin of a completed key = %+v, %v, want the stored response", stored, err)
+	}
+
+	// A released key is claimed again
+	if err := service.Release(ctx, claimed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := service.Begin(ctx, newKey("new", now, time.Hour)); err != nil || stored != nil {
+		t.Errorf("Begin of a released key = %v, %v, want a claim", stored, err)
+	}
+
+	// Expired and abandoned keys are taken over, and so are gone after DeleteExpired
+	tests := []struct {
+		key     string
+		created time.Time
+		ttl     time.Duration
+		status  int
+	}{
+		{"expired", now.Add(-2 * time.Hour), time.Hour, 201},
+		{"abandoned", now.Add(-idempotencyPendingTimeout - time.Minute), time.Hour, 0},
+	}
+	for _, tt := range tests {
+		stale := newKey(tt.key, tt.created, tt.ttl)
+		if _, err := service.Begin(ctx, stale); err != nil {
+			t.Fatal(err)
+		}
+		if tt.status != 0 {
+			if err := service.Complete(ctx, stale.ID, tt.status, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fresh := newKey(tt.key, now, time.Hour)
+		if stored, err := service.Begin(ctx, fresh); err != nil || stored != nil {
+			t.Errorf("%s: Begin = %v, %v, want the key taken over", tt.key, stored, err)
+		}
+		if fresh.ID == stale.ID {
+			t.Errorf("%s: taken over key kept the stale record", tt.key)
+		}
+
+		stale = newKey(tt.key+" cleaned", tt.created, tt.ttl)
+		if _, err := service.Begin(ctx, stale); err != nil {
+			t.Fatal(err)
+		}
+		if tt.status != 0 {
+			if err := service.Complete(ctx, stale.ID, tt.status, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := service.DeleteExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	DB.Model(&IdempotencyKey{}).Where("scope = ?", scope).Order("key").Pluck("key", &keys)
+	if fmt.Sprint(keys) != "[abandoned expired new]" {
+		t.Errorf("keys after DeleteExpired = %v, want [abandoned expired new]", keys)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"job-board/backend/audit"
+
+	"gorm.io/gorm"
+)
+
+// saveJobRevision stores the job's current state within tx as its next revision. previous
+// is the job as it was before an update; jobs created before revisions were kept have no
+// revisions yet, so their previous state is stored first as version 1.
+func saveJobRevision(ctx context.Context, tx *gorm.DB, id uint, previous *Job, restoredFrom *int) error {
+	var latest int
+	if err := tx.Model(&JobRevision{}).Where("job_id = ?", id).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return fmt.Errorf("failed to save job revision: %w", err)
+	}
+
+	if latest == 0 && previous != nil {
+		snapshot, err := jobSnapshot(previous)
+		if err != nil {
+			return fmt.Errorf("failed to save job revision: %w", err)
+		}
+		revision := JobRevision{JobID: id, Version: 1, Snapshot: snapshot, ActorType: audit.ActorSystem, CreatedAt: previous.UpdatedAt}
+		if err := tx.Create(&revision).Error; err != nil {
+			return fmt.Errorf("failed to save job revision: %w", err)
+		}
+		latest = 1
+	}
+
+	var current Job
+	if err := tx.First(&current, id).Error; err != nil {
+		return fmt.Errorf("failed to save job revision: %w", err)
+	}
+	snapshot, err := jobSnapshot(&current)
+	if err != nil {
+		return fmt.Errorf("failed to save job revision: %w", err)
+	}
+	actor := audit.ActorFromContext(ctx)
+	revision := JobRevision{
+		JobID:        id,
+		Version:      latest + 1,
+		Snapshot:     snapshot,
+		RestoredFrom: restoredFrom,
+		ActorType:    actor.Type,
+		ActorID:      actor.ID,
+		ActorEmail:   actor.Email,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return fmt.Errorf("failed to save job revision: %w", err)
+	}
+	return nil
+}
+
+// jobSnapshot returns the job's fields, without its videos
+func jobSnapshot(job *Job) (JSONDocument, error) {
+	fields, err := auditFields(job)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// Job returns the job as it was at the revision
+func (r *JobRevision) Job() (*Job, error) {
+	var job Job
+	if err := json.Unmarshal(r.Snapshot, &job); err != nil {
+		return nil, fmt.Errorf("failed to read revision %d of job %d: %w", r.Version, r.JobID, err)
+	}
+	return &job, nil
+}
+
+// DiffJobRevisions returns the fields that differ from revision from to revision to
+func DiffJobRevisions(from, to *JobRevision) (map[string]FieldChange, error) {
+	var fromFields, toFields map[string]json.RawMessage
+	if err := json.Unmarshal(from.Snapshot, &fromFields); err != nil {
+		return nil, fmt.Errorf("failed to read revision %d: %w", from.Version, err)
+	}
+	if err := json.Unmarshal(to.Snapshot, &toFields); err != nil {
+		return nil, fmt.Errorf("failed to read revision %d: %w", to.Version, err)
+	}
+	return auditChanges(fromFields, toFields), nil
+}
+
+// GetJobRevisions retrieves the revisions of a job, newest first
+func (s *JobService) GetJobRevisions(ctx context.Context, jobID uint) ([]JobRevision, error) {
+	// Looking the job up first applies the tenant scope to its revisions
+	if _, err := s.GetJobByID(ctx, jobID); err != nil {
+		return nil, err
+	}
+	var revisions []JobRevision
+	if err := s.db.WithContext(ctx).Where("job_id = ?", jobID).Order("version DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve job revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetJobRevision retrieves a revision of a job by its version
+func (s *JobService) GetJobRevision(ctx context.Context, jobID uint, version int) (*JobRevision, error) {
+	if _, err := s.GetJobByID(ctx, jobID); err != nil {
+		return nil, err
+	}
+	var revision JobRevision
+	err := s.db.WithContext(ctx).Where("job_id = ? AND version = ?", jobID, version).First(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("revision %d of job with ID %d not found", version, jobID)
+		}
+		return nil, fmt.Errorf("failed to retrieve job revision: %w", err)
+	}
+	return &revision, nil
+}
+
+// RestoreJobRevision updates a job to how it was at a revision, which is stored as a new
+// revision rather than discarding the ones after it
+func (s *JobService) RestoreJobRevision(ctx context.Context, jobID uint, version int) (*Job, error) {
+	revision, err := s.GetJobRevision(ctx, jobID, version)
+	if err != nil {
+		return nil, err
+	}
+	job, err := revision.Job()
+	if err != nil {
+		return nil, err
+	}
+	// The restored state becomes the job's next version rather than the one it was stored at
+	job.Version = 0
+	if err := s.updateJob(ctx, jobID, job, &version); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"job-board/backend/metrics"
+
+	"gorm.io/gorm"
+)
+
+// queryStartKey is the statement setting holding when a query started
+const queryStartKey = "metrics:start"
+
+// RegisterQueryMetrics times every query and records it by table and operation
+func RegisterQueryMetrics(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", startQuery),
+		callback.Create().After("*").Register("metrics:after_create", observeQuery("create")),
+		callback.Query().Before("*").Register("metrics:before_query", startQuery),
+		callback.Query().After("*").Register("metrics:after_query", observeQuery("query")),
+		callback.Update().Before("*").Register("metrics:before_update", startQuery),
+		callback.Update().After("*").Register("metrics:after_update", observeQuery("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", startQuery),
+		callback.Delete().After("*").Register("metrics:after_delete", observeQuery("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", startQuery),
+		callback.Row().After("*").Register("metrics:after_row", observeQuery("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", startQuery),
+		callback.Raw().After("*").Register("metrics:after_raw", observeQuery("raw")),
+	)
+}
+
+// startQuery records when a query started
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// observeQuery records how long a query took once it finished
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		metrics.ObserveQuery(table, operation, failed, time.Since(start))
+	}
+}
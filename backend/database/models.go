@@ -1,6 +1,8 @@
 package database
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,27 +20,258 @@ type Job struct {
 	Benefits     []string  `json:"benefits" gorm:"type:text[]"`
 	PostedAt     time.Time `json:"postedAt" gorm:"default:CURRENT_TIMESTAMP"`
 	VideoURL     *string   `json:"videoUrl"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-	DeletedAt    gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+	// Status is draft or published; drafts are only visible to the company
+	Status string `json:"status" gorm:"not null;default:published;index"`
+	// Version counts the job's updates; it's the job's ETag and guards against lost updates
+	Version   int            `json:"version" gorm:"not null;default:1"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+
+	// Relationship
+	Videos []Video `json:"videos,omitempty" gorm:"foreignKey:JobID"`
 }
 
+// Job statuses
+const (
+	JobStatusDraft     = "draft"
+	JobStatusPublished = "published"
+)
+
 // Video represents a video associated with a job
 type Video struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	JobID     uint      `json:"jobId" gorm:"not null"`
-	Title     string    `json:"title" gorm:"not null"`
-	URL       string    `json:"url" gorm:"not null"`
-	Duration  *int      `json:"duration"`
-	Thumbnail *string   `json:"thumbnail"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	JobID     uint           `json:"jobId" gorm:"not null"`
+	Title     string         `json:"title" gorm:"not null"`
+	URL       string         `json:"url" gorm:"not null"`
+	Duration  *int           `json:"duration"`
+	Thumbnail *string        `json:"thumbnail"`
+	Checksum  *string        `json:"checksum,omitempty"` // hex-encoded SHA-256 of the video file
+	SizeBytes *int64         `json:"sizeBytes,omitempty"`
+	Version   int            `json:"version" gorm:"not null;default:1"` // counts the video's updates, like Job.Version
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"index"`
-	
+
 	// Relationship
 	Job Job `json:"job" gorm:"foreignKey:JobID"`
 }
 
+// Company holds per-employer settings. Companies are identified by the name used on their jobs.
+type Company struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"not null;uniqueIndex"`
+	// StorageQuotaBytes overrides the default video storage quota (0 means unlimited)
+	StorageQuotaBytes *int64 `json:"storageQuotaBytes"`
+	// RequireTwoFactor forces the company's recruiters and admins to enroll in two-factor authentication
+	RequireTwoFactor bool      `json:"requireTwoFactor" gorm:"not null;default:false"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// VideoUsage holds the bandwidth served for a video
+type VideoUsage struct {
+	VideoID     uint      `json:"videoId" gorm:"primaryKey;autoIncrement:false"`
+	Company     string    `json:"company" gorm:"not null;index"`
+	BytesServed int64     `json:"bytesServed" gorm:"not null;default:0"`
+	Requests    int64     `json:"requests" gorm:"not null;default:0"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Application represents a candidate's application to a job
+type Application struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	JobID       uint           `json:"jobId" gorm:"not null;index"`
+	CandidateID uint           `json:"candidateId" gorm:"not null;index"`
+	CoverLetter string         `json:"coverLetter" gorm:"type:text"`
+	Status      string         `json:"status" gorm:"not null;default:submitted"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+
+	// Relationships
+	Job       Job  `json:"job" gorm:"foreignKey:JobID"`
+	Candidate User `json:"candidate" gorm:"foreignKey:CandidateID"`
+}
+
+// Application statuses
+const (
+	ApplicationSubmitted = "submitted"
+	ApplicationReviewing = "reviewing"
+	ApplicationRejected  = "rejected"
+	ApplicationHired     = "hired"
+)
+
+// User represents a user account
+type User struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Email        string `json:"email" gorm:"not null;uniqueIndex"`
+	Name         string `json:"name"`
+	Role         string `json:"role" gorm:"not null;default:candidate"`
+	Company      string `json:"company" gorm:"index"` // company the user recruits for, if any
+	PasswordHash string `json:"-" gorm:"not null"`
+	// TOTPSecret is set when enrollment starts; two-factor authentication is only
+	// required once TOTPEnabled is set by confirming a code
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"totpEnabled" gorm:"not null;default:false"`
+	TOTPLastStep int64  `json:"-"` // time step of the last accepted code, so codes can't be replayed
+	// TOTPAttempts counts the two-factor codes tried since the last accepted one; too many
+	// lock two-factor verification until TOTPLockedUntil
+	TOTPAttempts    int            `json:"-" gorm:"not null;default:0"`
+	TOTPLockedUntil *time.Time     `json:"-"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// User roles
+const (
+	RoleCandidate    = "candidate"
+	RoleRecruiter    = "recruiter"
+	RoleCompanyAdmin = "company_admin"
+	RoleSiteAdmin    = "site_admin"
+)
+
+// RefreshToken is a single-use refresh token. Tokens issued from one login share a
+// family, so reuse of a rotated token can revoke every token derived from it.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey"`
+	UserID       uint       `gorm:"not null;index"`
+	TokenHash    string     `gorm:"not null;uniqueIndex"` // hex-encoded SHA-256 of the token
+	FamilyID     string     `gorm:"not null;index"`
+	ExpiresAt    time.Time  `gorm:"not null;index"`
+	RevokedAt    *time.Time `gorm:"index"`
+	ReplacedByID *uint
+	CreatedAt    time.Time
+}
+
+// APIKey is a company-scoped key for server-to-server integrations
+type APIKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Company     string     `json:"company" gorm:"not null;index"`
+	Name        string     `json:"name" gorm:"not null"`
+	Prefix      string     `json:"prefix" gorm:"not null"`        // first characters of the key, to tell keys apart
+	KeyHash     string     `json:"-" gorm:"not null;uniqueIndex"` // hex-encoded SHA-256 of the key
+	Scopes      string     `json:"-" gorm:"not null"`             // space-separated
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+	CreatedByID uint       `json:"createdById"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// ScopeList returns the key's scopes
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// MarshalJSON includes the scopes as a list
+func (k APIKey) MarshalJSON() ([]byte, error) {
+	type apiKey APIKey
+	return json.Marshal(struct {
+		apiKey
+		Scopes []string `json:"scopes"`
+	}{apiKey(k), k.ScopeList()})
+}
+
+// SSOProvider is a company's OpenID Connect identity provider for recruiter single sign-on
+type SSOProvider struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Company      string `json:"company" gorm:"not null;uniqueIndex"`
+	IssuerURL    string `json:"issuerUrl" gorm:"not null"`
+	ClientID     string `json:"clientId" gorm:"not null"`
+	ClientSecret string `json:"-"`
+	// AllowedDomains restricts sign-ins to these email domains (space-separated, required
+	// to enable the provider)
+	AllowedDomains string `json:"allowedDomains"`
+	Enabled        bool   `json:"enabled" gorm:"not null"`
+	// TrustedForMFA is set by a site admin when the provider enforces a second factor, so
+	// accounts with two-factor authentication may sign in through it
+	TrustedForMFA bool      `json:"trustedForMfa" gorm:"not null;default:false"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// DomainAllowed reports whether users with an email at domain may sign in through the provider
+func (p *SSOProvider) DomainAllowed(domain string) bool {
+	for _, allowed := range strings.Fields(p.AllowedDomains) {
+		if strings.EqualFold(allowed, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the user's device is lost
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"` // hex-encoded SHA-256 of the code
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RevokedAccessToken denies an access token before it expires
+type RevokedAccessToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// AuditEntry records one change to a job, video or application. Entries are only ever
+// appended; the database rejects updates and deletes.
+type AuditEntry struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Action     string `json:"action" gorm:"not null;index"` // create, update or delete
+	EntityType string `json:"entityType" gorm:"not null;index:idx_audit_entries_entity"`
+	EntityID   uint   `json:"entityId" gorm:"not null;index:idx_audit_entries_entity"`
+	// ActorType is user, api_key, anonymous or system; ActorID is the user's or API key's ID
+	ActorType  string `json:"actorType" gorm:"not null;index:idx_audit_entries_actor"`
+	ActorID    uint   `json:"actorId" gorm:"index:idx_audit_entries_actor"`
+	ActorEmail string `json:"actorEmail,omitempty"`
+	// Before and After are the entity's fields before and after the change; Changes holds
+	// the fields that differ as {"field": {"from": ..., "to": ...}}
+	Before    JSONDocument `json:"before" gorm:"type:jsonb"`
+	After     JSONDocument `json:"after" gorm:"type:jsonb"`
+	Changes   JSONDocument `json:"changes" gorm:"type:jsonb"`
+	RequestID string       `json:"requestId,omitempty" gorm:"index"`
+	IP        string       `json:"ip,omitempty"`
+	CreatedAt time.Time    `json:"createdAt" gorm:"not null;index"`
+}
+
+// JobRevision is a snapshot of a job as it was after it was created or updated. Versions
+// count up from 1 for each job.
+type JobRevision struct {
+	ID      uint `json:"id" gorm:"primaryKey"`
+	JobID   uint `json:"jobId" gorm:"not null;uniqueIndex:idx_job_revisions_version"`
+	Version int  `json:"version" gorm:"not null;uniqueIndex:idx_job_revisions_version"`
+	// Snapshot holds the job's fields, without its videos
+	Snapshot JSONDocument `json:"snapshot" gorm:"type:jsonb;not null"`
+	// RestoredFrom is the version this revision restored, if any
+	RestoredFrom *int `json:"restoredFrom,omitempty"`
+	// ActorType and ActorID name who made the change, as in the audit trail
+	ActorType  string    `json:"actorType" gorm:"not null"`
+	ActorID    uint      `json:"actorId"`
+	ActorEmail string    `json:"actorEmail,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// IdempotencyKey holds the response to a request made with an Idempotency-Key header, so
+// retries of the request get the same response instead of repeating it
+type IdempotencyKey struct {
+	ID uint `gorm:"primaryKey"`
+	// Scope is the caller the key belongs to, such as "user:1", so callers' keys never collide
+	Scope string `gorm:"not null;uniqueIndex:idx_idempotency_keys_key"`
+	Key   string `gorm:"not null;uniqueIndex:idx_idempotency_keys_key"`
+	// Fingerprint is the hex-encoded SHA-256 of the request's method, path and body
+	Fingerprint string `gorm:"not null"`
+	// StatusCode is 0 while the first request with the key is still being handled
+	StatusCode int
+	Header     JSONDocument `gorm:"type:jsonb"`
+	Body       []byte
+	CreatedAt  time.Time
+	ExpiresAt  time.Time `gorm:"not null;index"`
+}
+
 // TableName specifies the table name for Job
 func (Job) TableName() string {
 	return "jobs"
@@ -48,3 +281,63 @@ func (Job) TableName() string {
 func (Video) TableName() string {
 	return "videos"
 }
+
+// TableName specifies the table name for Company
+func (Company) TableName() string {
+	return "companies"
+}
+
+// TableName specifies the table name for VideoUsage
+func (VideoUsage) TableName() string {
+	return "video_usage"
+}
+
+// TableName specifies the table name for User
+func (User) TableName() string {
+	return "users"
+}
+
+// TableName specifies the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// TableName specifies the table name for RevokedAccessToken
+func (RevokedAccessToken) TableName() string {
+	return "revoked_access_tokens"
+}
+
+// TableName specifies the table name for Application
+func (Application) TableName() string {
+	return "applications"
+}
+
+// TableName specifies the table name for RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// TableName specifies the table name for SSOProvider
+func (SSOProvider) TableName() string {
+	return "sso_providers"
+}
+
+// TableName specifies the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// TableName specifies the table name for AuditEntry
+func (AuditEntry) TableName() string {
+	return "audit_entries"
+}
+
+// TableName specifies the table name for JobRevision
+func (JobRevision) TableName() string {
+	return "job_revisions"
+}
+
+// TableName specifies the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
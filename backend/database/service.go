@@ -1,13 +1,20 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrVersionConflict is returned when a job or video was changed since the version a
+// caller expected
+var ErrVersionConflict = errors.New("record has been modified")
+
 // JobService handles job-related database operations
 type JobService struct {
 	db *gorm.DB
@@ -19,19 +26,19 @@ func NewJobService(db *gorm.DB) *JobService {
 }
 
 // GetAllJobs retrieves all jobs from the database with optimized queries
-func (s *JobService) GetAllJobs() ([]Job, error) {
+func (s *JobService) GetAllJobs(ctx context.Context) ([]Job, error) {
 	var jobs []Job
-	err := s.db.Preload("Videos").Find(&jobs).Error
+	err := s.db.WithContext(ctx).Preload("Videos").Find(&jobs).Error
 	return jobs, err
 }
 
 // GetJobsWithPagination retrieves jobs with pagination
-func (s *JobService) GetJobsWithPagination(page, pageSize int) ([]Job, int64, error) {
+func (s *JobService) GetJobsWithPagination(ctx context.Context, page, pageSize int) ([]Job, int64, error) {
 	var jobs []Job
 	var total int64
 
 	// Count total records
-	if err := s.db.Model(&Job{}).Count(&total).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&Job{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -39,7 +46,7 @@ func (s *JobService) GetJobsWithPagination(page, pageSize int) ([]Job, int64, er
 	offset := (page - 1) * pageSize
 
 	// Get jobs with pagination
-	err := s.db.Preload("Videos").
+	err := s.db.WithContext(ctx).Preload("Videos").
 		Offset(offset).
 		Limit(pageSize).
 		Order("created_at DESC").
@@ -49,28 +56,28 @@ func (s *JobService) GetJobsWithPagination(page, pageSize int) ([]Job, int64, er
 }
 
 // GetJobsByCompany retrieves jobs by company name
-func (s *JobService) GetJobsByCompany(company string) ([]Job, error) {
+func (s *JobService) GetJobsByCompany(ctx context.Context, company string) ([]Job, error) {
 	var jobs []Job
-	err := s.db.Where("company ILIKE ?", "%"+company+"%").
+	err := s.db.WithContext(ctx).Where("company ILIKE ?", "%"+company+"%").
 		Preload("Videos").
 		Find(&jobs).Error
 	return jobs, err
 }
 
 // GetJobsByLocation retrieves jobs by location
-func (s *JobService) GetJobsByLocation(location string) ([]Job, error) {
+func (s *JobService) GetJobsByLocation(ctx context.Context, location string) ([]Job, error) {
 	var jobs []Job
-	err := s.db.Where("location ILIKE ?", "%"+location+"%").
+	err := s.db.WithContext(ctx).Where("location ILIKE ?", "%"+location+"%").
 		Preload("Videos").
 		Find(&jobs).Error
 	return jobs, err
 }
 
 // SearchJobs performs a full-text search on jobs
-func (s *JobService) SearchJobs(query string) ([]Job, error) {
+func (s *JobService) SearchJobs(ctx context.Context, query string) ([]Job, error) {
 	var jobs []Job
 	searchQuery := "%" + query + "%"
-	err := s.db.Where("title ILIKE ? OR description ILIKE ? OR company ILIKE ?",
+	err := s.db.WithContext(ctx).Where("title ILIKE ? OR description ILIKE ? OR company ILIKE ?",
 		searchQuery, searchQuery, searchQuery).
 		Preload("Videos").
 		Find(&jobs).Error
@@ -78,9 +85,9 @@ func (s *JobService) SearchJobs(query string) ([]Job, error) {
 }
 
 // GetJobByID retrieves a job by its ID with related videos
-func (s *JobService) GetJobByID(id uint) (*Job, error) {
+func (s *JobService) GetJobByID(ctx context.Context, id uint) (*Job, error) {
 	var job Job
-	err := s.db.Preload("Videos").First(&job, id).Error
+	err := s.db.WithContext(ctx).Preload("Videos").First(&job, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("job with ID %d not found", id)
@@ -91,44 +98,169 @@ func (s *JobService) GetJobByID(id uint) (*Job, error) {
 }
 
 // CreateJob creates a new job in the database
-func (s *JobService) CreateJob(job *Job) error {
+func (s *JobService) CreateJob(ctx context.Context, job *Job) error {
 	job.PostedAt = time.Now()
-	if err := s.db.Create(job).Error; err != nil {
+	if job.Status == "" {
+		job.Status = JobStatusPublished
+	}
+	job.Version = 1
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(job).Error; err != nil {
+			return err
+		}
+		if err := saveJobRevision(ctx, tx, job.ID, nil, nil); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditActionCreate, AuditEntityJob, job.ID, nil, job)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create job: %w", err)
 	}
 	return nil
 }
 
-// UpdateJob updates an existing job
-func (s *JobService) UpdateJob(id uint, job *Job) error {
-	// Get existing job to preserve PostedAt
-	var existingJob Job
-	if err := s.db.First(&existingJob, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("job with ID %d not found", id)
+// UpdateJob updates an existing job, keeping its previous state as a revision. Unless
+// job.Version is 0, ErrVersionConflict is returned if the job is at another version; on
+// success job.Version is the job's new version.
+func (s *JobService) UpdateJob(ctx context.Context, id uint, job *Job) error {
+	return s.updateJob(ctx, id, job, nil)
+}
+
+// updateJob updates an existing job; restoredFrom is the revision being restored, if any
+func (s *JobService) updateJob(ctx context.Context, id uint, job *Job, restoredFrom *int) error {
+	err := audited(ctx, s.db, AuditActionUpdate, AuditEntityJob, id, func(tx *gorm.DB, existingJob *Job) (int64, error) {
+		if job.Version != 0 && job.Version != existingJob.Version {
+			return 0, ErrVersionConflict
 		}
-		return fmt.Errorf("failed to find job for update: %w", err)
-	}
+		job.Version = existingJob.Version + 1
 
-	// Preserve the original PostedAt and set the ID
-	job.PostedAt = existingJob.PostedAt
-	job.ID = id
+		// Preserve the original PostedAt, keep the status unless a new one is given, and set the ID
+		job.PostedAt = existingJob.PostedAt
+		job.CreatedAt = existingJob.CreatedAt
+		if job.Status == "" {
+			job.Status = existingJob.Status
+		}
+		job.ID = id
 
-	if err := s.db.Save(job).Error; err != nil {
+		// Updates rather than Save, which would insert the job if the tenant scope matched no rows
+		result := tx.Model(&Job{ID: id}).Select("*").Omit("id", "created_at", "deleted_at", clause.Associations).Updates(job)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.RowsAffected, result.Error
+		}
+		return result.RowsAffected, saveJobRevision(ctx, tx, id, existingJob, restoredFrom)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("job with ID %d not found", id)
+	}
+	if err == ErrVersionConflict {
+		return err
+	}
+	if err != nil {
 		return fmt.Errorf("failed to update job: %w", err)
 	}
 	return nil
 }
 
-// DeleteJob soft deletes a job
-func (s *JobService) DeleteJob(id uint) error {
-	result := s.db.Delete(&Job{}, id)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete job: %w", result.Error)
+// jobColumns maps the JSON names of the job fields callers may change to their columns
+var jobColumns = map[string]string{
+	"title":        "title",
+	"company":      "company",
+	"description":  "description",
+	"location":     "location",
+	"salary":       "salary",
+	"requirements": "requirements",
+	"benefits":     "benefits",
+	"videoUrl":     "video_url",
+	"status":       "status",
+}
+
+// PatchJob updates an existing job to job, writing only the columns that differ. Like
+// UpdateJob, it checks job.Version unless it's 0, sets it to the job's new version and
+// keeps the previous state as a revision. A patch that changes nothing leaves the job's
+// version, revisions and audit trail as they are.
+func (s *JobService) PatchJob(ctx context.Context, id uint, job *Job) error {
+	err := audited(ctx, s.db, AuditActionUpdate, AuditEntityJob, id, func(tx *gorm.DB, existingJob *Job) (int64, error) {
+		if job.Version != 0 && job.Version != existingJob.Version {
+			return 0, ErrVersionConflict
+		}
+
+		before, err := auditFields(existingJob)
+		if err != nil {
+			return 0, err
+		}
+		after, err := auditFields(job)
+		if err != nil {
+			return 0, err
+		}
+		var columns []string
+		for name := range auditChanges(before, after) {
+			if column, ok := jobColumns[name]; ok {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 {
+			job.Version = existingJob.Version
+			return 0, errUnchanged
+		}
+		sort.Strings(columns)
+
+		job.Version = existingJob.Version + 1
+		result := tx.Model(&Job{ID: id}).Select(append(columns, "version")).Updates(job)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.RowsAffected, result.Error
+		}
+		return result.RowsAffected, saveJobRevision(ctx, tx, id, existingJob, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("job with ID %d not found", id)
+	}
+	if err == ErrVersionConflict {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
 	}
-	if result.RowsAffected == 0 {
+	return nil
+}
+
+// DeleteJob soft deletes a job along with its videos, which are given the job's deletion
+// time so restoring the job from the trash restores them too. Unless version is 0,
+// ErrVersionConflict is returned if the job is at another version.
+func (s *JobService) DeleteJob(ctx context.Context, id uint, version int) error {
+	err := audited(ctx, s.db, AuditActionDelete, AuditEntityJob, id, func(tx *gorm.DB, existingJob *Job) (int64, error) {
+		if version != 0 && version != existingJob.Version {
+			return 0, ErrVersionConflict
+		}
+		deletedAt := time.Now()
+		tx = tx.Session(&gorm.Session{NowFunc: func() time.Time { return deletedAt }})
+
+		var videos []Video
+		if err := tx.Where("job_id = ?", id).Find(&videos).Error; err != nil {
+			return 0, err
+		}
+		result := tx.Delete(&Job{}, id)
+		if result.Error != nil || result.RowsAffected == 0 || len(videos) == 0 {
+			return result.RowsAffected, result.Error
+		}
+		if err := tx.Where("job_id = ?", id).Delete(&Video{}).Error; err != nil {
+			return 0, err
+		}
+		for i := range videos {
+			if err := recordAudit(ctx, tx, AuditActionDelete, AuditEntityVideo, videos[i].ID, &videos[i], nil); err != nil {
+				return 0, err
+			}
+		}
+		return result.RowsAffected, nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("job with ID %d not found", id)
 	}
+	if err == ErrVersionConflict {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
 	return nil
 }
 
@@ -143,19 +275,19 @@ func NewVideoService(db *gorm.DB) *VideoService {
 }
 
 // GetAllVideos retrieves all videos from the database
-func (s *VideoService) GetAllVideos() ([]Video, error) {
+func (s *VideoService) GetAllVideos(ctx context.Context) ([]Video, error) {
 	var videos []Video
-	err := s.db.Preload("Job").Find(&videos).Error
+	err := s.db.WithContext(ctx).Preload("Job").Find(&videos).Error
 	return videos, err
 }
 
 // GetVideosWithPagination retrieves videos with pagination
-func (s *VideoService) GetVideosWithPagination(page, pageSize int) ([]Video, int64, error) {
+func (s *VideoService) GetVideosWithPagination(ctx context.Context, page, pageSize int) ([]Video, int64, error) {
 	var videos []Video
 	var total int64
 
 	// Count total records
-	if err := s.db.Model(&Video{}).Count(&total).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&Video{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -163,7 +295,7 @@ func (s *VideoService) GetVideosWithPagination(page, pageSize int) ([]Video, int
 	offset := (page - 1) * pageSize
 
 	// Get videos with pagination
-	err := s.db.Preload("Job").
+	err := s.db.WithContext(ctx).Preload("Job").
 		Offset(offset).
 		Limit(pageSize).
 		Order("created_at DESC").
@@ -173,9 +305,9 @@ func (s *VideoService) GetVideosWithPagination(page, pageSize int) ([]Video, int
 }
 
 // GetVideoByID retrieves a video by its ID
-func (s *VideoService) GetVideoByID(id uint) (*Video, error) {
+func (s *VideoService) GetVideoByID(ctx context.Context, id uint) (*Video, error) {
 	var video Video
-	err := s.db.Preload("Job").First(&video, id).Error
+	err := s.db.WithContext(ctx).Preload("Job").First(&video, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("video with ID %d not found", id)
@@ -185,38 +317,137 @@ func (s *VideoService) GetVideoByID(id uint) (*Video, error) {
 	return &video, nil
 }
 
+// GetAllVideosIncludingDeleted retrieves all videos, including soft-deleted ones
+func (s *VideoService) GetAllVideosIncludingDeleted(ctx context.Context) ([]Video, error) {
+	var videos []Video
+	err := s.db.WithContext(ctx).Unscoped().Order("id").Find(&videos).Error
+	return videos, err
+}
+
 // GetVideosByJobID retrieves all videos for a specific job
-func (s *VideoService) GetVideosByJobID(jobID uint) ([]Video, error) {
+func (s *VideoService) GetVideosByJobID(ctx context.Context, jobID uint) ([]Video, error) {
 	var videos []Video
-	err := s.db.Where("job_id = ?", jobID).Find(&videos).Error
+	err := s.db.WithContext(ctx).Where("job_id = ?", jobID).Find(&videos).Error
 	return videos, err
 }
 
 // CreateVideo creates a new video in the database
-func (s *VideoService) CreateVideo(video *Video) error {
-	if err := s.db.Create(video).Error; err != nil {
+func (s *VideoService) CreateVideo(ctx context.Context, video *Video) error {
+	video.Version = 1
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(video).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditActionCreate, AuditEntityVideo, video.ID, nil, video)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create video: %w", err)
 	}
 	return nil
 }
 
-// UpdateVideo updates an existing video
-func (s *VideoService) UpdateVideo(id uint, video *Video) error {
-	video.ID = id
-	if err := s.db.Save(video).Error; err != nil {
+// UpdateVideo updates an existing video. Like UpdateJob, it checks video.Version unless
+// it's 0 and sets it to the video's new version.
+func (s *VideoService) UpdateVideo(ctx context.Context, id uint, video *Video) error {
+	err := audited(ctx, s.db, AuditActionUpdate, AuditEntityVideo, id, func(tx *gorm.DB, existingVideo *Video) (int64, error) {
+		if video.Version != 0 && video.Version != existingVideo.Version {
+			return 0, ErrVersionConflict
+		}
+		video.Version = existingVideo.Version + 1
+		video.ID = id
+		result := tx.Model(&Video{ID: id}).Select("*").Omit("id", "created_at", "deleted_at", clause.Associations).Updates(video)
+		return result.RowsAffected, result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("video with ID %d not found", id)
+	}
+	if err == ErrVersionConflict {
+		return err
+	}
+	if err != nil {
 		return fmt.Errorf("failed to update video: %w", err)
 	}
 	return nil
 }
 
 // DeleteVideo soft deletes a video
-func (s *VideoService) DeleteVideo(id uint) error {
-	result := s.db.Delete(&Video{}, id)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete video: %w", result.Error)
+func (s *VideoService) DeleteVideo(ctx context.Context, id uint) error {
+	err := audited(ctx, s.db, AuditActionDelete, AuditEntityVideo, id, func(tx *gorm.DB, _ *Video) (int64, error) {
+		result := tx.Delete(&Video{}, id)
+		return result.RowsAffected, result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("video with ID %d not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete video: %w", err)
 	}
-	if result.RowsAffected == 0 {
+	return nil
+}
+
+// UpdateVideoChecksum records the checksum of a video's file
+func (s *VideoService) UpdateVideoChecksum(ctx context.Context, id uint, checksum string) error {
+	err := audited(ctx, s.db, AuditActionUpdate, AuditEntityVideo, id, func(tx *gorm.DB, _ *Video) (int64, error) {
+		result := tx.Model(&Video{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"checksum": checksum,
+			"version":  gorm.Expr("version + 1"),
+		})
+		return result.RowsAffected, result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("video with ID %d not found", id)
 	}
+	if err != nil {
+		return fmt.Errorf("failed to update video checksum: %w", err)
+	}
+	return nil
+}
+
+// GetVideoByURL retrieves a video by its URL along with its job
+func (s *VideoService) GetVideoByURL(ctx context.Context, url string) (*Video, error) {
+	var video Video
+	err := s.db.WithContext(ctx).Preload("Job").Where("url = ?", url).First(&video).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("video with URL %s not found", url)
+		}
+		return nil, fmt.Errorf("failed to retrieve video: %w", err)
+	}
+	return &video, nil
+}
+
+// UpdateVideoFile records the location, size and checksum of an uploaded video file.
+// Unless version is 0, ErrVersionConflict is returned if the video is at another version.
+// store puts the file in place once the record is updated, as the last step of the
+// transaction, so the record is left alone if the file can't be stored and the file is
+// left alone if the version doesn't match or the update fails.
+func (s *VideoService) UpdateVideoFile(ctx context.Context, id uint, version int, url string, sizeBytes int64, checksum string, store func() error) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := audited(ctx, tx, AuditActionUpdate, AuditEntityVideo, id, func(tx *gorm.DB, existingVideo *Video) (int64, error) {
+			if version != 0 && version != existingVideo.Version {
+				return 0, ErrVersionConflict
+			}
+			result := tx.Model(&Video{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"url":        url,
+				"size_bytes": sizeBytes,
+				"checksum":   checksum,
+				"version":    gorm.Expr("version + 1"),
+			})
+			return result.RowsAffected, result.Error
+		})
+		if err != nil {
+			return err
+		}
+		return store()
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("video with ID %d not found", id)
+	}
+	if err == ErrVersionConflict {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update video file: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestPatchJobUnchanged checks that a patch changing nothing records no revision or audit
+// entry, while one that changes a field records both. It needs a PostgreSQL database in
+// TEST_DATABASE_URL.
+func TestPatchJobUnchanged(t *testing.T) {
+	testDatabase(t)
+	ctx := context.Background()
+	jobs := NewJobService(DB)
+
+	job := &Job{Title: "Engineer", Company: fmt.Sprintf("Patch %d", time.Now().UnixNano()), Location: "Remote"}
+	if err := jobs.CreateJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Unscoped().Delete(&Job{}, job.ID) })
+	t.Cleanup(func() { DB.Where("job_id = ?", job.ID).Delete(&JobRevision{}) })
+
+	history := func() (revisions, updates int64) {
+		DB.Model(&JobRevision{}).Where("job_id = ?", job.ID).Count(&revisions)
+		DB.Model(&AuditEntry{}).Where("action = ? AND entity_type = ? AND entity_id = ?", AuditActionUpdate, AuditEntityJob, job.ID).Count(&updates)
+		return revisions, updates
+	}
+
+	same := *job
+	if err := jobs.PatchJob(ctx, job.ID, &same); err != nil {
+		t.Fatalf("PatchJob without changes: %v", err)
+	}
+	if same.Version != job.Version {
+		t.Errorf("version after an empty patch = %d, want %d", same.Version, job.Version)
+	}
+	if revisions, updates := history(); revisions != 1 || updates != 0 {
+		t.Errorf("after an empty patch: %d revisions and %d audit updates, want 1 and 0", revisions, updates)
+	}
+
+	changed := *job
+	changed.Title = "Senior Engineer"
+	if err := jobs.PatchJob(ctx, job.ID, &changed); err != nil {
+		t.Fatalf("PatchJob: %v", err)
+	}
+	if changed.Version != job.Version+1 {
+		t.Errorf("version after a patch = %d, want %d", changed.Version, job.Version+1)
+	}
+	if revisions, updates := history(); revisions != 2 || updates != 1 {
+		t.Errorf("after a patch: %d revisions and %d audit updates, want 2 and 1", revisions, updates)
+	}
+
+	// A stale version still conflicts, even when nothing would change
+	stale := changed
+	stale.Version = job.Version
+	if err := jobs.PatchJob(ctx, job.ID, &stale); err != ErrVersionConflict {
+		t.Errorf("PatchJob at a stale version = %v, want ErrVersionConflict", err)
+	}
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SSOService handles SSO provider database operations
+type SSOService struct {
+	db *gorm.DB
+}
+
+// NewSSOService creates a new SSOService
+func NewSSOService(db *gorm.DB) *SSOService {
+	return &SSOService{db: db}
+}
+
+// GetSSOProvider retrieves a company's SSO provider, returning nil if it has none
+func (s *SSOService) GetSSOProvider(company string) (*SSOProvider, error) {
+	var provider SSOProvider
+	err := s.db.Where("LOWER(company) = LOWER(?)", company).First(&provider).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retrieve SSO provider: %w", err)
+	}
+	return &provider, nil
+}
+
+// SaveSSOProvider creates or replaces a company's SSO provider. An empty client secret
+// keeps the stored one, so the secret doesn't have to be resent with every change.
+func (s *SSOService) SaveSSOProvider(provider *SSOProvider) error {
+	existing, err := s.GetSSOProvider(provider.Company)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		provider.ID = existing.ID
+		provider.Company = existing.Company
+		provider.CreatedAt = existing.CreatedAt
+		if provider.ClientSecret == "" {
+			provider.ClientSecret = existing.ClientSecret
+		}
+	}
+	if err := s.db.Save(provider).Error; err != nil {
+		return fmt.Errorf("failed to save SSO provider: %w", err)
+	}
+	return nil
+}
+
+// DeleteSSOProvider removes a company's SSO provider
+func (s *SSOService) DeleteSSOProvider(company string) error {
+	result := s.db.Where("LOWER(company) = LOWER(?)", company).Delete(&SSOProvider{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete SSO provider: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("SSO provider for company %s not found", company)
+	}
+	return nil
+}
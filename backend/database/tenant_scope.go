@@ -0,0 +1,188 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+
+	"job-board/backend/tenant"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCrossTenant is returned when a request tries to create data for another company
+var ErrCrossTenant = errors.New("record belongs to another company")
+
+// RegisterTenantScopes makes every query on jobs, videos, applications, job revisions
+// and the audit trail respect the tenant carried by the statement's context, so a
+// company's drafts, videos, applications and their history can't be read or changed on
+// behalf of another company even if a caller forgets a check. Statements without a
+// tenant in their context are not restricted.
+func RegisterTenantScopes(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", scopeTenantRead); err != nil {
+		return err
+	}
+	// Scan and Rows go through the row callbacks rather than the query ones
+	if err := db.Callback().Row().Before("gorm:row").Register("tenant:row", scopeTenantRead); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", scopeTenantWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", scopeTenantWrite); err != nil {
+		return err
+	}
+	return db.Callback().Create().Before("gorm:create").Register("tenant:create", checkTenantCreate)
+}
+
+// scopeTenantRead restricts reads to published jobs and the tenant's own data
+func scopeTenantRead(db *gorm.DB) {
+	if t := tenant.FromContext(db.Statement.Context); t != nil {
+		if condition := tenantCondition(t, db.Statement.Table, false); condition != nil {
+			db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{condition}})
+		}
+	}
+}
+
+// scopeTenantWrite restricts updates and deletes to the tenant's own data
+func scopeTenantWrite(db *gorm.DB) {
+	if t := tenant.FromContext(db.Statement.Context); t != nil {
+		if condition := tenantCondition(t, db.Statement.Table, true); condition != nil {
+			db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{condition}})
+		}
+	}
+}
+
+// tenantCondition returns the condition limiting a table to what the tenant may read or write,
+// or nil for tables that aren't tenant-scoped
+func tenantCondition(t *tenant.Tenant, table string, write bool) clause.Expression {
+	column := func(name string) clause.Column {
+		return clause.Column{Table: clause.CurrentTable, Name: name}
+	}
+	// Everyone may read published jobs; only the company may read its drafts or write at all
+	companyJobs := "SELECT id FROM jobs WHERE LOWER(company) = LOWER(?)"
+	publishedJobs := "SELECT id FROM jobs WHERE status = ?"
+	visibleJobs := "SELECT id FROM jobs WHERE status = ? OR LOWER(company) = LOWER(?)"
+
+	switch table {
+	case Job{}.TableName():
+		if write {
+			if t.Company == "" {
+				return clause.Expr{SQL: "1 = 0"}
+			}
+			return clause.Expr{SQL: "LOWER(?) = LOWER(?)", Vars: []interface{}{column("company"), t.Company}}
+		}
+		if t.Company == "" {
+			return clause.Expr{SQL: "? = ?", Vars: []interface{}{column("status"), JobStatusPublished}}
+		}
+		return clause.Expr{
+			SQL:  "(? = ? OR LOWER(?) = LOWER(?))",
+			Vars: []interface{}{column("status"), JobStatusPublished, column("company"), t.Company},
+		}
+	case Video{}.TableName():
+		if write {
+			if t.Company == "" {
+				return clause.Expr{SQL: "1 = 0"}
+			}
+			return clause.Expr{SQL: "? IN (" + companyJobs + ")", Vars: []interface{}{column("job_id"), t.Company}}
+		}
+		if t.Company == "" {
+			return clause.Expr{SQL: "? IN (" + publishedJobs + ")", Vars: []interface{}{column("job_id"), JobStatusPublished}}
+		}
+		return clause.Expr{SQL: "? IN (" + visibleJobs + ")", Vars: []interface{}{column("job_id"), JobStatusPublished, t.Company}}
+	case Application{}.TableName():
+		// Companies see the applications to their jobs, candidates their own applications
+		if t.Company != "" {
+			return clause.Expr{SQL: "? IN (" + companyJobs + ")", Vars: []interface{}{column("job_id"), t.Company}}
+		}
+		return clause.Expr{SQL: "? = ?", Vars: []interface{}{column("candidate_id"), t.UserID}}
+	case JobRevision{}.TableName():
+		// Revisions include a job's drafts, so only its company sees them, even once it's published
+		if t.Company == "" {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+		return clause.Expr{SQL: "? IN (" + companyJobs + ")", Vars: []interface{}{column("job_id"), t.Company}}
+	case AuditEntry{}.TableName():
+		// Only site admins, who aren't restricted, read the trail; tenants just add to it
+		return clause.Expr{SQL: "1 = 0"}
+	}
+	return nil
+}
+
+// checkTenantCreate rejects new jobs, videos, applications and job revisions that don't
+// belong to the tenant
+func checkTenantCreate(db *gorm.DB) {
+	t := tenant.FromContext(db.Statement.Context)
+	if t == nil || db.Statement.Schema == nil {
+		return
+	}
+
+	switch db.Statement.Table {
+	case Job{}.TableName():
+		for _, company := range fieldValues(db, "Company") {
+			if !t.IsCompany(company.(string)) {
+				db.AddError(ErrCrossTenant)
+				return
+			}
+		}
+	case Video{}.TableName(), JobRevision{}.TableName():
+		jobIDs := fieldValues(db, "JobID")
+		if t.Company == "" {
+			db.AddError(ErrCrossTenant)
+			return
+		}
+		var count int64
+		err := db.Session(&gorm.Session{NewDB: true}).Model(&Job{}).
+			Where("id IN ? AND LOWER(company) = LOWER(?)", jobIDs, t.Company).
+			Count(&count).Error
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if count != int64(len(uniqueValues(jobIDs))) {
+			db.AddError(ErrCrossTenant)
+		}
+	case Application{}.TableName():
+		for _, candidateID := range fieldValues(db, "CandidateID") {
+			if candidateID.(uint) != t.UserID {
+				db.AddError(ErrCrossTenant)
+				return
+			}
+		}
+	}
+}
+
+// fieldValues returns the values of a field across the records being created
+func fieldValues(db *gorm.DB, name string) []interface{} {
+	field := db.Statement.Schema.LookUpField(name)
+	if field == nil {
+		return nil
+	}
+
+	var values []interface{}
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			value, _ := field.ValueOf(db.Statement.Context, reflect.Indirect(rv.Index(i)))
+			values = append(values, value)
+		}
+	case reflect.Struct:
+		value, _ := field.ValueOf(db.Statement.Context, rv)
+		values = append(values, value)
+	}
+	return values
+}
+
+// uniqueValues removes duplicates from values
+func uniqueValues(values []interface{}) []interface{} {
+	seen := make(map[interface{}]bool, len(values))
+	unique := values[:0:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,372 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"job-board/backend/tenant"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// dryRunDB returns a database with the tenant scopes registered that builds SQL without
+// connecting, so the conditions the scopes add can be checked
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		Logger:                 gormlogger.Discard,
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTenantScopes(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+var (
+	companyB  = tenant.WithTenant(context.Background(), &tenant.Tenant{Company: "Company B", UserID: 2})
+	candidate = tenant.WithTenant(context.Background(), &tenant.Tenant{UserID: 3})
+	siteAdmin = tenant.WithTenant(context.Background(), &tenant.Tenant{UserID: 1, Unrestricted: true})
+)
+
+func TestTenantScopeReads(t *testing.T) {
+	db := dryRunDB(t)
+	var latest int
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		query func(tx *gorm.DB) *gorm.DB
+		want  string
+	}{
+		{
+			"jobs", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]Job{}) },
+			`("jobs"."status" = 'published' OR LOWER("jobs"."company") = LOWER('Company B'))`,
+		},
+		{
+			"jobs of a candidate", candidate,
+			func(tx *gorm.DB) *gorm.DB { return tx.First(&Job{}, 1) },
+			`"jobs"."status" = 'published'`,
+		},
+		{
+			"videos", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.First(&Video{}, 1) },
+			`"videos"."job_id" IN (SELECT id FROM jobs WHERE status = 'published' OR LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"applications", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]Application{}) },
+			`"applications"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"applications of a candidate", candidate,
+			func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]Application{}) },
+			`"applications"."candidate_id" = 3`,
+		},
+		{
+			"job revisions", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Where("job_id = ?", 1).Find(&[]JobRevision{}) },
+			`"job_revisions"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"job revisions of a candidate", candidate,
+			func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]JobRevision{}) },
+			`1 = 0`,
+		},
+		{
+			"scanned job revisions", companyB,
+			func(tx *gorm.DB) *gorm.DB {
+				return tx.Model(&JobRevision{}).Where("job_id = ?", 1).Select("COALESCE(MAX(version), 0)").Scan(&latest)
+			},
+			`"job_revisions"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"audit entries", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Where("entity_type = ?", AuditEntityJob).Find(&[]AuditEntry{}) },
+			`1 = 0`,
+		},
+		{
+			"counted audit entries", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Model(&AuditEntry{}).Count(new(int64)) },
+			`1 = 0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB { return tt.query(tx.WithContext(tt.ctx)) })
+			if !strings.Contains(sql, tt.want) {
+				t.Errorf("query is not scoped:\n%s\nwant it to contain\n%s", sql, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantScopeWrites(t *testing.T) {
+	db := dryRunDB(t)
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		query func(tx *gorm.DB) *gorm.DB
+		want  string
+	}{
+		{
+			"job update", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Model(&Job{ID: 1}).Update("title", "Taken") },
+			`LOWER("jobs"."company") = LOWER('Company B')`,
+		},
+		{
+			"job delete", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Delete(&Job{}, 1) },
+			`LOWER("jobs"."company") = LOWER('Company B')`,
+		},
+		{
+			"job update by a candidate", candidate,
+			func(tx *gorm.DB) *gorm.DB { return tx.Model(&Job{ID: 1}).Update("title", "Taken") },
+			`1 = 0`,
+		},
+		{
+			"video update", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Model(&Video{ID: 1}).Update("title", "Taken") },
+			`"videos"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"video delete", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Delete(&Video{}, 1) },
+			`"videos"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"application update", companyB,
+			func(tx *gorm.DB) *gorm.DB {
+				return tx.Model(&Application{ID: 1}).Update("status", ApplicationRejected)
+			},
+			`"applications"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"application delete by a candidate", candidate,
+			func(tx *gorm.DB) *gorm.DB { return tx.Delete(&Application{}, 1) },
+			`"applications"."candidate_id" = 3`,
+		},
+		{
+			"job revision delete", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Where("job_id = ?", 1).Delete(&JobRevision{}) },
+			`"job_revisions"."job_id" IN (SELECT id FROM jobs WHERE LOWER(company) = LOWER('Company B'))`,
+		},
+		{
+			"audit entry update", companyB,
+			func(tx *gorm.DB) *gorm.DB { return tx.Model(&AuditEntry{ID: 1}).Update("action", "forged") },
+			`1 = 0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB { return tt.query(tx.WithContext(tt.ctx)) })
+			if !strings.Contains(sql, tt.want) {
+				t.Errorf("statement is not scoped:\n%s\nwant it to contain\n%s", sql, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantScopeUnrestricted(t *testing.T) {
+	db := dryRunDB(t)
+	for _, ctx := range []context.Context{context.Background(), siteAdmin} {
+		sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB { return tx.WithContext(ctx).First(&Job{}, 1) })
+		if strings.Contains(sql, "company") || strings.Contains(sql, "status") {
+			t.Errorf("unrestricted query is scoped: %s", sql)
+		}
+	}
+}
+
+func TestTenantCreateRejectsOtherCompanies(t *testing.T) {
+	db := dryRunDB(t)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		record interface{}
+	}{
+		{"job of another company", companyB, &Job{Company: "Company A", Title: "Engineer"}},
+		{"job by a candidate", candidate, &Job{Company: "Company A", Title: "Engineer"}},
+		// Dry runs find no jobs, so the job of any video or revision counts as another company's
+		{"video on another company's job", companyB, &Video{JobID: 1, Title: "Intro"}},
+		{"video by a candidate", candidate, &Video{JobID: 1, Title: "Intro"}},
+		{"revision of another company's job", companyB, &JobRevision{JobID: 1, Version: 2}},
+		{"application for another candidate", candidate, &Application{JobID: 1, CandidateID: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.WithContext(tt.ctx).Create(tt.record).Error; err != ErrCrossTenant {
+				t.Errorf("Create = %v, want ErrCrossTenant", err)
+			}
+		})
+	}
+
+	// The audit trail records every tenant's changes
+	if err := db.WithContext(companyB).Create(&AuditEntry{Action: AuditActionUpdate}).Error; err != nil {
+		t.Errorf("Create audit entry = %v", err)
+	}
+}
+
+// TestTenantIsolation checks against a real database that company B can't list, read,
+// change or delete company A's jobs, drafts, videos, applications, revisions or audit
+// entries. It needs a PostgreSQL database in TEST_DATABASE_URL.
+func TestTenantIsolation(t *testing.T) {
+	testDatabase(t)
+
+	suffix := time.Now().UnixNano()
+	companyA := fmt.Sprintf("Tenant A %d", suffix)
+	ctxA := tenant.WithTenant(context.Background(), &tenant.Tenant{Company: companyA, UserID: 1})
+	ctxB := tenant.WithTenant(context.Background(), &tenant.Tenant{Company: fmt.Sprintf("Tenant B %d", suffix), UserID: 2})
+
+	jobs := NewJobService(DB)
+	videos := NewVideoService(DB)
+	applications := NewApplicationService(DB)
+
+	// Company A's data; audit entries are append-only, so they stay behind
+	candidateUser := &User{Email: fmt.Sprintf("candidate.%d@example.com", suffix), Name: "Candidate", Role: RoleCandidate}
+	if err := DB.Create(candidateUser).Error; err != nil {
+		t.Fatal(err)
+	}
+	published := &Job{Title: "Published", Company: companyA, Location: "Remote", Status: JobStatusPublished}
+	draft := &Job{Title: "Draft", Company: companyA, Location: "Remote", Status: JobStatusDraft}
+	for _, job := range []*Job{published, draft} {
+		if err := jobs.CreateJob(ctxA, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publishedVideo := &Video{JobID: published.ID, Title: "Published intro", URL: "/video/published"}
+	draftVideo := &Video{JobID: draft.ID, Title: "Draft intro", URL: "/video/draft"}
+	for _, video := range []*Video{publishedVideo, draftVideo} {
+		if err := videos.CreateVideo(ctxA, video); err != nil {
+			t.Fatal(err)
+		}
+	}
+	application := &Application{JobID: published.ID, CandidateID: candidateUser.ID}
+	if err := DB.Create(application).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		DB.Unscoped().Where("job_id IN ?", []uint{published.ID, draft.ID}).Delete(&Application{})
+		DB.Unscoped().Where("job_id IN ?", []uint{published.ID, draft.ID}).Delete(&Video{})
+		DB.Where("job_id IN ?", []uint{published.ID, draft.ID}).Delete(&JobRevision{})
+		DB.Unscoped().Delete(&Job{}, []uint{published.ID, draft.ID})
+		DB.Unscoped().Delete(candidateUser)
+	})
+
+	// Listing
+	listed, err := jobs.GetJobsByCompany(ctxB, companyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range listed {
+		if job.ID == draft.ID {
+			t.Error("company B listed company A's draft")
+		}
+	}
+	allVideos, err := videos.GetAllVideos(ctxB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, video := range allVideos {
+		if video.ID == draftVideo.ID {
+			t.Error("company B listed the video of company A's draft")
+		}
+	}
+	if listed, err := applications.GetApplicationsByCompany(ctxB, companyA); err != nil || len(listed) != 0 {
+		t.Errorf("company B listed company A's applications: %d, %v", len(listed), err)
+	}
+	if listed, err := applications.GetApplicationsByJob(ctxB, published.ID); err != nil || len(listed) != 0 {
+		t.Errorf("company B listed the applications to company A's job: %d, %v", len(listed), err)
+	}
+	if revisions, err := jobs.GetJobRevisions(ctxB, published.ID); err != nil || len(revisions) != 0 {
+		t.Errorf("company B listed the revisions of company A's job: %d, %v", len(revisions), err)
+	}
+	if _, err := jobs.GetJobRevisions(ctxB, draft.ID); err == nil {
+		t.Error("company B listed the revisions of company A's draft")
+	}
+	entries, total, err := NewAuditService(DB).GetEntries(ctxB, AuditFilter{EntityType: AuditEntityJob, EntityID: draft.ID}, 1, 50)
+	if err != nil || total != 0 || len(entries) != 0 {
+		t.Errorf("company B read company A's audit entries: %d, %v", total, err)
+	}
+
+	// Reading
+	if _, err := jobs.GetJobByID(ctxB, draft.ID); err == nil {
+		t.Error("company B read company A's draft")
+	}
+	if _, err := videos.GetVideoByID(ctxB, draftVideo.ID); err == nil {
+		t.Error("company B read the video of company A's draft")
+	}
+	if _, err := applications.GetApplicationByID(ctxB, application.ID); err == nil {
+		t.Error("company B read an application to company A's job")
+	}
+	if _, err := jobs.GetJobRevision(ctxB, published.ID, 1); err == nil {
+		t.Error("company B read a revision of company A's job")
+	}
+
+	// Changing
+	if err := jobs.UpdateJob(ctxB, published.ID, &Job{Title: "Taken", Company: companyA, Location: "Remote"}); err == nil {
+		t.Error("company B updated company A's job")
+	}
+	if err := jobs.UpdateJob(ctxB, draft.ID, &Job{Title: "Taken", Company: companyA, Location: "Remote"}); err == nil {
+		t.Error("company B updated company A's draft")
+	}
+	if err := videos.UpdateVideo(ctxB, publishedVideo.ID, &Video{JobID: published.ID, Title: "Taken", URL: "/video/taken"}); err == nil {
+		t.Error("company B updated company A's video")
+	}
+	if err := applications.UpdateApplicationStatus(ctxB, application.ID, ApplicationRejected); err == nil {
+		t.Error("company B updated an application to company A's job")
+	}
+	if _, err := jobs.RestoreJobRevision(ctxB, published.ID, 1); err == nil {
+		t.Error("company B restored a revision of company A's job")
+	}
+
+	// Deleting
+	if err := jobs.DeleteJob(ctxB, published.ID, 0); err == nil {
+		t.Error("company B deleted company A's job")
+	}
+	if err := videos.DeleteVideo(ctxB, publishedVideo.ID); err == nil {
+		t.Error("company B deleted company A's video")
+	}
+
+	// Everything is still there, unchanged, for company A
+	for _, id := range []uint{published.ID, draft.ID} {
+		job, err := jobs.GetJobByID(ctxA, id)
+		if err != nil {
+			t.Fatalf("company A lost its job %d: %v", id, err)
+		}
+		if job.Title == "Taken" || job.Version != 1 {
+			t.Errorf("job %d was changed: %+v", id, job)
+		}
+	}
+	for _, id := range []uint{publishedVideo.ID, draftVideo.ID} {
+		video, err := videos.GetVideoByID(ctxA, id)
+		if err != nil {
+			t.Fatalf("company A lost its video %d: %v", id, err)
+		}
+		if video.Title == "Taken" {
+			t.Errorf("video %d was changed", id)
+		}
+	}
+	stored, err := applications.GetApplicationByID(ctxA, application.ID)
+	if err != nil {
+		t.Fatalf("company A lost its application: %v", err)
+	}
+	if stored.Status == ApplicationRejected {
+		t.Error("application status was changed")
+	}
+	if revisions, err := jobs.GetJobRevisions(ctxA, published.ID); err != nil || len(revisions) != 1 {
+		t.Errorf("company A's revisions = %d, %v; want 1", len(revisions), err)
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"errors"
+
+	"job-board/backend/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// querySpanKey is the statement setting holding a query's span
+const querySpanKey = "tracing:span"
+
+// RegisterQueryTracing creates a span for every query, as a child of the span in the
+// statement's context
+func RegisterQueryTracing(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("tracing:before_create", startSpan("create")),
+		callback.Create().After("*").Register("tracing:after_create", endSpan("create")),
+		callback.Query().Before("*").Register("tracing:before_query", startSpan("query")),
+		callback.Query().After("*").Register("tracing:after_query", endSpan("query")),
+		callback.Update().Before("*").Register("tracing:before_update", startSpan("update")),
+		callback.Update().After("*").Register("tracing:after_update", endSpan("update")),
+		callback.Delete().Before("*").Register("tracing:before_delete", startSpan("delete")),
+		callback.Delete().After("*").Register("tracing:after_delete", endSpan("delete")),
+		callback.Row().Before("*").Register("tracing:before_row", startSpan("row")),
+		callback.Row().After("*").Register("tracing:after_row", endSpan("row")),
+		callback.Raw().Before("*").Register("tracing:before_raw", startSpan("raw")),
+		callback.Raw().After("*").Register("tracing:after_raw", endSpan("raw")),
+	)
+}
+
+// startSpan starts the span of a query
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			// Queries outside a request, e.g. from background workers, don't start traces
+			return
+		}
+		_, span := tracing.Start(ctx, operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperation(operation)),
+		)
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+// endSpan ends the span of a query once it finished, naming it after the operation and table
+func endSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(querySpanKey)
+		if !ok {
+			return
+		}
+		span, ok := value.(trace.Span)
+		if !ok {
+			return
+		}
+		if table := db.Statement.Table; table != "" {
+			span.SetName(operation + " " + table)
+			span.SetAttributes(semconv.DBSQLTable(table))
+		}
+		span.SetAttributes(semconv.DBStatement(db.Statement.SQL.String()))
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+		span.End()
+	}
+}
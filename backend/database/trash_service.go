@@ -0,0 +1,352 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrJobInTrash is returned when restoring a video whose job is still in the trash
+var ErrJobInTrash = errors.New("the video's job is in the trash")
+
+// Trash entry types
+const (
+	TrashTypeJob   = "job"
+	TrashTypeVideo = "video"
+)
+
+// trashEntries lists soft-deleted jobs and videos; videos are listed under their job's company
+const trashEntries = `(SELECT 'job' AS type, id, NULL::bigint AS job_id, title, company, deleted_at
+	FROM jobs WHERE deleted_at IS NOT NULL
+UNION ALL
+SELECT 'video', videos.id, videos.job_id, videos.title, jobs.company, videos.deleted_at
+	FROM videos JOIN jobs ON jobs.id = videos.job_id WHERE videos.deleted_at IS NOT NULL) AS trash`
+
+// TrashEntry is a soft-deleted job or video
+type TrashEntry struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	JobID     *uint     `json:"jobId,omitempty"`
+	Title     string    `json:"title"`
+	Company   string    `json:"company"`
+	DeletedAt time.Time `json:"deletedAt"`
+	// PurgeAt is when the retention policy permanently deletes the entry, if it does
+	PurgeAt *time.Time `json:"purgeAt,omitempty" gorm:"-"`
+}
+
+// TrashFilter selects trash entries; zero fields match everything
+type TrashFilter struct {
+	Type    string
+	Company string
+}
+
+// TrashService lists, restores and permanently deletes soft-deleted jobs and videos
+type TrashService struct {
+	db *gorm.DB
+}
+
+// NewTrashService creates a new TrashService
+func NewTrashService(db *gorm.DB) *TrashService {
+	return &TrashService{db: db}
+}
+
+// query selects the trash entries matching filter
+func (s *TrashService) query(ctx context.Context, filter TrashFilter) *gorm.DB {
+	query := s.db.WithContext(ctx).Table(trashEntries)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Company != "" {
+		query = query.Where("LOWER(company) = LOWER(?)", filter.Company)
+	}
+	return query
+}
+
+// GetEntries retrieves a page of the trash matching the filter, most recently deleted first
+func (s *TrashService) GetEntries(ctx context.Context, filter TrashFilter, page, pageSize int) ([]TrashEntry, int64, error) {
+	var total int64
+	if err := s.query(ctx, filter).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count trash entries: %w", err)
+	}
+
+	var entries []TrashEntry
+	err := s.query(ctx, filter).
+		Order("deleted_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve trash entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// GetJob retrieves a job in the trash with all its videos
+func (s *TrashService) GetJob(ctx context.Context, id uint) (*Job, error) {
+	var job Job
+	err := s.db.WithContext(ctx).Unscoped().Preload("Videos").Where("deleted_at IS NOT NULL").First(&job, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("job with ID %d not found in the trash", id)
+		}
+		return nil, fmt.Errorf("failed to retrieve job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetVideo retrieves a video in the trash
+func (s *TrashService) GetVideo(ctx context.Context, id uint) (*Video, error) {
+	var video Video
+	err := s.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&video, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("video with ID %d not found in the trash", id)
+		}
+		return nil, fmt.Errorf("failed to retrieve video: %w", err)
+	}
+	return &video, nil
+}
+
+// lockTrashed locks a record in the trash within tx
+func lockTrashed[T any](tx *gorm.DB, id uint) (*T, error) {
+	var record T
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("deleted_at IS NOT NULL").First(&record, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// untrash clears the deletion of records within tx, bumping their version, and records
+// their restore in the audit trail
+func untrash[T any](ctx context.Context, tx *gorm.DB, entityType string, ids []uint, before []T) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := tx.Unscoped().Model(new(T)).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"version":    gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		var after T
+		if err := tx.First(&after, id).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionRestore, entityType, id, &before[i], &after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreJob takes a job out of the trash along with the videos deleted with it. Videos
+// deleted on their own before the job stay in the trash.
+func (s *TrashService) RestoreJob(ctx context.Context, id uint) (*Job, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		job, err := lockTrashed[Job](tx, id)
+		if err != nil {
+			return err
+		}
+		var videos []Video
+		err = tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("job_id = ? AND deleted_at = ?", id, job.DeletedAt.Time).
+			Find(&videos).Error
+		if err != nil {
+			return err
+		}
+
+		if err := untrash(ctx, tx, AuditEntityJob, []uint{id}, []Job{*job}); err != nil {
+			return err
+		}
+		videoIDs := make([]uint, len(videos))
+		for i := range videos {
+			videoIDs[i] = videos[i].ID
+		}
+		return untrash(ctx, tx, AuditEntityVideo, videoIDs, videos)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("job with ID %d not found in the trash", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore job: %w", err)
+	}
+
+	var job Job
+	if err := s.db.WithContext(ctx).Preload("Videos").First(&job, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve job: %w", err)
+	}
+	return &job, nil
+}
+
+// RestoreVideo takes a video out of the trash. ErrJobInTrash is returned if its job is
+// in the trash too; restoring the job restores the videos deleted with it.
+func (s *TrashService) RestoreVideo(ctx context.Context, id uint) (*Video, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		video, err := lockTrashed[Video](tx, id)
+		if err != nil {
+			return err
+		}
+		var job Job
+		if err := tx.Unscoped().Select("id", "deleted_at").First(&job, video.JobID).Error; err != nil {
+			return err
+		}
+		if job.DeletedAt.Valid {
+			return ErrJobInTrash
+		}
+		return untrash(ctx, tx, AuditEntityVideo, []uint{id}, []Video{*video})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("video with ID %d not found in the trash", id)
+	}
+	if err == ErrJobInTrash {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore video: %w", err)
+	}
+
+	var video Video
+	if err := s.db.WithContext(ctx).First(&video, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve video: %w", err)
+	}
+	return &video, nil
+}
+
+// PurgeJob permanently deletes a job in the trash along with its videos, applications and
+// revisions. It returns the URLs of the purged videos that no remaining video uses, whose
+// files can be removed.
+func (s *TrashService) PurgeJob(ctx context.Context, id uint) ([]string, error) {
+	var unused []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		job, err := lockTrashed[Job](tx, id)
+		if err != nil {
+			return err
+		}
+		// Purged rows are deleted outright, including ones already soft-deleted
+		tx = tx.Unscoped().Session(&gorm.Session{})
+
+		var applications []Application
+		if err := tx.Where("job_id = ?", id).Find(&applications).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", id).Delete(&Application{}).Error; err != nil {
+			return err
+		}
+		for i := range applications {
+			if err := recordAudit(ctx, tx, AuditActionPurge, AuditEntityApplication, applications[i].ID, &applications[i], nil); err != nil {
+				return err
+			}
+		}
+
+		var videos []Video
+		if err := tx.Where("job_id = ?", id).Find(&videos).Error; err != nil {
+			return err
+		}
+		if unused, err = purgeVideos(ctx, tx, videos); err != nil {
+			return err
+		}
+
+		if err := tx.Where("job_id = ?", id).Delete(&JobRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&Job{}, id).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditActionPurge, AuditEntityJob, id, job, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("job with ID %d not found in the trash", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge job: %w", err)
+	}
+	return unused, nil
+}
+
+// PurgeVideo permanently deletes a video in the trash. Like PurgeJob, it returns the
+// video's URL if no remaining video uses it.
+func (s *TrashService) PurgeVideo(ctx context.Context, id uint) ([]string, error) {
+	var unused []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		video, err := lockTrashed[Video](tx, id)
+		if err != nil {
+			return err
+		}
+		unused, err = purgeVideos(ctx, tx.Unscoped().Session(&gorm.Session{}), []Video{*video})
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("video with ID %d not found in the trash", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge video: %w", err)
+	}
+	return unused, nil
+}
+
+// purgeVideos permanently deletes videos within tx, which must be unscoped, and returns
+// the URLs no remaining video uses
+func purgeVideos(ctx context.Context, tx *gorm.DB, videos []Video) ([]string, error) {
+	if len(videos) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(videos))
+	urls := make([]string, len(videos))
+	for i := range videos {
+		ids[i] = videos[i].ID
+		urls[i] = videos[i].URL
+	}
+
+	if err := tx.Where("id IN ?", ids).Delete(&Video{}).Error; err != nil {
+		return nil, err
+	}
+	for i := range videos {
+		if err := recordAudit(ctx, tx, AuditActionPurge, AuditEntityVideo, videos[i].ID, &videos[i], nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var used []string
+	if err := tx.Model(&Video{}).Where("url IN ?", urls).Distinct().Pluck("url", &used).Error; err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool, len(used))
+	for _, url := range used {
+		inUse[url] = true
+	}
+	var unused []string
+	for _, url := range urls {
+		if !inUse[url] {
+			inUse[url] = true
+			unused = append(unused, url)
+		}
+	}
+	return unused, nil
+}
+
+// GetExpiredJobs returns the IDs of jobs deleted before the given time
+func (s *TrashService) GetExpiredJobs(ctx context.Context, before time.Time) ([]uint, error) {
+	var ids []uint
+	err := s.db.WithContext(ctx).Unscoped().Model(&Job{}).Where("deleted_at < ?", before).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve expired jobs: %w", err)
+	}
+	return ids, nil
+}
+
+// GetExpiredVideos returns the IDs of videos deleted before the given time
+func (s *TrashService) GetExpiredVideos(ctx context.Context, before time.Time) ([]uint, error) {
+	var ids []uint
+	err := s.db.WithContext(ctx).Unscoped().Model(&Video{}).Where("deleted_at < ?", before).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve expired videos: %w", err)
+	}
+	return ids, nil
+}
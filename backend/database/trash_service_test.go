@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestTrashRestore deletes a job and its videos and restores them. It needs a PostgreSQL
+// database in TEST_DATABASE_URL.
+func TestTrashRestore(t *testing.T) {
+	testDatabase(t)
+	ctx := context.Background()
+	jobs := NewJobService(DB)
+	videos := NewVideoService(DB)
+	trash := NewTrashService(DB)
+
+	job := &Job{Title: "Engineer", Company: fmt.Sprintf("Trash %d", time.Now().UnixNano()), Location: "Remote"}
+	if err := jobs.CreateJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Unscoped().Delete(&Job{}, job.ID) })
+	t.Cleanup(func() { DB.Where("job_id = ?", job.ID).Delete(&JobRevision{}) })
+	t.Cleanup(func() { DB.Unscoped().Where("job_id = ?", job.ID).Delete(&Video{}) })
+
+	kept := &Video{JobID: job.ID, Title: "Intro", URL: "https://videos.example.com/intro.mp4"}
+	deletedFirst := &Video{JobID: job.ID, Title: "Outtakes", URL: "https://videos.example.com/outtakes.mp4"}
+	for _, video := range []*Video{kept, deletedFirst} {
+		if err := videos.CreateVideo(ctx, video); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A video deleted on its own stays in the trash when its job is restored
+	if err := videos.DeleteVideo(ctx, deletedFirst.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := jobs.DeleteJob(ctx, job.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	entries, total, err := trash.GetEntries(ctx, TrashFilter{Company: job.Company}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(entries) != 3 {
+		t.Errorf("trash of %s has %d entries, want the job and both videos", job.Company, total)
+	}
+
+	if _, err := trash.RestoreVideo(ctx, kept.ID); err != ErrJobInTrash {
+		t.Errorf("RestoreVideo while the job is in the trash = %v, want ErrJobInTrash", err)
+	}
+
+	restored, err := trash.RestoreJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("RestoreJob: %v", err)
+	}
+	if restored.DeletedAt.Valid || restored.Version != job.Version+1 {
+		t.Errorf("restored job deleted at %v, version %d; want it live at version %d", restored.DeletedAt, restored.Version, job.Version+1)
+	}
+	if len(restored.Videos) != 1 || restored.Videos[0].ID != kept.ID {
+		t.Errorf("restored job has videos %+v, want only %d", restored.Videos, kept.ID)
+	}
+	if _, err := trash.RestoreJob(ctx, job.ID); err == nil {
+		t.Error("restoring a job that isn't in the trash succeeded")
+	}
+
+	video, err := trash.RestoreVideo(ctx, deletedFirst.ID)
+	if err != nil {
+		t.Fatalf("RestoreVideo: %v", err)
+	}
+	if video.DeletedAt.Valid {
+		t.Errorf("restored video still deleted at %v", video.DeletedAt)
+	}
+
+	var restores int64
+	DB.Model(&AuditEntry{}).Where("action = ? AND ((entity_type = ? AND entity_id = ?) OR (entity_type = ? AND entity_id IN ?))",
+		AuditActionRestore, AuditEntityJob, job.ID, AuditEntityVideo, []uint{kept.ID, deletedFirst.ID}).Count(&restores)
+	if restores != 3 {
+		t.Errorf("%d restores in the audit trail, want 3", restores)
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SetTOTPSecret stores a pending TOTP secret for a user, replacing any enrollment in progress
+func (s *UserService) SetTOTPSecret(userID uint, secret string) error {
+	err := s.db.Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP turns on two-factor authentication for a user and replaces their recovery codes
+func (s *UserService) EnableTOTP(userID uint, step int64, codeHashes []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error
+		if err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// DisableTOTP turns off two-factor authentication for a user and deletes their recovery codes
+func (s *UserService) DisableTOTP(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error
+		if err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns false if a code of
+// the same or a later step was already used, so concurrent replays can't both succeed.
+func (s *UserService) UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := s.db.Model(&User{}).Where("id = ? AND totp_last_step < ?", userID, step).Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ClaimTOTPAttempt counts an attempt at a two-factor code before it's checked, so
+// concurrent guesses can't get past the limit. It returns false if verification is
+// locked. The attempt that reaches maxAttempts locks verification until now+lockout and
+// starts the count over; ResetTOTPAttempts clears both once a code is accepted.
+func (s *UserService) ClaimTOTPAttempt(userID uint, now time.Time, maxAttempts int, lockout time.Duration) (bool, error) {
+	result := s.db.Model(&User{}).
+		Where("id = ? AND (totp_locked_until IS NULL OR totp_locked_until <= ?)", userID, now).
+		Updates(map[string]interface{}{
+			"totp_attempts":     gorm.Expr("CASE WHEN totp_attempts + 1 >= ? THEN 0 ELSE totp_attempts + 1 END", maxAttempts),
+			"totp_locked_until": gorm.Expr("CASE WHEN totp_attempts + 1 >= ? THEN ? ELSE totp_locked_until END", maxAttempts, now.Add(lockout)),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to count two-factor attempt: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ResetTOTPAttempts clears a user's count of two-factor attempts and any lock
+func (s *UserService) ResetTOTPAttempts(userID uint) error {
+	err := s.db.Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"totp_attempts": 0, "totp_locked_until": nil}).Error
+	if err != nil {
+		return fmt.Errorf("failed to reset two-factor attempts: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all of a user's recovery codes
+func (s *UserService) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code as used, returning false if there is none
+func (s *UserService) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := s.db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (s *UserService) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores new ones within a transaction
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	if err := tx.Create(&codes).Error; err != nil {
+		return fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageService handles video storage and bandwidth accounting
+type UsageService struct {
+	db *gorm.DB
+}
+
+// NewUsageService creates a new UsageService
+func NewUsageService(db *gorm.DB) *UsageService {
+	return &UsageService{db: db}
+}
+
+// StorageUsedByCompany returns the total size of the stored videos of a company's jobs,
+// whatever the case the jobs spell the company in. Videos in the trash count until
+// they're purged, as their files are still stored.
+func (s *UsageService) StorageUsedByCompany(company string) (int64, error) {
+	var total int64
+	err := s.db.Unscoped().Model(&Video{}).
+		Joins("JOIN jobs ON jobs.id = videos.job_id").
+		Where("LOWER(jobs.company) = LOWER(?)", company).
+		Select("COALESCE(SUM(videos.size_bytes), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute storage usage: %w", err)
+	}
+	return total, nil
+}
+
+// AddBytesServed adds streamed bytes and requests to a video's bandwidth usage
+func (s *UsageService) AddBytesServed(videoID uint, company string, bytes, requests int64) error {
+	usage := VideoUsage{VideoID: videoID, Company: company, BytesServed: bytes, Requests: requests}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "video_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"company":      company,
+			"bytes_served": gorm.Expr("video_usage.bytes_served + ?", bytes),
+			"requests":     gorm.Expr("video_usage.requests + ?", requests),
+			"updated_at":   gorm.Expr("NOW()"),
+		}),
+	}).Create(&usage).Error
+	if err != nil {
+		return fmt.Errorf("failed to record bandwidth usage: %w", err)
+	}
+	return nil
+}
+
+// GetVideoUsage retrieves the bandwidth usage of a video
+func (s *UsageService) GetVideoUsage(videoID uint) (*VideoUsage, error) {
+	usage := VideoUsage{VideoID: videoID}
+	if err := s.db.Where("video_id = ?", videoID).Limit(1).Find(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve video usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// GetCompanyUsage retrieves the bandwidth usage of every video of a company
+func (s *UsageService) GetCompanyUsage(company string) ([]VideoUsage, error) {
+	var usage []VideoUsage
+	if err := s.db.Where("LOWER(company) = LOWER(?)", company).Order("bytes_served DESC").Find(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve company usage: %w", err)
+	}
+	return usage, nil
+}
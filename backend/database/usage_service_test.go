@@ -0,0 +1,125 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recordSQL returns the statements run on a dry run database
+func recordSQL(t *testing.T, db *gorm.DB) *[]string {
+	t.Helper()
+	var statements []string
+	record := func(tx *gorm.DB) { statements = append(statements, tx.Statement.SQL.String()) }
+	if err := db.Callback().Query().After("gorm:query").Register("test:query", record); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("test:row", record); err != nil {
+		t.Fatal(err)
+	}
+	return &statements
+}
+
+func TestCompanyLookupsIgnoreCase(t *testing.T) {
+	db := dryRunDB(t)
+	statements := recordSQL(t, db)
+	usage := NewUsageService(db)
+	companies := NewCompanyService(db)
+
+	tests := []struct {
+		name   string
+		lookup func() error
+		want   string
+	}{
+		{
+			"storage used",
+			func() error { _, err := usage.StorageUsedByCompany("Acme"); return err },
+			"LOWER(jobs.company) = LOWER($1)",
+		},
+		{
+			"bandwidth used",
+			func() error { _, err := usage.GetCompanyUsage("Acme"); return err },
+			"LOWER(company) = LOWER($1)",
+		},
+		{
+			"company settings",
+			func() error { _, err := companies.GetCompanyByName("Acme"); return err },
+			"LOWER(name) = LOWER($1)",
+		},
+	}
+	for _, tt := range tests {
+		*statements = nil
+		// Scan can't run without a database, but its statement is built first
+		if err := tt.lookup(); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(*statements) != 1 || !strings.Contains((*statements)[0], tt.want) {
+			t.Errorf("%s ran %q, want a statement containing %s", tt.name, *statements, tt.want)
+		}
+	}
+}
+
+// TestStorageUsedAcrossCaseVariants checks that jobs posted under different spellings of
+// a company share its usage and quota. It needs a PostgreSQL database in TEST_DATABASE_URL.
+func TestStorageUsedAcrossCaseVariants(t *testing.T) {
+	testDatabase(t)
+
+	name := fmt.Sprintf("Acme %d", time.Now().UnixNano())
+	variants := []string{name, strings.ToLower(name), strings.ToUpper(name)}
+	var jobIDs []uint
+	t.Cleanup(func() {
+		DB.Unscoped().Where("job_id IN ?", jobIDs).Delete(&Video{})
+		DB.Unscoped().Delete(&Job{}, jobIDs)
+		DB.Where("LOWER(name) = LOWER(?)", name).Delete(&Company{})
+	})
+	for i, company := range variants {
+		job := &Job{Title: "Engineer", Company: company, Location: "Remote", Status: JobStatusDraft}
+		if err := DB.Create(job).Error; err != nil {
+			t.Fatal(err)
+		}
+		jobIDs = append(jobIDs, job.ID)
+		size := int64(100 * (i + 1))
+		video := &Video{JobID: job.ID, Title: "Intro", URL: fmt.Sprintf("/video/%d", job.ID), SizeBytes: &size}
+		if err := DB.Create(video).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage := NewUsageService(DB)
+	for _, company := range variants {
+		used, err := usage.StorageUsedByCompany(company)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != 600 {
+			t.Errorf("StorageUsedByCompany(%q) = %d, want 600", company, used)
+		}
+	}
+
+	companies := NewCompanyService(DB)
+	quota := int64(1000)
+	if _, err := companies.SetStorageQuota(variants[1], &quota); err != nil {
+		t.Fatal(err)
+	}
+	for _, company := range variants {
+		settings, err := companies.GetCompanyByName(company)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings == nil || settings.StorageQuotaBytes == nil || *settings.StorageQuotaBytes != quota {
+			t.Errorf("GetCompanyByName(%q) = %+v, want the quota set for %q", company, settings, variants[1])
+		}
+	}
+	created, err := companies.GetOrCreateCompany(variants[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != variants[1] {
+		t.Errorf("GetOrCreateCompany(%q) created %q rather than finding %q", variants[2], created.Name, variants[1])
+	}
+}
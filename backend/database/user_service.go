@@ -0,0 +1,193 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmailTaken is returned when registering an email that already has an account
+var ErrEmailTaken = errors.New("email already registered")
+
+// UserService handles user-related database operations
+type UserService struct {
+	db *gorm.DB
+}
+
+// NewUserService creates a new UserService
+func NewUserService(db *gorm.DB) *UserService {
+	return &UserService{db: db}
+}
+
+// CreateUser creates a new user
+func (s *UserService) CreateUser(user *User) error {
+	user.Email = strings.ToLower(user.Email)
+	if user.Role == "" {
+		user.Role = RoleCandidate
+	}
+
+	var count int64
+	if err := s.db.Model(&User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+
+	if err := s.db.Create(user).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUserByID retrieves a user by ID
+func (s *UserService) GetUserByID(id uint) (*User, error) {
+	var user User
+	err := s.db.First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user by email, returning nil if there is none
+func (s *UserService) GetUserByEmail(email string) (*User, error) {
+	var user User
+	err := s.db.Where("email = ?", strings.ToLower(email)).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUsersByCompany retrieves the members of a company
+func (s *UserService) GetUsersByCompany(company string) ([]User, error) {
+	var users []User
+	err := s.db.Where("LOWER(company) = LOWER(?)", company).Order("id").Find(&users).Error
+	return users, err
+}
+
+// SetRole sets a user's role and company
+func (s *UserService) SetRole(id uint, role, company string) (*User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).Updates(map[string]interface{}{"role": role, "company": company}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+	user.Role = role
+	user.Company = company
+	return user, nil
+}
+
+// TokenService handles refresh tokens and access token revocation
+type TokenService struct {
+	db *gorm.DB
+}
+
+// NewTokenService creates a new TokenService
+func NewTokenService(db *gorm.DB) *TokenService {
+	return &TokenService{db: db}
+}
+
+// CreateRefreshToken stores a new refresh token
+func (s *TokenService) CreateRefreshToken(token *RefreshToken) error {
+	if err := s.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by its hash, returning nil if there is none
+func (s *TokenService) GetRefreshTokenByHash(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := s.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retrieve refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken revokes a refresh token and stores its replacement atomically.
+// It fails if the token was already revoked by a concurrent rotation.
+func (s *TokenService) RotateRefreshToken(old *RefreshToken, replacement *RefreshToken) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return fmt.Errorf("failed to create refresh token: %w", err)
+		}
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by_id": replacement.ID})
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("refresh token %d was already rotated", old.ID)
+		}
+		return nil
+	})
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of a family
+func (s *TokenService) RevokeRefreshTokenFamily(familyID string) error {
+	err := s.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user
+func (s *TokenService) RevokeUserRefreshTokens(userID uint) error {
+	err := s.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken denies an access token until it expires
+func (s *TokenService) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	if err := s.db.Save(&RevokedAccessToken{JTI: jti, ExpiresAt: expiresAt}).Error; err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token has been revoked
+func (s *TokenService) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&RevokedAccessToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check access token: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteExpiredTokens removes refresh tokens and revocations that have expired
+func (s *TokenService) DeleteExpiredTokens() error {
+	now := time.Now()
+	if err := s.db.Where("expires_at < ?", now).Delete(&RefreshToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+	if err := s.db.Where("expires_at < ?", now).Delete(&RevokedAccessToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete expired revocations: %w", err)
+	}
+	return nil
+}
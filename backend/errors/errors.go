@@ -39,22 +39,65 @@ var (
 	ErrDatabaseQuery      = NewAppError(http.StatusInternalServerError, "Database query failed")
 	ErrRecordNotFound     = NewAppError(http.StatusNotFound, "Record not found")
 	ErrRecordExists       = NewAppError(http.StatusConflict, "Record already exists")
+	ErrPreconditionFailed = NewAppError(http.StatusPreconditionFailed, "Record has been modified since it was read")
+	ErrPreconditionNeeded = NewAppError(http.StatusPreconditionRequired, "If-Match header is required")
 
 	// Validation errors
-	ErrInvalidInput  = NewAppError(http.StatusBadRequest, "Invalid input")
-	ErrMissingField  = NewAppError(http.StatusBadRequest, "Required field is missing")
-	ErrInvalidFormat = NewAppError(http.StatusBadRequest, "Invalid format")
+	ErrInvalidInput         = NewAppError(http.StatusBadRequest, "Invalid input")
+	ErrMissingField         = NewAppError(http.StatusBadRequest, "Required field is missing")
+	ErrInvalidFormat        = NewAppError(http.StatusBadRequest, "Invalid format")
+	ErrUnsupportedMediaType = NewAppError(http.StatusUnsupportedMediaType, "Unsupported content type")
 
 	// Job errors
 	ErrJobNotFound       = NewAppError(http.StatusNotFound, "Job not found")
 	ErrJobCreationFailed = NewAppError(http.StatusInternalServerError, "Failed to create job")
 	ErrJobUpdateFailed   = NewAppError(http.StatusInternalServerError, "Failed to update job")
 	ErrJobDeleteFailed   = NewAppError(http.StatusInternalServerError, "Failed to delete job")
+	ErrJobPatchConflict  = NewAppError(http.StatusConflict, "Patch can't be applied to the job")
+	ErrRevisionNotFound  = NewAppError(http.StatusNotFound, "Job revision not found")
+	ErrJobRestoreFailed  = NewAppError(http.StatusInternalServerError, "Failed to restore job revision")
 
 	// Video errors
 	ErrVideoNotFound       = NewAppError(http.StatusNotFound, "Video not found")
 	ErrVideoCreationFailed = NewAppError(http.StatusInternalServerError, "Failed to create video")
 	ErrVideoStreamFailed   = NewAppError(http.StatusInternalServerError, "Failed to stream video")
+	ErrVideoUploadFailed   = NewAppError(http.StatusInternalServerError, "Failed to upload video")
+	ErrTooManyStreams      = NewAppError(http.StatusServiceUnavailable, "Too many concurrent video streams")
+
+	// Trash errors
+	ErrNotInTrash         = NewAppError(http.StatusNotFound, "Not found in the trash")
+	ErrTrashJobDeleted    = NewAppError(http.StatusConflict, "The video's job is in the trash; restore the job first")
+	ErrTrashRestoreFailed = NewAppError(http.StatusInternalServerError, "Failed to restore from the trash")
+	ErrTrashPurgeFailed   = NewAppError(http.StatusInternalServerError, "Failed to purge from the trash")
+
+	// Application errors
+	ErrApplicationNotFound       = NewAppError(http.StatusNotFound, "Application not found")
+	ErrApplicationCreationFailed = NewAppError(http.StatusInternalServerError, "Failed to create application")
+	ErrApplicationUpdateFailed   = NewAppError(http.StatusInternalServerError, "Failed to update application")
+
+	// User errors
+	ErrUserNotFound = NewAppError(http.StatusNotFound, "User not found")
+
+	// Quota errors
+	ErrStorageQuotaExceeded = NewAppError(http.StatusInsufficientStorage, "Storage quota exceeded")
+	// ErrStorageSoftLimitReached is a warning: the request succeeds but the company is close to its quota
+	ErrStorageSoftLimitReached = NewAppError(http.StatusOK, "Storage soft limit reached")
+
+	// Authentication errors
+	ErrUnauthorized            = NewAppError(http.StatusUnauthorized, "Authentication required")
+	ErrInvalidCredentials      = NewAppError(http.StatusUnauthorized, "Invalid email or password")
+	ErrInvalidToken            = NewAppError(http.StatusUnauthorized, "Invalid or expired token")
+	ErrEmailTaken              = NewAppError(http.StatusConflict, "Email already registered")
+	ErrSSONotConfigured        = NewAppError(http.StatusNotFound, "Single sign-on is not configured for this company")
+	ErrSSOFailed               = NewAppError(http.StatusUnauthorized, "Single sign-on failed")
+	ErrSSOAccountConflict      = NewAppError(http.StatusConflict, "An account with this email already exists outside this company")
+	ErrSSOAccountNotAllowed    = NewAppError(http.StatusForbidden, "This account must sign in with its password")
+	ErrInvalidTwoFactorCode    = NewAppError(http.StatusUnauthorized, "Invalid two-factor code")
+	ErrTwoFactorNotEnrolled    = NewAppError(http.StatusConflict, "Two-factor authentication is not set up")
+	ErrTwoFactorAlreadyEnabled = NewAppError(http.StatusConflict, "Two-factor authentication is already enabled")
+	ErrTwoFactorRequired       = NewAppError(http.StatusForbidden, "Two-factor authentication is required by your company")
+	ErrTwoFactorLocked         = NewAppError(http.StatusTooManyRequests, "Too many failed two-factor codes, try again later")
+	ErrRegistrationDisabled    = NewAppError(http.StatusForbidden, "Registration is disabled")
 
 	// Server errors
 	ErrInternalServer     = NewAppError(http.StatusInternalServerError, "Internal server error")
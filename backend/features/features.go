@@ -0,0 +1,46 @@
+package features
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Feature flags
+const (
+	// Registration lets visitors create accounts with POST /api/auth/register
+	Registration = "registration"
+)
+
+// Known lists the feature flags the application checks
+var Known = []string{Registration}
+
+// enabled holds the set of enabled flags; it's replaced as a whole so readers never see a partial update
+var enabled atomic.Pointer[map[string]bool]
+
+func init() {
+	Set([]string{Registration})
+}
+
+// Set atomically replaces the enabled feature flags
+func Set(names []string) {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	enabled.Store(&set)
+}
+
+// Enabled reports whether the named feature flag is on
+func Enabled(name string) bool {
+	return (*enabled.Load())[name]
+}
+
+// IsKnown reports whether name is a feature flag the application checks
+func IsKnown(name string) bool {
+	for _, known := range Known {
+		if strings.EqualFold(known, name) {
+			return true
+		}
+	}
+	return false
+}
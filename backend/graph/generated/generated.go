@@ -37,7 +37,8 @@ type QueryResolver interface {
 type MutationResolver interface {
 	CreateJob(ctx context.Context, input model.JobInput) (*model.Job, error)
 	UpdateJob(ctx context.Context, id string, input model.JobInput) (*model.Job, error)
-	DeleteJob(ctx context.Context, id string) (bool, error)
+	PatchJob(ctx context.Context, id string, input model.JobPatchInput) (*model.Job, error)
+	DeleteJob(ctx context.Context, id string, version int) (bool, error)
 	CreateVideo(ctx context.Context, input model.VideoInput) (*model.Video, error)
 }
 
@@ -11,6 +11,7 @@ type Job struct {
 	Benefits     []string `json:"benefits"`
 	PostedAt     string   `json:"postedAt"`
 	VideoURL     *string  `json:"videoUrl"`
+	Version      int      `json:"version"`
 }
 
 type Video struct {
@@ -20,6 +21,7 @@ type Video struct {
 	URL       string  `json:"url"`
 	Duration  *int    `json:"duration"`
 	Thumbnail *string `json:"thumbnail"`
+	Version   int     `json:"version"`
 }
 
 type JobInput struct {
@@ -31,6 +33,19 @@ type JobInput struct {
 	Requirements []string `json:"requirements"`
 	Benefits     []string `json:"benefits"`
 	VideoURL     *string  `json:"videoUrl"`
+	Version      *int     `json:"version"`
+}
+
+type JobPatchInput struct {
+	Title        *string  `json:"title"`
+	Company      *string  `json:"company"`
+	Description  *string  `json:"description"`
+	Location     *string  `json:"location"`
+	Salary       *string  `json:"salary"`
+	Requirements []string `json:"requirements"`
+	Benefits     []string `json:"benefits"`
+	VideoURL     *string  `json:"videoUrl"`
+	Version      int      `json:"version"`
 }
 
 type VideoInput struct {
@@ -3,9 +3,14 @@ package graph
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
+	"job-board/backend/auth"
 	"job-board/backend/graph/model"
+	"job-board/backend/policy"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 // This file will not be regenerated automatically.
@@ -36,6 +41,7 @@ func (r *Resolver) Jobs(ctx context.Context) ([]*model.Job, error) {
 			},
 			PostedAt: time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
 			VideoURL: stringPtr("/video/1"),
+			Version:  1,
 		},
 		{
 			ID:          "2",
@@ -56,6 +62,7 @@ func (r *Resolver) Jobs(ctx context.Context) ([]*model.Job, error) {
 			},
 			PostedAt: time.Now().Add(-48 * time.Hour).Format(time.RFC3339),
 			VideoURL: stringPtr("/video/2"),
+			Version:  1,
 		},
 	}
 	return jobs, nil
@@ -86,6 +93,7 @@ func (r *Resolver) Videos(ctx context.Context) ([]*model.Video, error) {
 			URL:       "/video/1",
 			Duration:  intPtr(120),
 			Thumbnail: stringPtr("/thumbnails/1.jpg"),
+			Version:   1,
 		},
 		{
 			ID:        "2",
@@ -94,6 +102,7 @@ func (r *Resolver) Videos(ctx context.Context) ([]*model.Video, error) {
 			URL:       "/video/2",
 			Duration:  intPtr(90),
 			Thumbnail: stringPtr("/thumbnails/2.jpg"),
+			Version:   1,
 		},
 	}
 	return videos, nil
@@ -116,6 +125,10 @@ func (r *Resolver) Video(ctx context.Context, id string) (*model.Video, error) {
 
 // CreateJob creates a new job
 func (r *Resolver) CreateJob(ctx context.Context, input model.JobInput) (*model.Job, error) {
+	if err := authorize(ctx, policy.PermJobsWrite, policy.Resource{Company: input.Company}); err != nil {
+		return nil, err
+	}
+
 	// In a real app, this would save to database
 	job := &model.Job{
 		ID:           fmt.Sprintf("%d", time.Now().Unix()),
@@ -128,12 +141,30 @@ func (r *Resolver) CreateJob(ctx context.Context, input model.JobInput) (*model.
 		Benefits:     input.Benefits,
 		PostedAt:     time.Now().Format(time.RFC3339),
 		VideoURL:     input.VideoURL,
+		Version:      1,
 	}
 	return job, nil
 }
 
 // UpdateJob updates an existing job
 func (r *Resolver) UpdateJob(ctx context.Context, id string, input model.JobInput) (*model.Job, error) {
+	existing, err := r.Job(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, policy.PermJobsWrite, policy.Resource{Company: existing.Company}); err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, policy.PermJobsWrite, policy.Resource{Company: input.Company}); err != nil {
+		return nil, err
+	}
+	if input.Version == nil {
+		return nil, fmt.Errorf("version is required")
+	}
+	if *input.Version != existing.Version {
+		return nil, conflict(existing.Version, existing)
+	}
+
 	// In a real app, this would update in database
 	job := &model.Job{
 		ID:           id,
@@ -146,18 +177,86 @@ func (r *Resolver) UpdateJob(ctx context.Context, id string, input model.JobInpu
 		Benefits:     input.Benefits,
 		PostedAt:     time.Now().Format(time.RFC3339),
 		VideoURL:     input.VideoURL,
+		Version:      existing.Version + 1,
 	}
 	return job, nil
 }
 
+// PatchJob changes the given fields of a job, leaving the others as they are
+func (r *Resolver) PatchJob(ctx context.Context, id string, input model.JobPatchInput) (*model.Job, error) {
+	existing, err := r.Job(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, policy.PermJobsWrite, policy.Resource{Company: existing.Company}); err != nil {
+		return nil, err
+	}
+	if input.Company != nil {
+		if err := authorize(ctx, policy.PermJobsWrite, policy.Resource{Company: *input.Company}); err != nil {
+			return nil, err
+		}
+	}
+	if input.Version != existing.Version {
+		return nil, conflict(existing.Version, existing)
+	}
+
+	// In a real app, this would write the changed fields to the database
+	job := *existing
+	if input.Title != nil {
+		job.Title = *input.Title
+	}
+	if input.Company != nil {
+		job.Company = *input.Company
+	}
+	if input.Description != nil {
+		job.Description = *input.Description
+	}
+	if input.Location != nil {
+		job.Location = *input.Location
+	}
+	if input.Salary != nil {
+		job.Salary = input.Salary
+	}
+	if input.Requirements != nil {
+		job.Requirements = input.Requirements
+	}
+	if input.Benefits != nil {
+		job.Benefits = input.Benefits
+	}
+	if input.VideoURL != nil {
+		job.VideoURL = input.VideoURL
+	}
+	job.Version = existing.Version + 1
+	return &job, nil
+}
+
 // DeleteJob deletes a job
-func (r *Resolver) DeleteJob(ctx context.Context, id string) (bool, error) {
+func (r *Resolver) DeleteJob(ctx context.Context, id string, version int) (bool, error) {
+	existing, err := r.Job(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if err := authorize(ctx, policy.PermJobsWrite, policy.Resource{Company: existing.Company}); err != nil {
+		return false, err
+	}
+	if version != existing.Version {
+		return false, conflict(existing.Version, existing)
+	}
+
 	// In a real app, this would delete from database
 	return true, nil
 }
 
 // CreateVideo creates a new video
 func (r *Resolver) CreateVideo(ctx context.Context, input model.VideoInput) (*model.Video, error) {
+	job, err := r.Job(ctx, input.JobID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, policy.PermVideosWrite, policy.Resource{Company: job.Company}); err != nil {
+		return nil, err
+	}
+
 	// In a real app, this would save to database
 	video := &model.Video{
 		ID:        fmt.Sprintf("%d", time.Now().Unix()),
@@ -166,10 +265,30 @@ func (r *Resolver) CreateVideo(ctx context.Context, input model.VideoInput) (*mo
 		URL:       input.URL,
 		Duration:  input.Duration,
 		Thumbnail: input.Thumbnail,
+		Version:   1,
 	}
 	return video, nil
 }
 
+// authorize checks a permission for the principal the auth middleware put in the
+// request context, using the same policy as the REST handlers
+func authorize(ctx context.Context, perm policy.Permission, resource policy.Resource) error {
+	return policy.Authorize(auth.PrincipalFromContext(ctx), perm, resource)
+}
+
+// conflict is the error of a mutation made against a stale version. Like the REST API's 412
+// response, it carries the current state so the client can merge its change and retry.
+func conflict(version int, current interface{}) error {
+	return &gqlerror.Error{
+		Message: fmt.Sprintf("record has been modified; current version is %d", version),
+		Extensions: map[string]interface{}{
+			"code":    "CONFLICT",
+			"status":  http.StatusConflict,
+			"current": current,
+		},
+	}
+}
+
 // Helper function to create string pointer
 func stringPtr(s string) *string {
 	return &s
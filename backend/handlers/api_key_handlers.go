@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyRequest is the body of POST /api/companies/:company/api-keys
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreatedAPIKey is returned when an API key is created; Key is never shown again
+type CreatedAPIKey struct {
+	*database.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey handles POST /api/companies/:company/api-keys
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermAPIKeysManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	var req APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	req.Name = h.userValidator.SanitizeString(req.Name)
+	if req.Name == "" {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrMissingField.Code, errors.ErrMissingField.Message, "name is required"))
+		return
+	}
+	if len(req.Scopes) == 0 {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrMissingField.Code, errors.ErrMissingField.Message, "at least one scope is required"))
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !policy.ValidScope(scope) {
+			AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "scopes must be among "+validScopes()))
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "expiresAt must be in the future"))
+		return
+	}
+
+	principal := auth.GetPrincipal(c)
+	key, plaintext, err := h.authService.CreateAPIKey(company, req.Name, req.Scopes, req.ExpiresAt, principal.UserID)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Created API key", "company", company, "api_key_id", key.ID, "scopes", key.Scopes, "by", principal.UserID)
+	SuccessResponse(c, http.StatusCreated, CreatedAPIKey{APIKey: key, Key: plaintext})
+}
+
+// GetAPIKeys handles GET /api/companies/:company/api-keys
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermAPIKeysManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	keys, err := h.apiKeyService.GetAPIKeysByCompany(company)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /api/companies/:company/api-keys/:id
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermAPIKeysManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(company, id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrRecordNotFound))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Revoked API key", "company", company, "api_key_id", id, "by", auth.GetPrincipal(c).UserID)
+	SuccessResponse(c, http.StatusOK, true)
+}
+
+// validScopes lists the scopes an API key can be granted
+func validScopes() string {
+	scopes := make([]string, len(policy.APIKeyScopes))
+	for i, scope := range policy.APIKeyScopes {
+		scopes[i] = string(scope)
+	}
+	return strings.Join(scopes, ", ")
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApplicationRequest is the body of POST /api/jobs/:id/applications
+type ApplicationRequest struct {
+	CoverLetter string `json:"coverLetter"`
+}
+
+// ApplicationStatusRequest is the body of PUT /api/applications/:id/status
+type ApplicationStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// applicationStatuses are the statuses recruiters may move an application to
+var applicationStatuses = map[string]bool{
+	database.ApplicationSubmitted: true,
+	database.ApplicationReviewing: true,
+	database.ApplicationRejected:  true,
+	database.ApplicationHired:     true,
+}
+
+// ApplyToJob handles POST /api/jobs/:id/applications
+func (h *Handler) ApplyToJob(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+	if !h.authorize(c, policy.PermApplicationsCreate, policy.Resource{}) {
+		return
+	}
+
+	var req ApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+	if err := h.jobValidator.ValidateString(req.CoverLetter, "coverLetter", false, 5000); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	if _, err := h.jobService.GetJobByID(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+
+	application := database.Application{
+		JobID:       id,
+		CandidateID: auth.GetPrincipal(c).UserID,
+		CoverLetter: h.jobValidator.SanitizeHTML(req.CoverLetter),
+	}
+	if err := h.applicationService.CreateApplication(c.Request.Context(), &application); err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to create application", "job_id", id, "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrApplicationCreationFailed))
+		return
+	}
+
+	SuccessResponse(c, http.StatusCreated, application)
+}
+
+// GetApplications handles GET /api/applications. Site admins see every application,
+// recruiters the applications to their company's jobs and candidates their own.
+func (h *Handler) GetApplications(c *gin.Context) {
+	principal := auth.GetPrincipal(c)
+
+	var applications []database.Application
+	var err error
+	switch {
+	case principal.Role == policy.RoleSiteAdmin:
+		applications, err = h.applicationService.GetAllApplications(c.Request.Context())
+	case policy.HasPermission(principal.Role, policy.PermApplicationsRead) && principal.Company != "":
+		applications, err = h.applicationService.GetApplicationsByCompany(c.Request.Context(), principal.Company)
+	case policy.HasPermission(principal.Role, policy.PermApplicationsReadOwn):
+		applications, err = h.applicationService.GetApplicationsByCandidate(c.Request.Context(), principal.UserID)
+	default:
+		h.authorize(c, policy.PermApplicationsReadOwn, policy.Resource{OwnerID: principal.UserID})
+		return
+	}
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, applications)
+}
+
+// GetApplication handles GET /api/applications/:id
+func (h *Handler) GetApplication(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	application, err := h.applicationService.GetApplicationByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrApplicationNotFound))
+		return
+	}
+	if !h.authorizeAny(c,
+		policy.Check{Permission: policy.PermApplicationsRead, Resource: policy.Resource{Company: application.Job.Company}},
+		policy.Check{Permission: policy.PermApplicationsReadOwn, Resource: policy.Resource{OwnerID: application.CandidateID}},
+	) {
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, application)
+}
+
+// GetJobApplications handles GET /api/jobs/:id/applications
+func (h *Handler) GetJobApplications(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	job, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermApplicationsRead, policy.Resource{Company: job.Company}) {
+		return
+	}
+
+	applications, err := h.applicationService.GetApplicationsByJob(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, applications)
+}
+
+// UpdateApplicationStatus handles PUT /api/applications/:id/status
+func (h *Handler) UpdateApplicationStatus(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	var req ApplicationStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !applicationStatuses[req.Status] {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "status must be one of submitted, reviewing, rejected, hired"))
+		return
+	}
+
+	application, err := h.applicationService.GetApplicationByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrApplicationNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermApplicationsManage, policy.Resource{Company: application.Job.Company}) {
+		return
+	}
+
+	if err := h.applicationService.UpdateApplicationStatus(c.Request.Context(), id, req.Status); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrApplicationUpdateFailed))
+		return
+	}
+	application.Status = req.Status
+
+	SuccessResponse(c, http.StatusOK, application)
+}
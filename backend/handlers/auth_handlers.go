@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/features"
+	"job-board/backend/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRequest is the body of POST /api/auth/register
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+// LoginRequest is the body of POST /api/auth/login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest is the body of POST /api/auth/refresh and POST /api/auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Register handles POST /api/auth/register
+func (h *Handler) Register(c *gin.Context) {
+	if !features.Enabled(features.Registration) {
+		AppErrorResponse(c, errors.ErrRegistrationDisabled)
+		return
+	}
+
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	req.Email = h.userValidator.SanitizeString(req.Email)
+	req.Name = h.userValidator.SanitizeString(req.Name)
+	if err := h.userValidator.ValidateRegistration(req.Email, req.Password, req.Name); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	user, err := h.authService.Register(req.Email, req.Password, req.Name)
+	if err == database.ErrEmailTaken {
+		AppErrorResponse(c, errors.ErrEmailTaken)
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to register user", "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Registered user", "user_id", user.ID)
+	SuccessResponse(c, http.StatusCreated, user)
+}
+
+// Login handles POST /api/auth/login
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	tokens, challenge, err := h.authService.Login(req.Email, req.Password)
+	if err == auth.ErrInvalidCredentials {
+		logger.WarnContext(c.Request.Context(), "Failed login attempt", "client_ip", c.ClientIP())
+		AppErrorResponse(c, errors.ErrInvalidCredentials)
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to log in", "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	if challenge != nil {
+		SuccessResponse(c, http.StatusOK, challenge)
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, tokens)
+}
+
+// RefreshToken handles POST /api/auth/refresh
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken)
+	if err == auth.ErrInvalidToken || err == auth.ErrTokenRevoked {
+		AppErrorResponse(c, errors.ErrInvalidToken)
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to refresh token", "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+
+	SuccessResponse(c, http.StatusOK, tokens)
+}
+
+// Logout handles POST /api/auth/logout
+func (h *Handler) Logout(c *gin.Context) {
+	// The refresh token is optional: without it only the access token is revoked
+	var req RefreshRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.authService.Logout(auth.GetPrincipal(c), req.RefreshToken); err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to log out", "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, true)
+}
+
+// LogoutAll handles POST /api/auth/logout-all
+func (h *Handler) LogoutAll(c *gin.Context) {
+	if err := h.authService.LogoutAll(auth.GetPrincipal(c)); err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to log out of all sessions", "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, true)
+}
+
+// Me handles GET /api/auth/me
+func (h *Handler) Me(c *gin.Context) {
+	user, err := h.userService.GetUserByID(auth.GetPrincipal(c).UserID)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrRecordNotFound))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, user)
+}
@@ -3,48 +3,137 @@ package handlers
 import (
 	"strconv"
 
+	"job-board/backend/auth"
 	"job-board/backend/database"
 	"job-board/backend/errors"
 	"job-board/backend/logger"
+	"job-board/backend/policy"
+	"job-board/backend/quota"
 	"job-board/backend/response"
 	"job-board/backend/streaming"
+	"job-board/backend/trash"
 	"job-board/backend/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Services holds the dependencies of the handlers
+type Services struct {
+	JobService         *database.JobService
+	VideoService       *database.VideoService
+	CompanyService     *database.CompanyService
+	UsageService       *database.UsageService
+	UserService        *database.UserService
+	ApplicationService *database.ApplicationService
+	APIKeyService      *database.APIKeyService
+	SSOService         *database.SSOService
+	AuditService       *database.AuditService
+	TrashService       *database.TrashService
+	Purger             *trash.Purger
+	VideoStreamer      *streaming.VideoStreamer
+	QuotaManager       *quota.Manager
+	UsageMeter         *quota.Meter
+	AuthService        *auth.Service
+	SSO                *auth.SSO
+}
+
 // Handler struct holds all the services
 type Handler struct {
-	jobService     *database.JobService
-	videoService   *database.VideoService
-	jobValidator   *validation.JobValidator
-	videoValidator *validation.VideoValidator
-	videoStreamer  *streaming.VideoStreamer
+	jobService         *database.JobService
+	videoService       *database.VideoService
+	jobValidator       *validation.JobValidator
+	videoValidator     *validation.VideoValidator
+	videoStreamer      *streaming.VideoStreamer
+	quotaManager       *quota.Manager
+	usageMeter         *quota.Meter
+	companyService     *database.CompanyService
+	usageService       *database.UsageService
+	userService        *database.UserService
+	userValidator      *validation.UserValidator
+	authService        *auth.Service
+	applicationService *database.ApplicationService
+	apiKeyService      *database.APIKeyService
+	ssoService         *database.SSOService
+	auditService       *database.AuditService
+	trashService       *database.TrashService
+	purger             *trash.Purger
+	sso                *auth.SSO
 }
 
 // NewHandler creates a new handler instance
-func NewHandler(jobService *database.JobService, videoService *database.VideoService, videoStreamer *streaming.VideoStreamer) *Handler {
+func NewHandler(services Services) *Handler {
 	return &Handler{
-		jobService:     jobService,
-		videoService:   videoService,
-		jobValidator:   validation.NewJobValidator(),
-		videoValidator: validation.NewVideoValidator(),
-		videoStreamer:  videoStreamer,
+		jobService:         services.JobService,
+		videoService:       services.VideoService,
+		jobValidator:       validation.NewJobValidator(),
+		videoValidator:     validation.NewVideoValidator(),
+		videoStreamer:      services.VideoStreamer,
+		quotaManager:       services.QuotaManager,
+		usageMeter:         services.UsageMeter,
+		companyService:     services.CompanyService,
+		usageService:       services.UsageService,
+		userService:        services.UserService,
+		userValidator:      validation.NewUserValidator(),
+		authService:        services.AuthService,
+		applicationService: services.ApplicationService,
+		apiKeyService:      services.APIKeyService,
+		ssoService:         services.SSOService,
+		auditService:       services.AuditService,
+		trashService:       services.TrashService,
+		purger:             services.Purger,
+		sso:                services.SSO,
 	}
 }
 
 // AppErrorResponse creates a standardized error response from AppError
 func AppErrorResponse(c *gin.Context, appErr *errors.AppError) {
-	logger.Error("API Error", "status", appErr.Code, "message", appErr.Message, "path", c.Request.URL.Path)
+	logger.ErrorContext(c.Request.Context(), "API Error", "status", appErr.Code, "message", appErr.Message, "path", c.Request.URL.Path)
 	response.ErrorResponse(c, appErr.Code, appErr.Message, "")
 }
 
 // SuccessResponse creates a standardized success response
 func SuccessResponse(c *gin.Context, statusCode int, data interface{}) {
-	logger.Info("API Success", "status", statusCode, "path", c.Request.URL.Path)
+	logger.InfoContext(c.Request.Context(), "API Success", "status", statusCode, "path", c.Request.URL.Path)
 	response.SuccessResponse(c, statusCode, data)
 }
 
+// SuccessResponseWithWarnings creates a standardized success response carrying AppError warnings
+func SuccessResponseWithWarnings(c *gin.Context, statusCode int, data interface{}, warnings []*errors.AppError) {
+	logger.InfoContext(c.Request.Context(), "API Success", "status", statusCode, "path", c.Request.URL.Path, "warnings", len(warnings))
+	builder := response.NewResponseBuilder().WithData(data)
+	for _, warning := range warnings {
+		builder.WithWarning(warning.Code, warning.Message, warning.Details)
+	}
+	builder.Send(c, statusCode)
+}
+
+// authorize checks a permission on a resource through the shared policy, responding
+// with 401/403 and returning false if the caller isn't allowed
+func (h *Handler) authorize(c *gin.Context, perm policy.Permission, resource policy.Resource) bool {
+	return h.authorizeAny(c, policy.Check{Permission: perm, Resource: resource})
+}
+
+// authorizeAny is like authorize but succeeds if any of the checks does
+func (h *Handler) authorizeAny(c *gin.Context, checks ...policy.Check) bool {
+	principal := auth.GetPrincipal(c)
+	err := policy.AuthorizeAny(principal, checks...)
+	if err == nil {
+		return true
+	}
+
+	if err == policy.ErrUnauthenticated {
+		response.UnauthorizedResponse(c, "Authentication required")
+		return false
+	}
+	if err == policy.ErrTwoFactorEnrollment {
+		response.ForbiddenResponse(c, "Two-factor authentication must be set up before continuing")
+		return false
+	}
+	logger.WarnContext(c.Request.Context(), "Permission denied", "path", c.Request.URL.Path, "user_id", principal.UserID, "role", principal.Role)
+	response.ForbiddenResponse(c, "You do not have permission to perform this action")
+	return false
+}
+
 // parseID parses a string ID parameter to uint
 func parseID(idStr string) (uint, error) {
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -1,25 +1,31 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 
 	"job-board/backend/database"
 	"job-board/backend/errors"
 	"job-board/backend/logger"
+	"job-board/backend/patch"
+	"job-board/backend/policy"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetJobs handles GET /api/jobs
 func (h *Handler) GetJobs(c *gin.Context) {
-	logger.Info("Fetching all jobs")
-	jobs, err := h.jobService.GetAllJobs()
+	logger.InfoContext(c.Request.Context(), "Fetching all jobs")
+	jobs, err := h.jobService.GetAllJobs(c.Request.Context())
 	if err != nil {
-		logger.Error("Failed to fetch jobs", "error", err)
+		logger.ErrorContext(c.Request.Context(), "Failed to fetch jobs", "error", err)
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
 		return
 	}
-	logger.Info("Successfully fetched jobs", "count", len(jobs))
+	logger.InfoContext(c.Request.Context(), "Successfully fetched jobs", "count", len(jobs))
 	SuccessResponse(c, http.StatusOK, jobs)
 }
 
@@ -27,19 +33,24 @@ func (h *Handler) GetJobs(c *gin.Context) {
 func (h *Handler) GetJob(c *gin.Context) {
 	id, err := parseID(c.Param("id"))
 	if err != nil {
-		logger.Warn("Invalid job ID provided", "id", c.Param("id"), "error", err)
+		logger.WarnContext(c.Request.Context(), "Invalid job ID provided", "id", c.Param("id"), "error", err)
 		AppErrorResponse(c, errors.ErrInvalidInput)
 		return
 	}
 
-	logger.Info("Fetching job by ID", "id", id)
-	job, err := h.jobService.GetJobByID(id)
+	logger.InfoContext(c.Request.Context(), "Fetching job by ID", "id", id)
+	job, err := h.jobService.GetJobByID(c.Request.Context(), id)
 	if err != nil {
-		logger.Error("Failed to fetch job", "id", id, "error", err)
+		logger.ErrorContext(c.Request.Context(), "Failed to fetch job", "id", id, "error", err)
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
 		return
 	}
-	logger.Info("Successfully fetched job", "id", id, "title", job.Title)
+	tag := jobETag(job)
+	setETag(c, tag)
+	if notModified(c, tag) {
+		return
+	}
+	logger.InfoContext(c.Request.Context(), "Successfully fetched job", "id", id, "title", job.Title)
 	SuccessResponse(c, http.StatusOK, job)
 }
 
@@ -47,30 +58,36 @@ func (h *Handler) GetJob(c *gin.Context) {
 func (h *Handler) CreateJob(c *gin.Context) {
 	var job database.Job
 	if err := c.ShouldBindJSON(&job); err != nil {
-		logger.Error("Failed to bind JSON for job creation", "error", err)
+		logger.ErrorContext(c.Request.Context(), "Failed to bind JSON for job creation", "error", err)
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
 		return
 	}
 
-	logger.Info("Creating new job", "title", job.Title, "company", job.Company)
+	logger.InfoContext(c.Request.Context(), "Creating new job", "title", job.Title, "company", job.Company)
 
 	// Sanitize input
 	h.jobValidator.SanitizeJob(&job)
 
 	// Validate required fields
 	if err := h.jobValidator.ValidateJob(&job); err != nil {
-		logger.Warn("Job validation failed", "error", err)
+		logger.WarnContext(c.Request.Context(), "Job validation failed", "error", err)
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
 		return
 	}
 
-	if err := h.jobService.CreateJob(&job); err != nil {
-		logger.Error("Failed to create job", "error", err)
+	// Recruiters can only post jobs for their own company
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: job.Company}) {
+		return
+	}
+
+	if err := h.jobService.CreateJob(c.Request.Context(), &job); err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to create job", "error", err)
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobCreationFailed))
 		return
 	}
 
-	logger.Info("Successfully created job", "id", job.ID, "title", job.Title)
+	logger.InfoContext(c.Request.Context(), "Successfully created job", "id", job.ID, "title", job.Title)
+	setETag(c, jobETag(&job))
 	SuccessResponse(c, http.StatusCreated, job)
 }
 
@@ -82,6 +99,18 @@ func (h *Handler) UpdateJob(c *gin.Context) {
 		return
 	}
 
+	existing, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: existing.Company}) {
+		return
+	}
+	if !ifMatch(c, jobETag(existing), existing) {
+		return
+	}
+
 	var job database.Job
 	if err := c.ShouldBindJSON(&job); err != nil {
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
@@ -97,14 +126,149 @@ func (h *Handler) UpdateJob(c *gin.Context) {
 		return
 	}
 
-	if err := h.jobService.UpdateJob(id, &job); err != nil {
-		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobUpdateFailed))
+	// A job can't be moved to a company the caller doesn't recruit for
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: job.Company}) {
+		return
+	}
+
+	// The job may have changed since it was checked above; the update only applies to that version
+	job.Version = existing.Version
+	if err := h.jobService.UpdateJob(c.Request.Context(), id, &job); err != nil {
+		h.jobWriteFailed(c, id, err, errors.ErrJobUpdateFailed)
 		return
 	}
 
+	// The update leaves the job's videos alone
+	job.Videos = existing.Videos
+	setETag(c, jobETag(&job))
 	SuccessResponse(c, http.StatusOK, job)
 }
 
+// jobReadOnlyFields are the job fields a patch may not change
+var jobReadOnlyFields = []string{"id", "postedAt", "version", "createdAt", "updatedAt", "deletedAt", "videos"}
+
+// PatchJob handles PATCH /api/jobs/:id with a JSON merge patch (RFC 7396) or, with the
+// application/json-patch+json content type, a JSON Patch (RFC 6902). The patched job is
+// validated as a whole, but only the fields it changes are written.
+func (h *Handler) PatchJob(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	existing, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: existing.Company}) {
+		return
+	}
+	if !ifMatch(c, jobETag(existing), existing) {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	// The patch applies to the job's own fields, without its videos
+	current := *existing
+	current.Videos = nil
+	document, err := json.Marshal(&current)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobUpdateFailed))
+		return
+	}
+
+	var patched []byte
+	switch c.ContentType() {
+	case patch.ContentTypeMergePatch, "application/json":
+		patched, err = patch.MergePatch(document, body)
+		if err != nil {
+			AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+			return
+		}
+	case patch.ContentTypeJSONPatch:
+		operations, err := patch.ParseJSONPatch(body)
+		if err != nil {
+			AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+			return
+		}
+		if patched, err = operations.Apply(document); err != nil {
+			AppErrorResponse(c, errors.WrapError(err, errors.ErrJobPatchConflict))
+			return
+		}
+	default:
+		c.Header("Accept-Patch", patch.ContentTypeMergePatch+", "+patch.ContentTypeJSONPatch)
+		AppErrorResponse(c, errors.NewAppError(errors.ErrUnsupportedMediaType.Code, errors.ErrUnsupportedMediaType.Message,
+			"use "+patch.ContentTypeMergePatch+" or "+patch.ContentTypeJSONPatch))
+		return
+	}
+
+	job, err := decodePatchedJob(document, patched)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	// Sanitize input
+	h.jobValidator.SanitizeJob(job)
+
+	// Validate the job as patched
+	if err := h.jobValidator.ValidateJob(job); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	// A job can't be moved to a company the caller doesn't recruit for
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: job.Company}) {
+		return
+	}
+
+	job.Version = existing.Version
+	if err := h.jobService.PatchJob(c.Request.Context(), id, job); err != nil {
+		h.jobWriteFailed(c, id, err, errors.ErrJobUpdateFailed)
+		return
+	}
+
+	updated, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+	setETag(c, jobETag(updated))
+	SuccessResponse(c, http.StatusOK, updated)
+}
+
+// decodePatchedJob reads a patched job document, rejecting unknown fields and changes to
+// read-only ones
+func decodePatchedJob(original, patched []byte) (*database.Job, error) {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, fmt.Errorf("patched job must be an object")
+	}
+	for _, field := range jobReadOnlyFields {
+		if !bytes.Equal(before[field], after[field]) {
+			return nil, fmt.Errorf("field %s can't be changed", field)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	var job database.Job
+	if err := decoder.Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
 // DeleteJob handles DELETE /api/jobs/:id
 func (h *Handler) DeleteJob(c *gin.Context) {
 	id, err := parseID(c.Param("id"))
@@ -113,10 +277,34 @@ func (h *Handler) DeleteJob(c *gin.Context) {
 		return
 	}
 
-	if err := h.jobService.DeleteJob(id); err != nil {
-		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobDeleteFailed))
+	existing, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: existing.Company}) {
+		return
+	}
+	if !ifMatch(c, jobETag(existing), existing) {
+		return
+	}
+
+	if err := h.jobService.DeleteJob(c.Request.Context(), id, existing.Version); err != nil {
+		h.jobWriteFailed(c, id, err, errors.ErrJobDeleteFailed)
 		return
 	}
 
 	SuccessResponse(c, http.StatusOK, true)
 }
+
+// jobWriteFailed responds to a failed update or delete of a job, with 412 and the job's
+// current state if another request changed it first
+func (h *Handler) jobWriteFailed(c *gin.Context, id uint, err error, fallback *errors.AppError) {
+	if err == database.ErrVersionConflict {
+		if current, getErr := h.jobService.GetJobByID(c.Request.Context(), id); getErr == nil {
+			preconditionFailed(c, jobETag(current), current)
+			return
+		}
+	}
+	AppErrorResponse(c, errors.WrapError(err, fallback))
+}
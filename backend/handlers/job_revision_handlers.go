@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobRevisionDiff is the response of GET /api/jobs/:id/revisions/diff
+type JobRevisionDiff struct {
+	From    int                             `json:"from"`
+	To      int                             `json:"to"`
+	Changes map[string]database.FieldChange `json:"changes"`
+}
+
+// parseVersion parses a revision version, responding with 400 and returning false if it
+// isn't a positive number
+func parseVersion(c *gin.Context, value string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "version must be a positive number"))
+		return 0, false
+	}
+	return version, true
+}
+
+// authorizeJob loads the job named by the :id parameter and checks a permission on it,
+// responding and returning nil if it can't be found or the caller isn't allowed
+func (h *Handler) authorizeJob(c *gin.Context, perm policy.Permission) *database.Job {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return nil
+	}
+	job, err := h.jobService.GetJobByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return nil
+	}
+	if !h.authorize(c, perm, policy.Resource{Company: job.Company}) {
+		return nil
+	}
+	return job
+}
+
+// GetJobRevisions handles GET /api/jobs/:id/revisions
+func (h *Handler) GetJobRevisions(c *gin.Context) {
+	job := h.authorizeJob(c, policy.PermJobsRead)
+	if job == nil {
+		return
+	}
+
+	revisions, err := h.jobService.GetJobRevisions(c.Request.Context(), job.ID)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, revisions)
+}
+
+// GetJobRevision handles GET /api/jobs/:id/revisions/:version
+func (h *Handler) GetJobRevision(c *gin.Context) {
+	job := h.authorizeJob(c, policy.PermJobsRead)
+	if job == nil {
+		return
+	}
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	revision, err := h.jobService.GetJobRevision(c.Request.Context(), job.ID, version)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrRevisionNotFound))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, revision)
+}
+
+// DiffJobRevisions handles GET /api/jobs/:id/revisions/diff?from=1&to=2
+func (h *Handler) DiffJobRevisions(c *gin.Context) {
+	job := h.authorizeJob(c, policy.PermJobsRead)
+	if job == nil {
+		return
+	}
+	fromVersion, ok := parseVersion(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	toVersion, ok := parseVersion(c, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	from, err := h.jobService.GetJobRevision(c.Request.Context(), job.ID, fromVersion)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrRevisionNotFound))
+		return
+	}
+	to, err := h.jobService.GetJobRevision(c.Request.Context(), job.ID, toVersion)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrRevisionNotFound))
+		return
+	}
+
+	changes, err := database.DiffJobRevisions(from, to)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, JobRevisionDiff{From: fromVersion, To: toVersion, Changes: changes})
+}
+
+// RestoreJobRevision handles POST /api/jobs/:id/revisions/:version/restore
+func (h *Handler) RestoreJobRevision(c *gin.Context) {
+	job := h.authorizeJob(c, policy.PermJobsWrite)
+	if job == nil {
+		return
+	}
+	version, ok := parseVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	revision, err := h.jobService.GetJobRevision(c.Request.Context(), job.ID, version)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrRevisionNotFound))
+		return
+	}
+	snapshot, err := revision.Job()
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobRestoreFailed))
+		return
+	}
+	// A job can't be moved back to a company the caller doesn't recruit for
+	if !h.authorize(c, policy.PermJobsWrite, policy.Resource{Company: snapshot.Company}) {
+		return
+	}
+
+	restored, err := h.jobService.RestoreJobRevision(c.Request.Context(), job.ID, version)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobRestoreFailed))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Restored job revision", "id", job.ID, "version", version)
+	restored.Videos = job.Videos
+	setETag(c, jobETag(restored))
+	SuccessResponse(c, http.StatusOK, restored)
+}
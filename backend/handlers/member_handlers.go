@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+
+	"job-board/backend/auth"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemberRequest is the body of PUT /api/companies/:company/members
+type MemberRequest struct {
+	Email string `json:"email"`
+	// Role is recruiter or company_admin; candidate removes the user from the company
+	Role string `json:"role"`
+}
+
+// RoleRequest is the body of PUT /api/users/:id/role
+type RoleRequest struct {
+	Role    string `json:"role"`
+	Company string `json:"company"`
+}
+
+// GetCompanyMembers handles GET /api/companies/:company/members
+func (h *Handler) GetCompanyMembers(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermMembersManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	users, err := h.userService.GetUsersByCompany(company)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, users)
+}
+
+// SetCompanyMember handles PUT /api/companies/:company/members
+func (h *Handler) SetCompanyMember(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermMembersManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+	if req.Role != policy.RoleCandidate && req.Role != policy.RoleRecruiter && req.Role != policy.RoleCompanyAdmin {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "role must be one of candidate, recruiter, company_admin"))
+		return
+	}
+
+	user, err := h.userService.GetUserByEmail(req.Email)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	if user == nil {
+		AppErrorResponse(c, errors.ErrUserNotFound)
+		return
+	}
+
+	// Company admins can't take over members of other companies or demote site admins
+	principal := auth.GetPrincipal(c)
+	if principal.Role != policy.RoleSiteAdmin &&
+		(user.Role == policy.RoleSiteAdmin || (user.Company != "" && !policy.SameCompany(user.Company, company))) {
+		h.authorize(c, policy.PermUsersManage, policy.Resource{})
+		return
+	}
+
+	memberCompany := company
+	if req.Role == policy.RoleCandidate {
+		memberCompany = ""
+	}
+	updated, err := h.userService.SetRole(user.ID, req.Role, memberCompany)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Updated company member", "company", company, "user_id", updated.ID, "role", updated.Role, "by", principal.UserID)
+	SuccessResponse(c, http.StatusOK, updated)
+}
+
+// SetUserRole handles PUT /api/users/:id/role
+func (h *Handler) SetUserRole(c *gin.Context) {
+	if !h.authorize(c, policy.PermUsersManage, policy.Resource{}) {
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !policy.ValidRole(req.Role) {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "role must be one of candidate, recruiter, company_admin, site_admin"))
+		return
+	}
+	if (req.Role == policy.RoleRecruiter || req.Role == policy.RoleCompanyAdmin) && req.Company == "" {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "company is required for recruiters and company admins"))
+		return
+	}
+
+	user, err := h.userService.SetRole(id, req.Role, h.userValidator.SanitizeString(req.Company))
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrUserNotFound))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Updated user role", "user_id", user.ID, "role", user.Role, "company", user.Company, "by", auth.GetPrincipal(c).UserID)
+	SuccessResponse(c, http.StatusOK, user)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag returns the entity tag of a record at a version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// jobETag returns the entity tag of a job. Its representation embeds its videos, so the
+// tag also changes when one of them is added, changed or removed.
+func jobETag(job *database.Job) string {
+	if len(job.Videos) == 0 {
+		return etag(job.Version)
+	}
+	videos := make([]string, len(job.Videos))
+	for i, video := range job.Videos {
+		videos[i] = fmt.Sprintf("%d:%d", video.ID, video.Version)
+	}
+	sort.Strings(videos)
+	sum := sha256.Sum256([]byte(strings.Join(videos, ",")))
+	return `"` + strconv.Itoa(job.Version) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// setETag sets the ETag header
+func setETag(c *gin.Context, tag string) {
+	c.Header("ETag", tag)
+}
+
+// strongETagMatch reports whether an If-Match header names the tag. It uses the strong
+// comparison of RFC 9110, so weak tags never match: a weak validator can't guarantee that
+// a write is based on the current state.
+func strongETagMatch(header, tag string) bool {
+	return matchesETag(header, tag, false)
+}
+
+// weakETagMatch reports whether an If-None-Match header names the tag. It uses the weak
+// comparison of RFC 9110, so weak tags match by their value.
+func weakETagMatch(header, tag string) bool {
+	return matchesETag(header, tag, true)
+}
+
+// matchesETag reports whether a list of entity tags names want, or is "*"
+func matchesETag(header, want string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified responds with 304 and returns true if the If-None-Match header names the
+// record's current tag, i.e. the caller's copy is current
+func notModified(c *gin.Context, tag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" || !weakETagMatch(header, tag) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// ifMatch checks that the If-Match header names the record's current tag before it's
+// changed, responding with 428 if the header is missing and with 412 and the current
+// record if it names another one
+func ifMatch(c *gin.Context, tag string, current interface{}) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		AppErrorResponse(c, errors.ErrPreconditionNeeded)
+		return false
+	}
+	if !strongETagMatch(header, tag) {
+		preconditionFailed(c, tag, current)
+		return false
+	}
+	return true
+}
+
+// preconditionFailed responds with 412, the record's current state and its ETag, so the
+// caller can merge their change and retry
+func preconditionFailed(c *gin.Context, tag string, current interface{}) {
+	logger.InfoContext(c.Request.Context(), "Stale write rejected", "path", c.Request.URL.Path, "etag", tag, "if_match", c.GetHeader("If-Match"))
+	setETag(c, tag)
+	response.NewResponseBuilder().
+		WithError(errors.ErrPreconditionFailed.Code, errors.ErrPreconditionFailed.Message, "current ETag is "+tag).
+		WithData(current).
+		Send(c, errors.ErrPreconditionFailed.Code)
+}
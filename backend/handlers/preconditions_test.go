@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"testing"
+
+	"job-board/backend/database"
+)
+
+func TestETagMatch(t *testing.T) {
+	tests := []struct {
+		header      string
+		strongMatch bool
+		weakMatch   bool
+	}{
+		{`"3"`, true, true},
+		{`W/"3"`, false, true},
+		{`"2"`, false, false},
+		{`W/"2"`, false, false},
+		{`"2", "3"`, true, true},
+		{`"2", W/"3"`, false, true},
+		{` "1" ,"3" `, true, true},
+		{`*`, true, true},
+		{`3`, false, false},
+		{`W/3`, false, false},
+	}
+	for _, tt := range tests {
+		if got := strongETagMatch(tt.header, etag(3)); got != tt.strongMatch {
+			t.Errorf("strongETagMatch(%q, 3) = %v, want %v", tt.header, got, tt.strongMatch)
+		}
+		if got := weakETagMatch(tt.header, etag(3)); got != tt.weakMatch {
+			t.Errorf("weakETagMatch(%q, 3) = %v, want %v", tt.header, got, tt.weakMatch)
+		}
+	}
+}
+
+func TestJobETag(t *testing.T) {
+	job := &database.Job{ID: 1, Version: 3}
+	if got := jobETag(job); got != `"3"` {
+		t.Errorf("jobETag without videos = %s, want \"3\"", got)
+	}
+
+	job.Videos = []database.Video{{ID: 1, Version: 1}, {ID: 2, Version: 1}}
+	tag := jobETag(job)
+	if tag == `"3"` {
+		t.Errorf("jobETag with videos = %s, want it to differ from the job without videos", tag)
+	}
+	if !strongETagMatch(tag, tag) || !weakETagMatch("W/"+tag, tag) {
+		t.Errorf("jobETag = %s doesn't match itself", tag)
+	}
+
+	// The order the videos are loaded in doesn't matter
+	reordered := &database.Job{ID: 1, Version: 3, Videos: []database.Video{{ID: 2, Version: 1}, {ID: 1, Version: 1}}}
+	if got := jobETag(reordered); got != tag {
+		t.Errorf("jobETag with reordered videos = %s, want %s", got, tag)
+	}
+
+	changes := map[string]*database.Job{
+		"video updated": {ID: 1, Version: 3, Videos: []database.Video{{ID: 1, Version: 2}, {ID: 2, Version: 1}}},
+		"video added":   {ID: 1, Version: 3, Videos: []database.Video{{ID: 1, Version: 1}, {ID: 2, Version: 1}, {ID: 3, Version: 1}}},
+		"video deleted": {ID: 1, Version: 3, Videos: []database.Video{{ID: 1, Version: 1}}},
+		"job updated":   {ID: 1, Version: 4, Videos: []database.Video{{ID: 1, Version: 1}, {ID: 2, Version: 1}}},
+	}
+	for name, changed := range changes {
+		if got := jobETag(changed); got == tag || weakETagMatch(tag, got) {
+			t.Errorf("%s: jobETag = %s, want it to differ from %s", name, got, tag)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ssoStateCookie carries the state of a sign-in in progress between login and callback
+const ssoStateCookie = "sso_state"
+
+// SSOProviderRequest is the body of PUT /api/companies/:company/sso
+type SSOProviderRequest struct {
+	IssuerURL string `json:"issuerUrl"`
+	ClientID  string `json:"clientId"`
+	// ClientSecret may be omitted to keep the stored secret
+	ClientSecret   string   `json:"clientSecret"`
+	AllowedDomains []string `json:"allowedDomains"`
+	Enabled        *bool    `json:"enabled"`
+	// TrustedForMFA may only be set by site admins; when omitted it's kept unless the
+	// issuer changes
+	TrustedForMFA *bool `json:"trustedForMfa"`
+}
+
+// SSOProviderResponse is a company's SSO configuration with the redirect URI to register at the provider
+type SSOProviderResponse struct {
+	*database.SSOProvider
+	RedirectURI string `json:"redirectUri"`
+}
+
+// SSOLogin handles GET /api/auth/sso/:company/login
+func (h *Handler) SSOLogin(c *gin.Context) {
+	login, err := h.sso.BeginLogin(c.Param("company"))
+	if err == auth.ErrSSONotConfigured {
+		AppErrorResponse(c, errors.ErrSSONotConfigured)
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to start single sign-on", "company", c.Param("company"), "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrSSOFailed))
+		return
+	}
+
+	// Lax so the cookie survives the top-level redirect back from the identity provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, login.StateCookie, int(h.sso.StateTTL().Seconds()), "/api/auth/sso", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, login.AuthURL)
+}
+
+// SSOCallback handles GET /api/auth/sso/:company/callback
+func (h *Handler) SSOCallback(c *gin.Context) {
+	company := c.Param("company")
+	stateCookie, _ := c.Cookie(ssoStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, "", -1, "/api/auth/sso", "", c.Request.TLS != nil, true)
+
+	if idpError := c.Query("error"); idpError != "" {
+		logger.WarnContext(c.Request.Context(), "Identity provider rejected single sign-on", "company", company, "error", idpError)
+		AppErrorResponse(c, errors.NewAppError(errors.ErrSSOFailed.Code, errors.ErrSSOFailed.Message, c.Query("error_description")))
+		return
+	}
+
+	tokens, user, err := h.sso.CompleteLogin(c.Request.Context(), company, c.Query("code"), c.Query("state"), stateCookie)
+	switch err {
+	case nil:
+	case auth.ErrSSONotConfigured:
+		AppErrorResponse(c, errors.ErrSSONotConfigured)
+		return
+	case auth.ErrSSOAccountConflict:
+		AppErrorResponse(c, errors.ErrSSOAccountConflict)
+		return
+	case auth.ErrSSOAccountNotAllowed:
+		logger.WarnContext(c.Request.Context(), "Rejected single sign-on to a protected account", "company", company, "client_ip", c.ClientIP())
+		AppErrorResponse(c, errors.ErrSSOAccountNotAllowed)
+		return
+	case auth.ErrSSOInvalidState, auth.ErrSSOEmailNotAllowed:
+		logger.WarnContext(c.Request.Context(), "Rejected single sign-on", "company", company, "client_ip", c.ClientIP(), "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrSSOFailed))
+		return
+	default:
+		logger.ErrorContext(c.Request.Context(), "Single sign-on failed", "company", company, "error", err)
+		AppErrorResponse(c, errors.ErrSSOFailed)
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Signed in through single sign-on", "user_id", user.ID, "company", user.Company)
+	if target, ok := h.sso.SuccessRedirect(tokens); ok {
+		c.Redirect(http.StatusFound, target)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, tokens)
+}
+
+// GetSSOProvider handles GET /api/companies/:company/sso
+func (h *Handler) GetSSOProvider(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermSSOManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	provider, err := h.ssoService.GetSSOProvider(company)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	if provider == nil {
+		AppErrorResponse(c, errors.ErrSSONotConfigured)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, SSOProviderResponse{SSOProvider: provider, RedirectURI: h.sso.RedirectURI(provider.Company)})
+}
+
+// UpdateSSOProvider handles PUT /api/companies/:company/sso
+func (h *Handler) UpdateSSOProvider(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermSSOManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	var req SSOProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	issuerURL, err := h.sso.CheckIssuer(req.IssuerURL)
+	if err != nil {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "issuerUrl must be an https URL on a public address"))
+		return
+	}
+	req.ClientID = strings.TrimSpace(req.ClientID)
+	if req.ClientID == "" {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrMissingField.Code, errors.ErrMissingField.Message, "clientId is required"))
+		return
+	}
+	for i, domain := range req.AllowedDomains {
+		req.AllowedDomains[i] = strings.ToLower(strings.TrimSpace(domain))
+		if req.AllowedDomains[i] == "" || strings.ContainsAny(req.AllowedDomains[i], " @/") {
+			AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "allowedDomains must be domain names"))
+			return
+		}
+	}
+
+	enabled := req.Enabled == nil || *req.Enabled
+	if enabled && len(req.AllowedDomains) == 0 {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrMissingField.Code, errors.ErrMissingField.Message, "allowedDomains is required to enable single sign-on"))
+		return
+	}
+
+	// Trusting the provider's second factor is up to site admins. It doesn't carry over
+	// to another issuer.
+	var trustedForMFA bool
+	if req.TrustedForMFA != nil {
+		if !h.authorize(c, policy.PermSSOTrustMFA, policy.Resource{}) {
+			return
+		}
+		trustedForMFA = *req.TrustedForMFA
+	} else {
+		existing, err := h.ssoService.GetSSOProvider(company)
+		if err != nil {
+			AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+			return
+		}
+		trustedForMFA = existing != nil && existing.TrustedForMFA && existing.IssuerURL == issuerURL
+	}
+
+	provider := &database.SSOProvider{
+		Company:        company,
+		IssuerURL:      issuerURL,
+		ClientID:       req.ClientID,
+		ClientSecret:   req.ClientSecret,
+		AllowedDomains: strings.Join(req.AllowedDomains, " "),
+		Enabled:        enabled,
+		TrustedForMFA:  trustedForMFA,
+	}
+	if err := h.ssoService.SaveSSOProvider(provider); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Updated SSO provider", "company", provider.Company, "issuer", provider.IssuerURL, "enabled", provider.Enabled, "by", auth.GetPrincipal(c).UserID)
+	SuccessResponse(c, http.StatusOK, SSOProviderResponse{SSOProvider: provider, RedirectURI: h.sso.RedirectURI(provider.Company)})
+}
+
+// DeleteSSOProvider handles DELETE /api/companies/:company/sso
+func (h *Handler) DeleteSSOProvider(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermSSOManage, policy.Resource{Company: company}) {
+		return
+	}
+
+	if err := h.ssoService.DeleteSSOProvider(company); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrSSONotConfigured))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Deleted SSO provider", "company", company, "by", auth.GetPrincipal(c).UserID)
+	SuccessResponse(c, http.StatusOK, true)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+	"job-board/backend/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTrashPageSize = 50
+	maxTrashPageSize     = 500
+)
+
+// GetTrash handles GET /api/admin/trash, listing deleted jobs and videos most recently
+// deleted first. The type (job or video) and company query parameters filter the list.
+func (h *Handler) GetTrash(c *gin.Context) {
+	if !h.authorize(c, policy.PermTrashManage, policy.Resource{}) {
+		return
+	}
+
+	filter := database.TrashFilter{Type: c.Query("type"), Company: c.Query("company")}
+	if filter.Type != "" && filter.Type != database.TrashTypeJob && filter.Type != database.TrashTypeVideo {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "type must be job or video"))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "page must be a positive number"))
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultTrashPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxTrashPageSize {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, fmt.Sprintf("pageSize must be between 1 and %d", maxTrashPageSize)))
+		return
+	}
+
+	entries, total, err := h.trashService.GetEntries(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	for i := range entries {
+		entries[i].PurgeAt = h.purger.PurgeAt(entries[i].DeletedAt)
+	}
+	response.PaginatedResponse(c, http.StatusOK, entries, page, pageSize, total)
+}
+
+// RestoreTrashedJob handles POST /api/admin/trash/jobs/:id/restore, restoring the job
+// and the videos deleted with it
+func (h *Handler) RestoreTrashedJob(c *gin.Context) {
+	if !h.authorize(c, policy.PermTrashManage, policy.Resource{}) {
+		return
+	}
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+	if _, err := h.trashService.GetJob(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrNotInTrash))
+		return
+	}
+
+	job, err := h.trashService.RestoreJob(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrTrashRestoreFailed))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Restored job from the trash", "id", id, "videos", len(job.Videos))
+	setETag(c, jobETag(job))
+	SuccessResponse(c, http.StatusOK, job)
+}
+
+// RestoreTrashedVideo handles POST /api/admin/trash/videos/:id/restore. A video whose
+// job is in the trash can't be restored on its own.
+func (h *Handler) RestoreTrashedVideo(c *gin.Context) {
+	if !h.authorize(c, policy.PermTrashManage, policy.Resource{}) {
+		return
+	}
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+	if _, err := h.trashService.GetVideo(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrNotInTrash))
+		return
+	}
+
+	video, err := h.trashService.RestoreVideo(c.Request.Context(), id)
+	if err == database.ErrJobInTrash {
+		AppErrorResponse(c, errors.ErrTrashJobDeleted)
+		return
+	}
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrTrashRestoreFailed))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Restored video from the trash", "id", id)
+	setETag(c, etag(video.Version))
+	SuccessResponse(c, http.StatusOK, video)
+}
+
+// PurgeTrashedJob handles DELETE /api/admin/trash/jobs/:id, permanently deleting the job
+// with its videos, their files, its applications and its revisions
+func (h *Handler) PurgeTrashedJob(c *gin.Context) {
+	if !h.authorize(c, policy.PermTrashManage, policy.Resource{}) {
+		return
+	}
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+	if _, err := h.trashService.GetJob(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrNotInTrash))
+		return
+	}
+
+	if err := h.purger.PurgeJob(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrTrashPurgeFailed))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, true)
+}
+
+// PurgeTrashedVideo handles DELETE /api/admin/trash/videos/:id, permanently deleting the
+// video and its file
+func (h *Handler) PurgeTrashedVideo(c *gin.Context) {
+	if !h.authorize(c, policy.PermTrashManage, policy.Resource{}) {
+		return
+	}
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+	if _, err := h.trashService.GetVideo(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrNotInTrash))
+		return
+	}
+
+	if err := h.purger.PurgeVideo(c.Request.Context(), id); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrTrashPurgeFailed))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, true)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+
+	"job-board/backend/auth"
+	"job-board/backend/errors"
+	"job-board/backend/logger"
+	"job-board/backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorCodeRequest is the body of the two-factor endpoints that need a current code
+type TwoFactorCodeRequest struct {
+	// Code is a TOTP code or, where accepted, a recovery code
+	Code string `json:"code"`
+}
+
+// TwoFactorVerifyRequest is the body of POST /api/auth/2fa/verify
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorPolicyRequest is the body of PUT /api/companies/:company/2fa-policy
+type TwoFactorPolicyRequest struct {
+	Required bool `json:"required"`
+}
+
+// RecoveryCodesResponse holds recovery codes, which are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// VerifyTwoFactor handles POST /api/auth/2fa/verify
+func (h *Handler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	tokens, err := h.authService.VerifyTwoFactor(req.ChallengeToken, req.Code)
+	if err != nil {
+		if err == auth.ErrInvalidTwoFactorCode {
+			logger.WarnContext(c.Request.Context(), "Failed two-factor attempt", "client_ip", c.ClientIP())
+		}
+		if err == auth.ErrTwoFactorLocked {
+			logger.WarnContext(c.Request.Context(), "Two-factor verification locked", "client_ip", c.ClientIP())
+		}
+		twoFactorErrorResponse(c, err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, tokens)
+}
+
+// EnrollTwoFactor handles POST /api/auth/2fa/enroll
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	enrollment, err := h.authService.EnrollTwoFactor(auth.GetPrincipal(c).UserID)
+	if err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+	SuccessResponse(c, http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor handles POST /api/auth/2fa/confirm
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	principal := auth.GetPrincipal(c)
+	codes, err := h.authService.ConfirmTwoFactor(principal.UserID, req.Code)
+	if err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Enabled two-factor authentication", "user_id", principal.UserID)
+	SuccessResponse(c, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor handles POST /api/auth/2fa/disable
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	principal := auth.GetPrincipal(c)
+	if err := h.authService.DisableTwoFactor(principal.UserID, req.Code); err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Disabled two-factor authentication", "user_id", principal.UserID)
+	SuccessResponse(c, http.StatusOK, true)
+}
+
+// RegenerateRecoveryCodes handles POST /api/auth/2fa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	principal := auth.GetPrincipal(c)
+	codes, err := h.authService.RegenerateRecoveryCodes(principal.UserID, req.Code)
+	if err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Regenerated recovery codes", "user_id", principal.UserID)
+	SuccessResponse(c, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// UpdateTwoFactorPolicy handles PUT /api/companies/:company/2fa-policy
+func (h *Handler) UpdateTwoFactorPolicy(c *gin.Context) {
+	name := c.Param("company")
+	if !h.authorize(c, policy.PermTwoFactorManage, policy.Resource{Company: name}) {
+		return
+	}
+
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+
+	company, err := h.companyService.SetRequireTwoFactor(name, req.Required)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Updated two-factor policy", "company", company.Name, "required", company.RequireTwoFactor, "by", auth.GetPrincipal(c).UserID)
+	SuccessResponse(c, http.StatusOK, company)
+}
+
+// twoFactorErrorResponse maps two-factor authentication errors to responses
+func twoFactorErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case auth.ErrInvalidTwoFactorCode:
+		AppErrorResponse(c, errors.ErrInvalidTwoFactorCode)
+	case auth.ErrInvalidToken:
+		AppErrorResponse(c, errors.ErrInvalidToken)
+	case auth.ErrTwoFactorNotEnrolled:
+		AppErrorResponse(c, errors.ErrTwoFactorNotEnrolled)
+	case auth.ErrTwoFactorAlreadyEnabled:
+		AppErrorResponse(c, errors.ErrTwoFactorAlreadyEnabled)
+	case auth.ErrTwoFactorRequired:
+		AppErrorResponse(c, errors.ErrTwoFactorRequired)
+	case auth.ErrTwoFactorLocked:
+		AppErrorResponse(c, errors.ErrTwoFactorLocked)
+	default:
+		logger.ErrorContext(c.Request.Context(), "Two-factor authentication failed", "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInternalServer))
+	}
+}
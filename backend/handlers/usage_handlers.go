@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"job-board/backend/database"
+	"job-board/backend/errors"
+	"job-board/backend/policy"
+	"job-board/backend/quota"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompanyUsage is the storage and bandwidth usage of a company
+type CompanyUsage struct {
+	Storage          *quota.StorageStatus  `json:"storage"`
+	BytesServed      int64                 `json:"bytesServed"`
+	StreamRequests   int64                 `json:"streamRequests"`
+	VideoBytesServed []database.VideoUsage `json:"videos"`
+}
+
+// QuotaRequest is the body of PUT /api/companies/:company/quota
+type QuotaRequest struct {
+	// StorageQuotaBytes is the new quota; null restores the default and 0 means unlimited
+	StorageQuotaBytes *int64 `json:"storageQuotaBytes"`
+}
+
+// GetCompanyUsage handles GET /api/companies/:company/usage
+func (h *Handler) GetCompanyUsage(c *gin.Context) {
+	company := c.Param("company")
+	if !h.authorize(c, policy.PermUsageRead, policy.Resource{Company: company}) {
+		return
+	}
+
+	status, err := h.quotaManager.StorageStatus(company)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+
+	videos, err := h.usageService.GetCompanyUsage(company)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+
+	usage := CompanyUsage{Storage: status, VideoBytesServed: videos}
+	for _, video := range videos {
+		usage.BytesServed += video.BytesServed
+		usage.StreamRequests += video.Requests
+	}
+
+	SuccessResponseWithWarnings(c, http.StatusOK, usage, status.Warnings())
+}
+
+// UpdateCompanyQuota handles PUT /api/companies/:company/quota
+func (h *Handler) UpdateCompanyQuota(c *gin.Context) {
+	if !h.authorize(c, policy.PermQuotaManage, policy.Resource{}) {
+		return
+	}
+
+	var req QuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrInvalidInput))
+		return
+	}
+	if req.StorageQuotaBytes != nil && *req.StorageQuotaBytes < 0 {
+		AppErrorResponse(c, errors.NewAppError(errors.ErrInvalidInput.Code, errors.ErrInvalidInput.Message, "storageQuotaBytes must not be negative"))
+		return
+	}
+
+	company, err := h.companyService.SetStorageQuota(c.Param("company"), req.StorageQuotaBytes)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, company)
+}
+
+// GetVideoUsage handles GET /api/videos/:id/usage
+func (h *Handler) GetVideoUsage(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	video, err := h.videoService.GetVideoByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrVideoNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermUsageRead, policy.Resource{Company: video.Job.Company}) {
+		return
+	}
+
+	usage, err := h.usageService.GetVideoUsage(id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
+		return
+	}
+	SuccessResponse(c, http.StatusOK, usage)
+}
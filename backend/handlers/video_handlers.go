@@ -1,18 +1,22 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 
 	"job-board/backend/database"
 	"job-board/backend/errors"
 	"job-board/backend/logger"
+	"job-board/backend/policy"
+	"job-board/backend/streaming"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetVideos handles GET /api/videos
 func (h *Handler) GetVideos(c *gin.Context) {
-	videos, err := h.videoService.GetAllVideos()
+	videos, err := h.videoService.GetAllVideos(c.Request.Context())
 	if err != nil {
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrDatabaseQuery))
 		return
@@ -28,11 +32,15 @@ func (h *Handler) GetVideo(c *gin.Context) {
 		return
 	}
 
-	video, err := h.videoService.GetVideoByID(id)
+	video, err := h.videoService.GetVideoByID(c.Request.Context(), id)
 	if err != nil {
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrVideoNotFound))
 		return
 	}
+	setETag(c, etag(video.Version))
+	if notModified(c, etag(video.Version)) {
+		return
+	}
 	SuccessResponse(c, http.StatusOK, video)
 }
 
@@ -53,26 +61,136 @@ func (h *Handler) CreateVideo(c *gin.Context) {
 		return
 	}
 
-	if err := h.videoService.CreateVideo(&video); err != nil {
+	job, err := h.jobService.GetJobByID(c.Request.Context(), video.JobID)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrJobNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermVideosWrite, policy.Resource{Company: job.Company}) {
+		return
+	}
+
+	if err := h.videoService.CreateVideo(c.Request.Context(), &video); err != nil {
 		AppErrorResponse(c, errors.WrapError(err, errors.ErrVideoCreationFailed))
 		return
 	}
 
+	setETag(c, etag(video.Version))
 	SuccessResponse(c, http.StatusCreated, video)
 }
 
+// UploadVideoFile handles PUT /api/videos/:id/file
+func (h *Handler) UploadVideoFile(c *gin.Context) {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		AppErrorResponse(c, errors.ErrInvalidInput)
+		return
+	}
+
+	video, err := h.videoService.GetVideoByID(c.Request.Context(), id)
+	if err != nil {
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrVideoNotFound))
+		return
+	}
+	if !h.authorize(c, policy.PermVideosWrite, policy.Resource{Company: video.Job.Company}) {
+		return
+	}
+	// Checked before the upload is read so a stale upload isn't stored
+	if !ifMatch(c, etag(video.Version), video) {
+		return
+	}
+
+	// An upload replaces the video's current file, which stops counting against the quota
+	key := strconv.FormatUint(uint64(video.ID), 10)
+	url := streaming.LocalVideoURLPrefix + key
+	var replacedBytes int64
+	if video.SizeBytes != nil && video.URL == url {
+		replacedBytes = *video.SizeBytes
+	}
+
+	status, appErr := h.quotaManager.CheckUpload(video.Job.Company, c.Request.ContentLength, replacedBytes)
+	if appErr != nil {
+		logger.WarnContext(c.Request.Context(), "Video upload rejected", "video_id", video.ID, "company", video.Job.Company, "reason", appErr.Error())
+		AppErrorResponse(c, appErr)
+		return
+	}
+
+	staged, err := h.videoStreamer.StageVideo(c.Request.Body, status.RemainingBytes())
+	if err == streaming.ErrUploadTooLarge {
+		logger.WarnContext(c.Request.Context(), "Video upload exceeded storage quota", "video_id", video.ID, "company", video.Job.Company)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrStorageQuotaExceeded))
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to store video upload", "video_id", video.ID, "error", err)
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrVideoUploadFailed))
+		return
+	}
+
+	// The file only replaces the current one once the version is checked, so a stale or
+	// failed upload leaves the file and its record as they were
+	defer staged.Discard()
+	size, checksum := staged.Size, staged.Checksum
+	store := func() error { return staged.Store(key) }
+	if err := h.videoService.UpdateVideoFile(c.Request.Context(), video.ID, video.Version, url, size, checksum, store); err != nil {
+		if err == database.ErrVersionConflict {
+			if current, getErr := h.videoService.GetVideoByID(c.Request.Context(), video.ID); getErr == nil {
+				preconditionFailed(c, etag(current.Version), current)
+				return
+			}
+		}
+		AppErrorResponse(c, errors.WrapError(err, errors.ErrVideoUploadFailed))
+		return
+	}
+	video.URL = url
+	video.SizeBytes = &size
+	video.Checksum = &checksum
+	video.Version++
+	setETag(c, etag(video.Version))
+
+	status.UsedBytes += size
+	SuccessResponseWithWarnings(c, http.StatusOK, video, status.Warnings())
+}
+
 // StreamVideo handles GET /video/:id
 func (h *Handler) StreamVideo(c *gin.Context) {
 	videoID := c.Param("id")
 
-	logger.Info("Streaming video request", "video_id", videoID)
+	logger.InfoContext(c.Request.Context(), "Streaming video request", "video_id", videoID)
 
-	err := h.videoStreamer.StreamVideo(c.Writer, c.Request, videoID)
+	// Only files of videos visible to the caller are streamed, so drafts stay private
+	video, err := h.videoService.GetVideoByURL(c.Request.Context(), streaming.LocalVideoURLPrefix+videoID)
 	if err != nil {
-		logger.Error("Failed to stream video", "video_id", videoID, "error", err)
+		logger.WarnContext(c.Request.Context(), "Streamed video has no visible record", "video_id", videoID, "error", err)
 		AppErrorResponse(c, errors.ErrVideoNotFound)
 		return
 	}
 
-	logger.Info("Successfully streamed video", "video_id", videoID)
+	// Videos of unpublished jobs are only visible to their company and must stay out of shared caches
+	public := video.Job.Status == database.JobStatusPublished
+	written, err := h.videoStreamer.StreamVideo(c.Writer, c.Request, videoID, public)
+	if written > 0 {
+		h.usageMeter.Record(video.ID, video.Job.Company, written)
+	}
+	if err == streaming.ErrTooManyStreams {
+		retryAfter := int(math.Ceil(h.videoStreamer.RetryAfter().Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		AppErrorResponse(c, errors.ErrTooManyStreams)
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to stream video", "video_id", videoID, "error", err)
+		AppErrorResponse(c, errors.ErrVideoNotFound)
+		return
+	}
+
+	logger.InfoContext(c.Request.Context(), "Successfully streamed video", "video_id", videoID)
+}
+
+// GetStreamingStats handles GET /api/streaming/stats
+func (h *Handler) GetStreamingStats(c *gin.Context) {
+	if !h.authorize(c, policy.PermStreamingStatsRead, policy.Resource{}) {
+		return
+	}
+	SuccessResponse(c, http.StatusOK, h.videoStreamer.Stats())
 }
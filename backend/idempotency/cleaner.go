@@ -0,0 +1,63 @@
+// Package idempotency runs the background upkeep of stored Idempotency-Key responses
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+)
+
+// Cleaner deletes expired Idempotency-Key responses and the keys of requests that never
+// completed, so requests don't pay for it
+type Cleaner struct {
+	service *database.IdempotencyService
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewCleaner creates a new cleaner
+func NewCleaner(service *database.IdempotencyService) *Cleaner {
+	return &Cleaner{service: service}
+}
+
+// Start deletes expired keys every interval in the background until Stop is called
+func (c *Cleaner) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	c.stopped = make(chan struct{})
+
+	go func() {
+		defer close(c.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := c.service.DeleteExpired(context.Background())
+				if err != nil {
+					logger.Error("Idempotency key cleanup failed", "error", err)
+				}
+				if deleted > 0 {
+					logger.Info("Deleted expired idempotency keys", "count", deleted)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+
+	logger.Info("Idempotency key cleaner started", "interval", interval)
+}
+
+// Stop stops the periodic cleanup and waits for a run in progress to finish
+func (c *Cleaner) Stop() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.stopped
+	c.stop = nil
+}
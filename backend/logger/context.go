@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is the key of values this package stores in a context
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	fieldsKey
+)
+
+// WithRequestID returns a copy of ctx whose log lines carry the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithFields returns a copy of ctx whose log lines carry the given key/value fields in
+// addition to those ctx already carries
+func WithFields(ctx context.Context, fields ...interface{}) context.Context {
+	record := slog.Record{}
+	record.Add(fields...)
+	attrs := append([]slog.Attr(nil), contextFields(ctx)...)
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, fieldsKey, attrs)
+}
+
+// contextFields returns the fields carried by ctx
+func contextFields(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(fieldsKey).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the request ID, fields and trace ID carried by the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if requestID := RequestID(ctx); requestID != "" {
+			r.AddAttrs(slog.String("request_id", requestID))
+		}
+		r.AddAttrs(contextFields(ctx)...)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
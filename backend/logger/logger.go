@@ -1,9 +1,14 @@
 package logger
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"time"
+	"strings"
+	"sync/atomic"
 )
 
 // LogLevel represents the logging level
@@ -17,99 +22,240 @@ const (
 	FATAL
 )
 
-// Logger provides structured logging functionality
+// levelFatal is the slog level of fatal messages, above slog.LevelError
+const levelFatal = slog.LevelError + 4
+
+// slogLevel returns the slog level of a logging level
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	case FATAL:
+		return levelFatal
+	}
+	return slog.LevelInfo
+}
+
+// ParseLevel parses a level name such as "info" or "WARN"
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	case "fatal":
+		return FATAL, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %q", name)
+}
+
+// Output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Formats lists the output formats
+var Formats = []string{FormatJSON, FormatText}
+
+// Logger provides structured logging functionality. Messages carry key/value fields, and
+// the context-aware methods add the request ID, user and trace ID carried by the context.
 type Logger struct {
-	level  LogLevel
-	logger *log.Logger
+	// level can be changed while other goroutines log
+	level  *slog.LevelVar
+	logger *slog.Logger
+}
+
+// New creates a logger writing to w in the given format, which is FormatJSON or FormatText
+func New(w io.Writer, format string, level LogLevel) (*Logger, error) {
+	l := &Logger{level: new(slog.LevelVar)}
+	l.SetLevel(level)
+
+	opts := &slog.HandlerOptions{Level: l.level, ReplaceAttr: replaceLevel}
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	l.logger = slog.New(contextHandler{handler})
+	return l, nil
 }
 
-// NewLogger creates a new logger instance
+// NewLogger creates a new logger instance writing JSON to stdout
 func NewLogger(level LogLevel) *Logger {
-	return &Logger{
-		level:  level,
-		logger: log.New(os.Stdout, "", log.LstdFlags),
+	l, _ := New(os.Stdout, FormatJSON, level)
+	return l
+}
+
+// replaceLevel names the fatal level, which slog would print as "ERROR+4"
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == levelFatal {
+			a.Value = slog.StringValue("FATAL")
+		}
 	}
+	return a
 }
 
 // SetLevel sets the logging level
 func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+	l.level.Set(level.slogLevel())
+}
+
+// Enabled reports whether messages at level are logged
+func (l *Logger) Enabled(level LogLevel) bool {
+	return l.level.Level() <= level.slogLevel()
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(message string, fields ...interface{}) {
-	if l.level <= DEBUG {
-		l.log("DEBUG", message, fields...)
-	}
+	l.logger.Log(context.Background(), slog.LevelDebug, message, fields...)
 }
 
 // Info logs an info message
 func (l *Logger) Info(message string, fields ...interface{}) {
-	if l.level <= INFO {
-		l.log("INFO", message, fields...)
-	}
+	l.logger.Log(context.Background(), slog.LevelInfo, message, fields...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(message string, fields ...interface{}) {
-	if l.level <= WARN {
-		l.log("WARN", message, fields...)
-	}
+	l.logger.Log(context.Background(), slog.LevelWarn, message, fields...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(message string, fields ...interface{}) {
-	if l.level <= ERROR {
-		l.log("ERROR", message, fields...)
-	}
+	l.logger.Log(context.Background(), slog.LevelError, message, fields...)
 }
 
 // Fatal logs a fatal message and exits
 func (l *Logger) Fatal(message string, fields ...interface{}) {
-	l.log("FATAL", message, fields...)
+	l.logger.Log(context.Background(), levelFatal, message, fields...)
 	os.Exit(1)
 }
 
-// log formats and logs a message
-func (l *Logger) log(level, message string, fields ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+// DebugContext logs a debug message with the fields carried by ctx
+func (l *Logger) DebugContext(ctx context.Context, message string, fields ...interface{}) {
+	l.logger.Log(ctx, slog.LevelDebug, message, fields...)
+}
 
-	if len(fields) > 0 {
-		l.logger.Printf("[%s] %s: %s %v", timestamp, level, message, fields)
-	} else {
-		l.logger.Printf("[%s] %s: %s", timestamp, level, message)
-	}
+// InfoContext logs an info message with the fields carried by ctx
+func (l *Logger) InfoContext(ctx context.Context, message string, fields ...interface{}) {
+	l.logger.Log(ctx, slog.LevelInfo, message, fields...)
+}
+
+// WarnContext logs a warning message with the fields carried by ctx
+func (l *Logger) WarnContext(ctx context.Context, message string, fields ...interface{}) {
+	l.logger.Log(ctx, slog.LevelWarn, message, fields...)
+}
+
+// ErrorContext logs an error message with the fields carried by ctx
+func (l *Logger) ErrorContext(ctx context.Context, message string, fields ...interface{}) {
+	l.logger.Log(ctx, slog.LevelError, message, fields...)
 }
 
 // Global logger instance
-var defaultLogger = NewLogger(INFO)
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	defaultLogger.Store(NewLogger(INFO))
+}
+
+// Setup replaces the global logger with one writing to stdout in the given format at the
+// given level. The standard library's log package and slog's default logger are routed
+// through it, so every log line has the same format.
+func Setup(format string, level LogLevel) error {
+	l, err := New(os.Stdout, format, level)
+	if err != nil {
+		return err
+	}
+	defaultLogger.Store(l)
+	slog.SetDefault(l.logger)
+	return nil
+}
 
 // SetGlobalLevel sets the global logging level
 func SetGlobalLevel(level LogLevel) {
-	defaultLogger.SetLevel(level)
+	defaultLogger.Load().SetLevel(level)
+}
+
+// Enabled reports whether the global logger logs messages at level
+func Enabled(level LogLevel) bool {
+	return defaultLogger.Load().Enabled(level)
 }
 
 // Debug logs a debug message using the global logger
 func Debug(message string, fields ...interface{}) {
-	defaultLogger.Debug(message, fields...)
+	defaultLogger.Load().Debug(message, fields...)
 }
 
 // Info logs an info message using the global logger
 func Info(message string, fields ...interface{}) {
-	defaultLogger.Info(message, fields...)
+	defaultLogger.Load().Info(message, fields...)
 }
 
 // Warn logs a warning message using the global logger
 func Warn(message string, fields ...interface{}) {
-	defaultLogger.Warn(message, fields...)
+	defaultLogger.Load().Warn(message, fields...)
 }
 
 // Error logs an error message using the global logger
 func Error(message string, fields ...interface{}) {
-	defaultLogger.Error(message, fields...)
+	defaultLogger.Load().Error(message, fields...)
 }
 
 // Fatal logs a fatal message using the global logger and exits
 func Fatal(message string, fields ...interface{}) {
-	defaultLogger.Fatal(message, fields...)
+	defaultLogger.Load().Fatal(message, fields...)
+}
+
+// DebugContext logs a debug message with the fields carried by ctx using the global logger
+func DebugContext(ctx context.Context, message string, fields ...interface{}) {
+	defaultLogger.Load().DebugContext(ctx, message, fields...)
+}
+
+// InfoContext logs an info message with the fields carried by ctx using the global logger
+func InfoContext(ctx context.Context, message string, fields ...interface{}) {
+	defaultLogger.Load().InfoContext(ctx, message, fields...)
+}
+
+// WarnContext logs a warning message with the fields carried by ctx using the global logger
+func WarnContext(ctx context.Context, message string, fields ...interface{}) {
+	defaultLogger.Load().WarnContext(ctx, message, fields...)
+}
+
+// ErrorContext logs an error message with the fields carried by ctx using the global logger
+func ErrorContext(ctx context.Context, message string, fields ...interface{}) {
+	defaultLogger.Load().ErrorContext(ctx, message, fields...)
+}
+
+// Writer returns a writer logging each line written to it at level with the global
+// logger, for libraries that only log to an io.Writer
+func Writer(level LogLevel) io.Writer {
+	return lineWriter{level: level.slogLevel()}
+}
+
+// lineWriter logs each line written to it
+type lineWriter struct {
+	level slog.Level
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\r\n"), []byte("\n")) {
+		if line := strings.TrimSpace(string(line)); line != "" {
+			defaultLogger.Load().logger.Log(context.Background(), w.level, line)
+		}
+	}
+	return len(p), nil
 }
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"job-board/backend/ratelimit"
+	"job-board/backend/streaming"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the name of every metric
+const namespace = "job_board"
+
+// UnmatchedRoute labels requests that matched no route, so unknown paths don't create
+// a series each
+const UnmatchedRoute = "unmatched"
+
+// Registry holds the metrics served on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by table, operation and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"table", "operation", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		httpRequests,
+		httpDuration,
+		dbQueryDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: namespace}),
+	)
+}
+
+// Handler serves the registry in the Prometheus text exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a finished HTTP request
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// ObserveQuery records a finished database query
+func ObserveQuery(table, operation string, failed bool, elapsed time.Duration) {
+	status := "ok"
+	if failed {
+		status = "error"
+	}
+	dbQueryDuration.WithLabelValues(table, operation, status).Observe(elapsed.Seconds())
+}
+
+// RegisterDBStats exposes the connection pool's statistics
+func RegisterDBStats(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterStreamer exposes the video streamer's activity
+func RegisterStreamer(vs *streaming.VideoStreamer) error {
+	streamMetrics := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "video_streams_active",
+			Help:      "Video streams currently being served.",
+		}, func() float64 { return float64(vs.Stats().ActiveStreams) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "video_streams_total",
+			Help:      "Video streams started.",
+		}, func() float64 { return float64(vs.Stats().TotalStreams) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "video_streams_rejected_total",
+			Help:      "Video streams rejected because too many were active.",
+		}, func() float64 { return float64(vs.Stats().RejectedStreams) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "video_streamed_bytes_total",
+			Help:      "Bytes of video sent to clients.",
+		}, func() float64 { return float64(vs.Stats().BytesServed) }),
+	}
+	for _, c := range streamMetrics {
+		if err := Registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterLimiter exposes how many requests the rate limiter rejected
+func RegisterLimiter(l *ratelimit.Limiter) error {
+	return Registry.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter.",
+	}, func() float64 { return float64(l.Rejected()) }))
+}
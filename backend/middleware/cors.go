@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"job-board/backend/config"
+	"job-board/backend/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsPolicy is a CORSConfig prepared for matching requests
+type corsPolicy struct {
+	allowAll      bool
+	origins       map[string]bool
+	wildcards     []wildcardOrigin
+	methods       string
+	headers       string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// wildcardOrigin matches any subdomain of a host, e.g. "https://*.example.com"
+type wildcardOrigin struct {
+	prefix string // scheme and "://"
+	suffix string // "." followed by the parent host and optional port
+}
+
+// matches reports whether origin is a subdomain of the wildcard's host
+func (w wildcardOrigin) matches(origin string) bool {
+	if len(origin) <= len(w.prefix)+len(w.suffix) || !strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+		return false
+	}
+	subdomain := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+	return !strings.ContainsAny(subdomain, "/:@")
+}
+
+// newCORSPolicy prepares cfg for matching
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		origins:       make(map[string]bool),
+		methods:       strings.Join(cfg.AllowMethods, ", "),
+		headers:       strings.Join(cfg.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(cfg.ExposeHeaders, ", "),
+		credentials:   cfg.AllowCredentials,
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	for _, origin := range cfg.AllowOrigins {
+		origin = normalizeOrigin(origin)
+		switch {
+		case origin == "*":
+			p.allowAll = true
+		case strings.Contains(origin, "://*."):
+			i := strings.Index(origin, "://*.")
+			p.wildcards = append(p.wildcards, wildcardOrigin{prefix: origin[:i+3], suffix: origin[i+4:]})
+		case origin != "":
+			p.origins[origin] = true
+		}
+	}
+
+	// Browsers reject "*" on credentialed requests, and reflecting any origin with
+	// credentials would let every site act as the user
+	if p.allowAll && p.credentials {
+		logger.Warn("CORS allows any origin, so credentials are not allowed")
+		p.credentials = false
+	}
+	return p
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or "" if it isn't allowed
+func (p *corsPolicy) allowOrigin(origin string) string {
+	if p.allowAll {
+		return "*"
+	}
+	normalized := normalizeOrigin(origin)
+	if p.origins[normalized] {
+		return origin
+	}
+	for _, wildcard := range p.wildcards {
+		if wildcard.matches(normalized) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// normalizeOrigin lowercases an origin and strips a trailing slash
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// CORS answers preflight requests and adds CORS headers for the allowed origins. Origins
+// are matched exactly or, for entries like "https://*.example.com", as any subdomain of
+// the host. Its configuration can be replaced while requests are being served.
+type CORS struct {
+	policy atomic.Pointer[corsPolicy]
+}
+
+// NewCORS creates a CORS handler for cfg
+func NewCORS(cfg config.CORSConfig) *CORS {
+	cors := &CORS{}
+	cors.Update(cfg)
+	return cors
+}
+
+// Update atomically replaces the CORS configuration
+func (cors *CORS) Update(cfg config.CORSConfig) {
+	cors.policy.Store(newCORSPolicy(cfg))
+}
+
+// Middleware returns the CORS middleware
+func (cors *CORS) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := cors.policy.Load()
+
+		// The response depends on the origin, so caches must not share it between origins
+		if !policy.allowAll {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		allowed := policy.allowOrigin(origin)
+		if allowed == "" {
+			if preflight {
+				logger.DebugContext(c.Request.Context(), "CORS origin not allowed", "origin", origin)
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", allowed)
+		if policy.credentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", policy.methods)
+			c.Header("Access-Control-Allow-Headers", policy.headers)
+			if policy.maxAge != "" {
+				c.Header("Access-Control-Max-Age", policy.maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if policy.exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", policy.exposeHeaders)
+		}
+		c.Next()
+	}
+}
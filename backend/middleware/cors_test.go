@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"job-board/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSAllowOrigin(t *testing.T) {
+	policy := newCORSPolicy(config.CORSConfig{
+		AllowOrigins: []string{"https://app.example.com/", "HTTPS://Admin.Example.com", "https://*.example.org", "http://*.local.test:3000"},
+	})
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"https://APP.example.com", "https://APP.example.com"},
+		{"https://admin.example.com", "https://admin.example.com"},
+		{"http://app.example.com", ""},
+		{"https://app.example.com:8443", ""},
+		{"https://evil.com", ""},
+		{"https://app.example.com.evil.com", ""},
+		{"https://jobs.example.org", "https://jobs.example.org"},
+		{"https://a.b.example.org", "https://a.b.example.org"},
+		{"https://example.org", ""},
+		{"https://.example.org", ""},
+		{"https://evilexample.org", ""},
+		{"https://evil.com/.example.org", ""},
+		{"https://user@x.example.org", ""},
+		{"http://jobs.example.org", ""},
+		{"http://web.local.test:3000", "http://web.local.test:3000"},
+		{"http://web.local.test", ""},
+		{"null", ""},
+	}
+	for _, tt := range tests {
+		if got := policy.allowOrigin(tt.origin); got != tt.want {
+			t.Errorf("allowOrigin(%q) = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSAnyOriginDropsCredentials(t *testing.T) {
+	policy := newCORSPolicy(config.CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	if got := policy.allowOrigin("https://evil.com"); got != "*" {
+		t.Errorf("allowOrigin = %q, want *", got)
+	}
+	if policy.credentials {
+		t.Error("credentials allowed for any origin")
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cors := NewCORS(config.CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		ExposeHeaders:    []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	router := gin.New()
+	router.Use(cors.Middleware())
+	router.GET("/jobs", func(c *gin.Context) { c.String(http.StatusOK, "jobs") })
+
+	tests := []struct {
+		name      string
+		method    string
+		origin    string
+		preflight bool
+		status    int
+		header    map[string]string
+	}{
+		{
+			"same-origin request", http.MethodGet, "", false, http.StatusOK,
+			map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			"allowed origin", http.MethodGet, "https://app.example.com", false, http.StatusOK,
+			map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "ETag",
+				"Access-Control-Allow-Methods":     "",
+			},
+		},
+		{
+			"other origin", http.MethodGet, "https://evil.com", false, http.StatusOK,
+			map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Credentials": ""},
+		},
+		{
+			"preflight", http.MethodOptions, "https://app.example.com", true, http.StatusNoContent,
+			map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Authorization, Content-Type",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			"preflight from another origin", http.MethodOptions, "https://evil.com", true, http.StatusForbidden,
+			map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/jobs", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.preflight {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		for name, want := range tt.header {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, name, got, want)
+			}
+		}
+	}
+
+	// A new configuration applies to the next request
+	cors.Update(config.CORSConfig{AllowOrigins: []string{"https://new.example.com"}})
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("after Update, Access-Control-Allow-Origin = %q for a removed origin", got)
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+	"job-board/backend/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader names the header clients send to make a request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for a retried request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentHeaders are the response headers stored and replayed along with the body
+var idempotentHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes requests carrying an Idempotency-Key header safe to retry.
+// The first request with a key is handled and its response stored for ttl; retries with
+// the same key and body get the stored response, with an Idempotent-Replayed header,
+// instead of being handled again. Reusing a key for a different request fails with 422,
+// and a retry while the first request is still being handled fails with 409. Keys are
+// scoped to the caller. Server errors and 429 responses aren't stored, so those requests
+// can be retried. Must run after AuthMiddleware.
+func IdempotencyMiddleware(service *database.IdempotencyService, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.ValidationErrorResponse(c, "Invalid Idempotency-Key", "keys are at most 255 characters")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			response.ValidationErrorResponse(c, "Failed to read request body", err.Error())
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		now := time.Now()
+		record := &database.IdempotencyKey{
+			Scope:       KeyByPrincipal(c),
+			Key:         key,
+			Fingerprint: hex.EncodeToString(hash.Sum(nil)),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		ctx := c.Request.Context()
+		stored, err := service.Begin(ctx, record)
+		if err == database.ErrIdempotencyKeyBusy {
+			c.Header("Retry-After", "1")
+			response.ErrorResponse(c, http.StatusConflict, "A request with this Idempotency-Key is still being handled", "")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to claim idempotency key", "error", err)
+			response.InternalServerErrorResponse(c, "Database query failed")
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			replay(c, stored, record.Fingerprint)
+			c.Abort()
+			return
+		}
+
+		// The key is released or completed even if the client goes away or the handler panics
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := service.Release(storeCtx, record.ID); err != nil {
+				logger.WarnContext(ctx, "Failed to release idempotency key", "error", err)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return
+		}
+		header := make(map[string]string, len(idempotentHeaders))
+		for _, name := range idempotentHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				header[name] = value
+			}
+		}
+		headerJSON, _ := json.Marshal(header)
+		if err := service.Complete(storeCtx, record.ID, status, headerJSON, writer.body.Bytes()); err != nil {
+			logger.WarnContext(ctx, "Failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay responds to a retry of a request with the stored response
+func replay(c *gin.Context, stored *database.IdempotencyKey, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		response.ErrorResponse(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", "")
+		return
+	}
+	if stored.StatusCode == 0 {
+		c.Header("Retry-After", "1")
+		response.ErrorResponse(c, http.StatusConflict, "A request with this Idempotency-Key is still being handled", "")
+		return
+	}
+
+	var header map[string]string
+	if len(stored.Header) > 0 {
+		if err := json.Unmarshal(stored.Header, &header); err != nil {
+			logger.WarnContext(c.Request.Context(), "Failed to read stored response headers", "error", err)
+		}
+	}
+	for name, value := range header {
+		c.Header(name, value)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	logger.InfoContext(c.Request.Context(), "Replayed idempotent response", "path", c.Request.URL.Path, "status", stored.StatusCode)
+	c.Data(stored.StatusCode, header["Content-Type"], stored.Body)
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testDatabase connects to and migrates the PostgreSQL database in TEST_DATABASE_URL,
+// skipping the test when it isn't set
+func testDatabase(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := database.ConnectDatabase(dsn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.CloseDatabase() })
+	if err := database.MigrateDatabase(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestIdempotencyMiddleware needs a PostgreSQL database in TEST_DATABASE_URL
+func TestIdempotencyMiddleware(t *testing.T) {
+	testDatabase(t)
+	gin.SetMode(gin.TestMode)
+	prefix := fmt.Sprintf("test-%d-", time.Now().UnixNano())
+	t.Cleanup(func() { database.DB.Where("key LIKE ?", prefix+"%").Delete(&database.IdempotencyKey{}) })
+
+	var router *gin.Engine
+	handled := 0
+	send := func(key, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, prefix+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	newRouter := func(ttl time.Duration) {
+		router = gin.New()
+		router.Use(IdempotencyMiddleware(database.NewIdempotencyService(database.DB), ttl))
+		router.POST("/jobs", func(c *gin.Context) {
+			handled++
+			c.Header("Location", fmt.Sprintf("/jobs/%d", handled))
+			c.String(http.StatusCreated, "job %d", handled)
+		})
+		router.POST("/fail", func(c *gin.Context) {
+			handled++
+			if handled == 1 {
+				c.String(http.StatusInternalServerError, "failed")
+				return
+			}
+			c.String(http.StatusCreated, "job %d", handled)
+		})
+		router.POST("/nested", func(c *gin.Context) {
+			handled++
+			// A retry that arrives while this request is still being handled
+			w := send("nested", "/nested", "{}")
+			c.String(w.Code, w.Header().Get("Retry-After"))
+		})
+	}
+	newRouter(time.Hour)
+
+	t.Run("claim and replay", func(t *testing.T) {
+		handled = 0
+		first := send("claim", "/jobs", `{"title":"a"}`)
+		retry := send("claim", "/jobs", `{"title":"a"}`)
+		if handled != 1 {
+			t.Errorf("handler ran %d times, want 1", handled)
+		}
+		if first.Code != http.StatusCreated || first.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("first request: %d, replayed %q", first.Code, first.Header().Get(IdempotentReplayedHeader))
+		}
+		if retry.Code != http.StatusCreated || retry.Body.String() != "job 1" ||
+			retry.Header().Get("Location") != "/jobs/1" || retry.Header().Get(IdempotentReplayedHeader) != "true" {
+			t.Errorf("retry: %d %q, Location %q, replayed %q, want the first response replayed",
+				retry.Code, retry.Body, retry.Header().Get("Location"), retry.Header().Get(IdempotentReplayedHeader))
+		}
+	})
+
+	t.Run("fingerprint mismatch", func(t *testing.T) {
+		handled = 0
+		send("mismatch", "/jobs", `{"title":"a"}`)
+		if w := send("mismatch", "/jobs", `{"title":"b"}`); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("reused key with another body: %d, want 422", w.Code)
+		}
+		if w := send("mismatch", "/fail", `{"title":"a"}`); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("reused key on another path: %d, want 422", w.Code)
+		}
+		if handled != 1 {
+			t.Errorf("handler ran %d times, want 1", handled)
+		}
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		handled = 0
+		w := send("nested", "/nested", "{}")
+		if w.Code != http.StatusConflict || w.Body.String() != "1" {
+			t.Errorf("retry while pending: %d with Retry-After %q, want 409 with Retry-After 1", w.Code, w.Body)
+		}
+		if handled != 1 {
+			t.Errorf("handler ran %d times, want 1", handled)
+		}
+	})
+
+	t.Run("released on server error", func(t *testing.T) {
+		handled = 0
+		if w := send("fail", "/fail", "{}"); w.Code != http.StatusInternalServerError {
+			t.Fatalf("first request: %d, want 500", w.Code)
+		}
+		w := send("fail", "/fail", "{}")
+		if w.Code != http.StatusCreated || w.Body.String() != "job 2" || w.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("retry after a server error: %d %q, want the request handled again", w.Code, w.Body)
+		}
+	})
+
+	t.Run("expired key taken over", func(t *testing.T) {
+		handled = 0
+		newRouter(-time.Second)
+		defer newRouter(time.Hour)
+		send("expired", "/jobs", `{"title":"a"}`)
+		w := send("expired", "/jobs", `{"title":"b"}`)
+		if w.Code != http.StatusCreated || w.Body.String() != "job 2" {
+			t.Errorf("request with an expired key: %d %q, want it handled", w.Code, w.Body)
+		}
+	})
+
+	t.Run("without a key", func(t *testing.T) {
+		handled = 0
+		send("", "/jobs", "{}")
+		send("", "/jobs", "{}")
+		if handled != 2 {
+			t.Errorf("handler ran %d times, want 2", handled)
+		}
+	})
+}
@@ -1,36 +1,56 @@
 package middleware
 
 import (
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
+	"job-board/backend/audit"
+	"job-board/backend/auth"
 	"job-board/backend/logger"
+	"job-board/backend/metrics"
+	"job-board/backend/policy"
+	"job-board/backend/ratelimit"
+	"job-board/backend/response"
+	"job-board/backend/tenant"
+	"job-board/backend/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// LoggerMiddleware provides structured logging for HTTP requests
+// LoggerMiddleware logs each request once it has finished, with the request ID, user and
+// trace ID added by the middleware after it
 func LoggerMiddleware() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		logger.Info("HTTP Request",
-			"method", param.Method,
-			"path", param.Path,
-			"status", param.StatusCode,
-			"latency", param.Latency,
-			"client_ip", param.ClientIP,
-			"user_agent", param.Request.UserAgent(),
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		logger.InfoContext(c.Request.Context(), "HTTP Request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+			"user_agent", c.Request.UserAgent(),
 		)
-		return ""
-	})
+	}
 }
 
 // RecoveryMiddleware provides panic recovery with structured logging
 func RecoveryMiddleware() gin.HandlerFunc {
-	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		if err, ok := recovered.(string); ok {
-			logger.Error("Panic recovered", "error", err, "path", c.Request.URL.Path)
-		} else {
-			logger.Error("Panic recovered", "error", recovered, "path", c.Request.URL.Path)
-		}
+	// The panic is logged here as a single record, rather than by gin line by line
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		logger.ErrorContext(c.Request.Context(), "Panic recovered", "error", recovered, "path", c.Request.URL.Path, "stack", string(debug.Stack()))
 		c.JSON(500, gin.H{
 			"success": false,
 			"error": gin.H{
@@ -51,6 +71,7 @@ func RequestIDMiddleware() gin.HandlerFunc {
 		}
 		c.Header("X-Request-ID", requestID)
 		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
 		c.Next()
 	}
 }
@@ -82,61 +103,228 @@ func SecurityMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware provides basic rate limiting
-func RateLimitMiddleware() gin.HandlerFunc {
-	// Simple in-memory rate limiter (in production, use Redis or similar)
-	requests := make(map[string][]time.Time)
+// MetricsMiddleware counts requests and records their latency by route template, so
+// /api/jobs/1 and /api/jobs/2 share a series
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
 
+// TracingMiddleware starts a server span for each request, continuing the trace named by
+// an incoming W3C traceparent header. The span is named after the route template and
+// carried in the request context, so spans for queries and video reads become its children.
+func TracingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		now := time.Now()
-
-		// Clean old requests (older than 1 minute)
-		if clientRequests, exists := requests[clientIP]; exists {
-			var validRequests []time.Time
-			for _, reqTime := range clientRequests {
-				if now.Sub(reqTime) < time.Minute {
-					validRequests = append(validRequests, reqTime)
-				}
-			}
-			requests[clientIP] = validRequests
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := tracing.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
+
+// RateLimitKeyFunc returns the key a request is counted under
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP counts requests per client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByPrincipal counts requests per API key or user, falling back to the client IP for
+// anonymous requests. Must run after AuthMiddleware.
+func KeyByPrincipal(c *gin.Context) string {
+	principal := auth.GetPrincipal(c)
+	switch {
+	case principal == nil:
+		return KeyByIP(c)
+	case principal.IsAPIKey():
+		return "key:" + strconv.FormatUint(uint64(principal.APIKeyID), 10)
+	default:
+		return "user:" + strconv.FormatUint(uint64(principal.UserID), 10)
+	}
+}
+
+// RateLimitMiddleware limits requests under the named policy, counting them per key.
+// Responses carry RateLimit-* headers, and rejected requests get 429 with Retry-After.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, policy string, key RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rateLimit(c, limiter, policy, key) {
+			return
 		}
+		c.Next()
+	}
+}
+
+// RateLimitByMethodMiddleware limits reads (GET, HEAD and OPTIONS) under one policy and all
+// other requests under another
+func RateLimitByMethodMiddleware(limiter *ratelimit.Limiter, readPolicy, writePolicy string, key RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := writePolicy
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			policy = readPolicy
+		}
+		if !rateLimit(c, limiter, policy, key) {
+			return
+		}
+		c.Next()
+	}
+}
 
-		// Check rate limit (100 requests per minute)
-		if len(requests[clientIP]) >= 100 {
-			logger.Warn("Rate limit exceeded", "client_ip", clientIP)
-			c.JSON(429, gin.H{
-				"success": false,
-				"error": gin.H{
-					"code":    429,
-					"message": "Too many requests",
-				},
-				"timestamp": now.Format(time.RFC3339),
-			})
+// rateLimit takes a token for the request and reports whether it may proceed, aborting it otherwise
+func rateLimit(c *gin.Context, limiter *ratelimit.Limiter, policy string, key RateLimitKeyFunc) bool {
+	k := key(c)
+	result, p, limited := limiter.Allow(c.Request.Context(), policy, k)
+	if !limited {
+		return true
+	}
+
+	// With several policies on a route, report the one closest to being exhausted
+	if remaining, err := strconv.Atoi(c.Writer.Header().Get("RateLimit-Remaining")); err != nil || result.Remaining <= remaining || !result.Allowed {
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", p.String())
+	}
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		logger.WarnContext(c.Request.Context(), "Rate limit exceeded", "policy", policy, "key", k, "path", c.Request.URL.Path)
+		response.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests", "Rate limit exceeded, retry later")
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// AuthMiddleware authenticates requests carrying an "Authorization: Bearer <token>" header
+// and stores the principal in the gin and request contexts. Requests without the header
+// continue anonymously; requests with an invalid token are rejected.
+func AuthMiddleware(authService *auth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found || token == "" {
+			response.UnauthorizedResponse(c, "Invalid authorization header")
+			c.Abort()
+			return
+		}
+
+		principal, err := authService.Authenticate(token)
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "Authentication failed", "path", c.Request.URL.Path, "error", err)
+			response.UnauthorizedResponse(c, "Invalid or expired token")
 			c.Abort()
 			return
 		}
 
-		// Add current request
-		requests[clientIP] = append(requests[clientIP], now)
+		auth.SetPrincipal(c, principal)
 		c.Next()
 	}
 }
 
-// CORSMiddleware provides CORS configuration
-func CORSMiddleware() gin.HandlerFunc {
+// RequireAuth rejects anonymous requests
+func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
-		c.Header("Access-Control-Allow-Credentials", "true")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if auth.GetPrincipal(c) == nil {
+			response.UnauthorizedResponse(c, "Authentication required")
+			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
+// RequireUser rejects requests not made by a signed-in user, such as those made with an API key
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.GetPrincipal(c)
+		if principal == nil {
+			response.UnauthorizedResponse(c, "Authentication required")
+			c.Abort()
+			return
+		}
+		if principal.IsAPIKey() {
+			response.ForbiddenResponse(c, "This endpoint is not available to API keys")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TenantMiddleware scopes the request's database access to the caller's company.
+// It must run after AuthMiddleware.
+func TenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := &tenant.Tenant{}
+		if principal := auth.GetPrincipal(c); principal != nil {
+			t.UserID = principal.UserID
+			t.Unrestricted = principal.Role == policy.RoleSiteAdmin && !principal.IsAPIKey()
+			if principal.IsAPIKey() || principal.Role == policy.RoleRecruiter || principal.Role == policy.RoleCompanyAdmin {
+				t.Company = principal.Company
+			}
+		}
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// AuditMiddleware names the caller and client IP of the request in the audit trail of the
+// changes it makes. It must run after AuthMiddleware.
+func AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := &audit.Actor{Type: audit.ActorAnonymous, IP: c.ClientIP()}
+		if principal := auth.GetPrincipal(c); principal != nil {
+			if principal.IsAPIKey() {
+				actor.Type = audit.ActorAPIKey
+				actor.ID = principal.APIKeyID
+			} else {
+				actor.Type = audit.ActorUser
+				actor.ID = principal.UserID
+				actor.Email = principal.Email
+			}
+		}
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
 		c.Next()
 	}
 }
@@ -0,0 +1,435 @@
+// Package patch applies partial updates to JSON documents: RFC 7396 JSON merge patches and
+// RFC 6902 JSON Patches.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Content types of the patch formats
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// decode parses a JSON value, keeping numbers as written
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc: members of patch objects replace
+// those of doc, null removes them and anything else replaces doc outright
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	value, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(merge(target, value))
+}
+
+func merge(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = merge(object[name], value)
+		}
+	}
+	return object
+}
+
+// Operation is an operation of a JSON Patch
+type Operation struct {
+	Op   string
+	Path []string
+	From []string
+	// Value is the operand of add, replace and test
+	Value interface{}
+}
+
+// JSONPatch is an RFC 6902 JSON Patch: a list of operations applied in order
+type JSONPatch []Operation
+
+// ParseJSONPatch parses and checks a JSON Patch document
+func ParseJSONPatch(data []byte) (JSONPatch, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+
+	patch := make(JSONPatch, 0, len(raw))
+	for i, object := range raw {
+		members, err := operationMembers(object)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		var op Operation
+		if err := json.Unmarshal(members["op"], &op.Op); err != nil {
+			return nil, fmt.Errorf("operation %d: missing or invalid op", i)
+		}
+		var path string
+		if err := json.Unmarshal(members["path"], &path); err != nil {
+			return nil, fmt.Errorf("operation %d: missing or invalid path", i)
+		}
+		if op.Path, err = parsePointer(path); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			value, ok := members["value"]
+			if !ok {
+				return nil, fmt.Errorf("operation %d: %s requires a value", i, op.Op)
+			}
+			if op.Value, err = decode(value); err != nil {
+				return nil, fmt.Errorf("operation %d: invalid value: %w", i, err)
+			}
+		case "move", "copy":
+			var from string
+			if err := json.Unmarshal(members["from"], &from); err != nil {
+				return nil, fmt.Errorf("operation %d: %s requires from", i, op.Op)
+			}
+			if op.From, err = parsePointer(from); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			if op.Op == "move" && isPrefix(op.From, op.Path) && len(op.From) < len(op.Path) {
+				return nil, fmt.Errorf("operation %d: can't move a value into itself", i)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", i, op.Op)
+		}
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+// operationMembers decodes the members of an operation. A repeated member, such as two
+// ops, makes the patch invalid rather than letting the last one win.
+func operationMembers(data json.RawMessage) (map[string]json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("not an object")
+	}
+	members := make(map[string]json.RawMessage)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, ok := members[name]; ok {
+			return nil, fmt.Errorf("repeated member %q", name)
+		}
+		members[name] = value
+	}
+	return members, nil
+}
+
+// Apply applies the patch to doc. It fails without changing anything if any operation
+// fails, including a test that doesn't match.
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	for i, op := range p {
+		switch op.Op {
+		case "add":
+			target, err = add(target, op.Path, op.Value)
+		case "remove":
+			target, _, err = remove(target, op.Path)
+		case "replace":
+			if target, _, err = remove(target, op.Path); err == nil {
+				target, err = add(target, op.Path, op.Value)
+			}
+		case "move":
+			var value interface{}
+			if target, value, err = remove(target, op.From); err == nil {
+				target, err = add(target, op.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			if value, err = get(target, op.From); err == nil {
+				target, err = add(target, op.Path, deepCopy(value))
+			}
+		case "test":
+			var value interface{}
+			if value, err = get(target, op.Path); err == nil && !equal(value, op.Value) {
+				err = fmt.Errorf("test of %s failed", formatPointer(op.Path))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// formatPointer joins reference tokens into a JSON pointer
+func formatPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// isPrefix reports whether the pointer prefix is pointer or one of its ancestors
+func isPrefix(prefix, pointer []string) bool {
+	if len(prefix) > len(pointer) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != pointer[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses an array index token; "-" is the index past the last element, which
+// only add accepts
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// get returns the value at path
+func get(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for i, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s not found", formatPointer(path[:i+1]))
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, fmt.Errorf("path %s not found", formatPointer(path[:i+1]))
+		}
+	}
+	return node, nil
+}
+
+// add sets the value at path, inserting it if path names an array element, and returns
+// the resulting document
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			container[token] = value
+			return container, nil
+		}
+		child, ok := container[token]
+		if !ok {
+			return nil, fmt.Errorf("path %s not found", formatPointer(path[:1]))
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+		return container, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			index, err := arrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := add(container[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+		return container, nil
+	default:
+		return nil, fmt.Errorf("path %s not found", formatPointer(path[:1]))
+	}
+}
+
+// remove removes the value at path and returns the resulting document and the value
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	token, rest := path[0], path[1:]
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, ok := container[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %s not found", formatPointer(path[:1]))
+		}
+		if len(rest) == 0 {
+			delete(container, token)
+			return container, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		container[token] = child
+		return container, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := container[index]
+			return append(container[:index], container[index+1:]...), removed, nil
+		}
+		child, removed, err := remove(container[index], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		container[index] = child
+		return container, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("path %s not found", formatPointer(path[:1]))
+	}
+}
+
+// deepCopy copies a decoded JSON value
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			object[name] = deepCopy(member)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, element := range v {
+			array[i] = deepCopy(element)
+		}
+		return array
+	default:
+		return value
+	}
+}
+
+// equal reports whether two decoded JSON values are equal; numbers are compared by value
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, member := range x {
+			other, ok := y[name]
+			if !ok || !equal(member, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		xf, errX := x.Float64()
+		yf, errY := y.Float64()
+		return errX == nil && errY == nil && xf == yf
+	default:
+		return a == b
+	}
+}
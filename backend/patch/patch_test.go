@@ -0,0 +1,271 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+// sameJSON reports whether two JSON documents hold the same value
+func sameJSON(t *testing.T, a, b string) bool {
+	t.Helper()
+	x, err := decode([]byte(a))
+	if err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	y, err := decode([]byte(b))
+	if err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return equal(x, y)
+}
+
+// The examples of RFC 7396 appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Numbers keep their precision
+		{`{"id":12345678901234567890}`, `{"n":1.10}`, `{"id":12345678901234567890,"n":1.10}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if !sameJSON(t, string(got), tt.want) {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	if _, err := MergePatch([]byte(`{"a":`), []byte(`{}`)); err == nil {
+		t.Error("invalid document accepted")
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":1} {"b":2}`)); err == nil {
+		t.Error("merge patch with trailing data accepted")
+	}
+}
+
+// The examples of RFC 6902 appendix A, and the errors of invalid pointers and indexes
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		// want is the patched document, or empty if the patch must fail
+		want string
+	}{
+		{
+			"A.1 adding an object member",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			"A.2 adding an array element",
+			`{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			"A.3 removing an object member",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`,
+		},
+		{
+			"A.4 removing an array element",
+			`{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`,
+		},
+		{
+			"A.5 replacing a value",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			"A.6 moving a value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			"A.7 moving an array element",
+			`{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			"A.8 testing a value: success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			"A.9 testing a value: error",
+			`{"baz":"qux"}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`,
+			"",
+		},
+		{
+			"A.10 adding a nested member object",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			"A.11 ignoring unrecognized elements",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			`{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			"A.12 adding to a nonexistent target",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			"",
+		},
+		{
+			"A.13 invalid JSON patch document",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			"",
+		},
+		{
+			"A.14 ~ escape ordering",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`,
+		},
+		{
+			"A.15 comparing strings and numbers",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`,
+			"",
+		},
+		{
+			"A.16 adding an array value",
+			`{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			"copying a value",
+			`{"foo":{"bar":[1]}}`,
+			`[{"op":"copy","from":"/foo","path":"/baz"},{"op":"add","path":"/baz/bar/-","value":2}]`,
+			`{"foo":{"bar":[1]},"baz":{"bar":[1,2]}}`,
+		},
+		{
+			"replacing the whole document",
+			`{"foo":"bar"}`,
+			`[{"op":"replace","path":"","value":[1]}]`,
+			`[1]`,
+		},
+		{
+			"numbers compared by value",
+			`{"n":1.0}`,
+			`[{"op":"test","path":"/n","value":1}]`,
+			`{"n":1.0}`,
+		},
+		{
+			"a failed operation discards the earlier ones",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"},{"op":"remove","path":"/missing"}]`,
+			"",
+		},
+
+		// Invalid pointers
+		{"pointer without a leading slash", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, ""},
+		{"from pointer without a leading slash", `{"foo":"bar"}`, `[{"op":"copy","from":"foo","path":"/baz"}]`, ""},
+		{"pointer into a string", `{"foo":"bar"}`, `[{"op":"add","path":"/foo/bar","value":1}]`, ""},
+		{"missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, ""},
+		{"moving a value into itself", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar"}]`, ""},
+
+		// Array indexes
+		{"add at the end index", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/2","value":3}]`, `{"foo":[1,2,3]}`},
+		{"add past the end", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/3","value":3}]`, ""},
+		{"remove past the end", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/2"}]`, ""},
+		{"replace past the end", `{"foo":[1,2]}`, `[{"op":"replace","path":"/foo/2","value":3}]`, ""},
+		{"test past the end", `{"foo":[1,2]}`, `[{"op":"test","path":"/foo/5","value":1}]`, ""},
+		{"remove with -", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/-"}]`, ""},
+		{"negative index", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/-1"}]`, ""},
+		{"index with a sign", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/+1"}]`, ""},
+		{"index with a leading zero", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`, ""},
+		{"index that isn't a number", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/one"}]`, ""},
+		{"empty index", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/"}]`, ""},
+
+		// Invalid operations
+		{"unknown op", `{}`, `[{"op":"merge","path":"/foo"}]`, ""},
+		{"missing op", `{}`, `[{"path":"/foo","value":1}]`, ""},
+		{"missing path", `{}`, `[{"op":"add","value":1}]`, ""},
+		{"missing value", `{}`, `[{"op":"add","path":"/foo"}]`, ""},
+		{"missing from", `{"foo":1}`, `[{"op":"move","path":"/bar"}]`, ""},
+		{"operation that isn't an object", `{}`, `["add"]`, ""},
+		{"patch that isn't an array", `{}`, `{"op":"add","path":"/foo","value":1}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(tt.doc, tt.patch)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("patch succeeded with %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patch failed: %v", err)
+			}
+			if !sameJSON(t, got, tt.want) {
+				t.Errorf("patched document = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func applyPatch(doc, data string) (string, error) {
+	patch, err := ParseJSONPatch([]byte(data))
+	if err != nil {
+		return "", err
+	}
+	got, err := patch.Apply([]byte(doc))
+	return string(got), err
+}
+
+func TestJSONPatchRepeatedMember(t *testing.T) {
+	_, err := ParseJSONPatch([]byte(`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`))
+	if err == nil || !strings.Contains(err.Error(), `repeated member "op"`) {
+		t.Errorf("ParseJSONPatch = %v, want a repeated member error", err)
+	}
+}
+
+func TestPointerRoundTrip(t *testing.T) {
+	for _, pointer := range []string{"", "/", "/foo", "/a~1b", "/m~0n", "/~01", "/foo/0/bar"} {
+		tokens, err := parsePointer(pointer)
+		if err != nil {
+			t.Errorf("parsePointer(%q): %v", pointer, err)
+			continue
+		}
+		if got := formatPointer(tokens); got != pointer {
+			t.Errorf("formatPointer(parsePointer(%q)) = %q", pointer, got)
+		}
+	}
+}
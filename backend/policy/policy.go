@@ -0,0 +1,195 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
+)
+
+// Role is a user's role
+type Role = string
+
+// Roles
+const (
+	RoleCandidate    Role = database.RoleCandidate
+	RoleRecruiter    Role = database.RoleRecruiter
+	RoleCompanyAdmin Role = database.RoleCompanyAdmin
+	RoleSiteAdmin    Role = database.RoleSiteAdmin
+)
+
+// Permission is an action a role may perform
+type Permission string
+
+// Permissions
+const (
+	// PermJobsRead allows reading a company's jobs, including ones not visible to the public
+	PermJobsRead Permission = "jobs:read"
+	// PermJobsWrite allows creating, updating and deleting a company's jobs
+	PermJobsWrite Permission = "jobs:write"
+	// PermVideosWrite allows creating and uploading a company's videos
+	PermVideosWrite Permission = "videos:write"
+	// PermApplicationsCreate allows applying to jobs
+	PermApplicationsCreate Permission = "applications:create"
+	// PermApplicationsReadOwn allows reading one's own applications
+	PermApplicationsReadOwn Permission = "applications:read:own"
+	// PermApplicationsRead allows reading the applications to a company's jobs
+	PermApplicationsRead Permission = "applications:read"
+	// PermApplicationsManage allows changing the status of applications to a company's jobs
+	PermApplicationsManage Permission = "applications:manage"
+	// PermUsageRead allows reading a company's storage and bandwidth usage
+	PermUsageRead Permission = "usage:read"
+	// PermMembersManage allows assigning roles to a company's members
+	PermMembersManage Permission = "members:manage"
+	// PermQuotaManage allows changing a company's storage quota
+	PermQuotaManage Permission = "quota:manage"
+	// PermUsersManage allows assigning any role to any user
+	PermUsersManage Permission = "users:manage"
+	// PermSSOManage allows configuring a company's single sign-on identity provider
+	PermSSOManage Permission = "sso:manage"
+	// PermSSOTrustMFA allows marking an identity provider as enforcing a second factor, so
+	// accounts with two-factor authentication may sign in through it
+	PermSSOTrustMFA Permission = "sso:trust_mfa"
+	// PermTwoFactorManage allows requiring two-factor authentication for a company's members
+	PermTwoFactorManage Permission = "2fa:manage"
+	// PermAPIKeysManage allows creating, listing and revoking a company's API keys
+	PermAPIKeysManage Permission = "api_keys:manage"
+	// PermAuditRead allows reading and exporting the audit trail
+	PermAuditRead Permission = "audit:read"
+	// PermStreamingStatsRead allows reading the platform-wide streaming statistics
+	PermStreamingStatsRead Permission = "streaming:stats:read"
+	// PermTrashManage allows listing, restoring and permanently deleting deleted jobs and videos
+	PermTrashManage Permission = "trash:manage"
+)
+
+var (
+	// ErrUnauthenticated is returned when an anonymous caller needs a permission
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden is returned when the caller lacks a permission
+	ErrForbidden = errors.New("permission denied")
+	// ErrTwoFactorEnrollment is returned when the caller must enroll in two-factor authentication first
+	ErrTwoFactorEnrollment = errors.New("two-factor authentication enrollment required")
+)
+
+// APIKeyScopes are the permissions that can be granted to API keys
+var APIKeyScopes = []Permission{PermJobsRead, PermJobsWrite, PermVideosWrite}
+
+// rolePermissions lists the permissions of each role. Site admins are allowed everything.
+var rolePermissions = map[Role][]Permission{
+	RoleCandidate: {
+		PermApplicationsCreate,
+		PermApplicationsReadOwn,
+	},
+	RoleRecruiter: {
+		PermJobsRead,
+		PermJobsWrite,
+		PermVideosWrite,
+		PermApplicationsRead,
+		PermApplicationsManage,
+		PermUsageRead,
+	},
+	RoleCompanyAdmin: {
+		PermJobsRead,
+		PermJobsWrite,
+		PermVideosWrite,
+		PermApplicationsRead,
+		PermApplicationsManage,
+		PermUsageRead,
+		PermMembersManage,
+		PermAPIKeysManage,
+		PermSSOManage,
+		PermTwoFactorManage,
+	},
+}
+
+// Resource identifies who owns the target of an action. Empty fields aren't checked.
+type Resource struct {
+	// Company the resource belongs to; the caller must be a member of it
+	Company string
+	// OwnerID is the user the resource belongs to; the caller must be that user
+	OwnerID uint
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role Role) bool {
+	if role == RoleSiteAdmin {
+		return true
+	}
+	_, exists := rolePermissions[role]
+	return exists
+}
+
+// ValidScope reports whether scope can be granted to an API key
+func ValidScope(scope string) bool {
+	for _, granted := range APIKeyScopes {
+		if string(granted) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether a role grants a permission
+func HasPermission(role Role, perm Permission) bool {
+	if role == RoleSiteAdmin {
+		return true
+	}
+	for _, granted := range rolePermissions[role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks that a principal may perform perm on a resource. Site admins may
+// do anything; users need the permission through their role and API keys through
+// their scopes, and both must belong to the resource's company or own the resource.
+func Authorize(principal *auth.Principal, perm Permission, resource Resource) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if principal.EnrollTwoFactor {
+		return ErrTwoFactorEnrollment
+	}
+	if principal.IsAPIKey() {
+		if !principal.HasScope(string(perm)) {
+			return ErrForbidden
+		}
+	} else if principal.Role == RoleSiteAdmin {
+		return nil
+	} else if !HasPermission(principal.Role, perm) {
+		return ErrForbidden
+	}
+	if resource.Company != "" && !SameCompany(principal.Company, resource.Company) {
+		return ErrForbidden
+	}
+	if resource.OwnerID != 0 && resource.OwnerID != principal.UserID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Check is a single permission check for AuthorizeAny
+type Check struct {
+	Permission Permission
+	Resource   Resource
+}
+
+// AuthorizeAny succeeds if any of the checks succeeds, e.g. a recruiter reading
+// their company's application or a candidate reading their own
+func AuthorizeAny(principal *auth.Principal, checks ...Check) error {
+	err := ErrForbidden
+	for _, check := range checks {
+		if err = Authorize(principal, check.Permission, check.Resource); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// SameCompany reports whether two company names refer to the same company
+func SameCompany(a, b string) bool {
+	return a != "" && strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"testing"
+
+	"job-board/backend/auth"
+)
+
+var (
+	candidate    = &auth.Principal{UserID: 1, Role: RoleCandidate}
+	recruiter    = &auth.Principal{UserID: 2, Role: RoleRecruiter, Company: "Acme"}
+	companyAdmin = &auth.Principal{UserID: 3, Role: RoleCompanyAdmin, Company: "Acme"}
+	siteAdmin    = &auth.Principal{UserID: 4, Role: RoleSiteAdmin}
+	enrolling    = &auth.Principal{UserID: 5, Role: RoleRecruiter, Company: "Acme", EnrollTwoFactor: true}
+	apiKey       = &auth.Principal{APIKeyID: 6, Company: "Acme", Scopes: []string{string(PermJobsRead), string(PermJobsWrite)}}
+)
+
+func TestAuthorize(t *testing.T) {
+	acme := Resource{Company: "Acme"}
+	globex := Resource{Company: "Globex"}
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		perm      Permission
+		resource  Resource
+		want      error
+	}{
+		{"anonymous", nil, PermJobsRead, Resource{}, ErrUnauthenticated},
+		{"candidate writing jobs", candidate, PermJobsWrite, acme, ErrForbidden},
+		{"candidate applying", candidate, PermApplicationsCreate, Resource{}, nil},
+		{"recruiter of the company", recruiter, PermJobsWrite, acme, nil},
+		{"recruiter with the company in other case", recruiter, PermJobsWrite, Resource{Company: " acme "}, nil},
+		{"recruiter of another company", recruiter, PermJobsWrite, globex, ErrForbidden},
+		{"recruiter managing members", recruiter, PermMembersManage, acme, ErrForbidden},
+		{"company admin managing members", companyAdmin, PermMembersManage, acme, nil},
+		{"company admin of another company", companyAdmin, PermMembersManage, globex, ErrForbidden},
+		{"company admin managing quotas", companyAdmin, PermQuotaManage, acme, ErrForbidden},
+		{"company admin trusting an identity provider", companyAdmin, PermSSOTrustMFA, acme, ErrForbidden},
+		{"company admin reading the audit trail", companyAdmin, PermAuditRead, Resource{}, ErrForbidden},
+		{"site admin", siteAdmin, PermQuotaManage, globex, nil},
+		{"site admin owning nothing", siteAdmin, PermApplicationsReadOwn, Resource{OwnerID: 1}, nil},
+		{"user enrolling in two-factor", enrolling, PermJobsRead, acme, ErrTwoFactorEnrollment},
+		{"recruiter without a company", &auth.Principal{UserID: 7, Role: RoleRecruiter}, PermJobsWrite, acme, ErrForbidden},
+		{"unknown role", &auth.Principal{UserID: 8, Role: "owner"}, PermJobsRead, Resource{}, ErrForbidden},
+		{"owner", candidate, PermApplicationsReadOwn, Resource{OwnerID: 1}, nil},
+		{"not the owner", candidate, PermApplicationsReadOwn, Resource{OwnerID: 9}, ErrForbidden},
+		{"API key scope", apiKey, PermJobsWrite, acme, nil},
+		{"API key of another company", apiKey, PermJobsWrite, globex, ErrForbidden},
+		{"API key without the scope", apiKey, PermVideosWrite, acme, ErrForbidden},
+		{"API key beyond the key scopes", apiKey, PermMembersManage, acme, ErrForbidden},
+	}
+	for _, tt := range tests {
+		if err := Authorize(tt.principal, tt.perm, tt.resource); err != tt.want {
+			t.Errorf("%s: Authorize = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestApplicationAccess checks the rules for reading one application: recruiters of the
+// job's company and the candidate who applied may read it
+func TestApplicationAccess(t *testing.T) {
+	checks := func(company string, candidateID uint) []Check {
+		return []Check{
+			{Permission: PermApplicationsRead, Resource: Resource{Company: company}},
+			{Permission: PermApplicationsReadOwn, Resource: Resource{OwnerID: candidateID}},
+		}
+	}
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		company   string
+		candidate uint
+		want      error
+	}{
+		{"candidate who applied", candidate, "Acme", 1, nil},
+		{"another candidate", candidate, "Acme", 9, ErrForbidden},
+		{"recruiter of the company", recruiter, "Acme", 9, nil},
+		{"recruiter of another company", recruiter, "Globex", 9, ErrForbidden},
+		{"company admin", companyAdmin, "Acme", 9, nil},
+		{"site admin", siteAdmin, "Globex", 9, nil},
+		{"API key", apiKey, "Acme", 9, ErrForbidden},
+		{"anonymous", nil, "Acme", 1, ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		if err := AuthorizeAny(tt.principal, checks(tt.company, tt.candidate)...); err != tt.want {
+			t.Errorf("%s: AuthorizeAny = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := AuthorizeAny(siteAdmin); err != ErrForbidden {
+		t.Errorf("AuthorizeAny without checks = %v, want ErrForbidden", err)
+	}
+}
+
+func TestRolesAndScopes(t *testing.T) {
+	for _, role := range []Role{RoleCandidate, RoleRecruiter, RoleCompanyAdmin, RoleSiteAdmin} {
+		if !ValidRole(role) {
+			t.Errorf("ValidRole(%q) = false", role)
+		}
+	}
+	if ValidRole("owner") || ValidRole("") {
+		t.Error("ValidRole accepted an unknown role")
+	}
+
+	for _, scope := range APIKeyScopes {
+		if !ValidScope(string(scope)) {
+			t.Errorf("ValidScope(%q) = false", scope)
+		}
+	}
+	for _, scope := range []Permission{PermMembersManage, PermAPIKeysManage, PermUsersManage, ""} {
+		if ValidScope(string(scope)) {
+			t.Errorf("ValidScope(%q) = true, want API keys limited to jobs and videos", scope)
+		}
+	}
+}
+
+func TestSameCompany(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Acme", "Acme", true},
+		{"Acme", "ACME", true},
+		{" Acme", "acme ", true},
+		{"Acme", "Acme Inc", false},
+		{"", "", false},
+		{"", "Acme", false},
+	}
+	for _, tt := range tests {
+		if got := SameCompany(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameCompany(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+)
+
+// pendingUsage is bandwidth served since the last flush
+type pendingUsage struct {
+	company  string
+	bytes    int64
+	requests int64
+}
+
+// Meter accumulates bytes served per video in memory and periodically writes them to
+// the database, so streaming, and range requests in particular, don't hit the database
+// on every response.
+type Meter struct {
+	usageService *database.UsageService
+
+	mu      sync.Mutex
+	pending map[uint]*pendingUsage
+	// flushErr is the error of the last flush, if it failed
+	flushErr error
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewMeter creates a new bandwidth meter
+func NewMeter(usageService *database.UsageService) *Meter {
+	return &Meter{
+		usageService: usageService,
+		pending:      make(map[uint]*pendingUsage),
+	}
+}
+
+// Record meters bytes served for a video of a company
+func (m *Meter) Record(videoID uint, company string, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage, exists := m.pending[videoID]
+	if !exists {
+		usage = &pendingUsage{company: company}
+		m.pending[videoID] = usage
+	}
+	usage.bytes += bytes
+	usage.requests++
+}
+
+// Flush writes the accumulated usage to the database. Usage that fails to be written
+// is kept for the next flush.
+func (m *Meter) Flush() error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[uint]*pendingUsage)
+	m.mu.Unlock()
+
+	var firstErr error
+	for videoID, usage := range pending {
+		if err := m.usageService.AddBytesServed(videoID, usage.company, usage.bytes, usage.requests); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			m.requeue(videoID, usage)
+		}
+	}
+
+	m.mu.Lock()
+	m.flushErr = firstErr
+	m.mu.Unlock()
+	return firstErr
+}
+
+// Health returns an error when the last flush failed, reporting how much usage is waiting
+// to be written
+func (m *Meter) Health(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.flushErr != nil {
+		return fmt.Errorf("last flush failed with %d video(s) pending: %w", len(m.pending), m.flushErr)
+	}
+	return nil
+}
+
+// requeue puts usage that failed to be written back into the pending set
+func (m *Meter) requeue(videoID uint, usage *pendingUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, exists := m.pending[videoID]; exists {
+		existing.bytes += usage.bytes
+		existing.requests += usage.requests
+		return
+	}
+	m.pending[videoID] = usage
+}
+
+// Start flushes the meter every interval in the background until Stop is called
+func (m *Meter) Start(interval time.Duration) {
+	m.stop = make(chan struct{})
+	m.stopped = make(chan struct{})
+
+	go func() {
+		defer close(m.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.Flush(); err != nil {
+					logger.Error("Failed to flush bandwidth usage", "error", err)
+				}
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic flush and writes any remaining usage
+func (m *Meter) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.stopped
+		m.stop = nil
+	}
+	if err := m.Flush(); err != nil {
+		logger.Error("Failed to flush bandwidth usage", "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package quota
+
+import (
+	"fmt"
+
+	"job-board/backend/database"
+	"job-board/backend/errors"
+)
+
+// StorageStatus describes a company's video storage usage against its quota
+type StorageStatus struct {
+	Company        string `json:"company"`
+	UsedBytes      int64  `json:"usedBytes"`
+	QuotaBytes     int64  `json:"quotaBytes"` // 0 means unlimited
+	SoftLimitBytes int64  `json:"softLimitBytes"`
+}
+
+// Unlimited reports whether the company has no storage quota
+func (s *StorageStatus) Unlimited() bool {
+	return s.QuotaBytes <= 0
+}
+
+// RemainingBytes returns the storage left before the hard limit, or -1 when unlimited
+func (s *StorageStatus) RemainingBytes() int64 {
+	if s.Unlimited() {
+		return -1
+	}
+	if s.UsedBytes >= s.QuotaBytes {
+		return 0
+	}
+	return s.QuotaBytes - s.UsedBytes
+}
+
+// Warnings returns the soft/hard-limit warnings for the current usage
+func (s *StorageStatus) Warnings() []*errors.AppError {
+	if s.Unlimited() {
+		return nil
+	}
+	if s.UsedBytes >= s.QuotaBytes {
+		return []*errors.AppError{errors.NewAppError(errors.ErrStorageQuotaExceeded.Code, errors.ErrStorageQuotaExceeded.Message, s.describe())}
+	}
+	if s.UsedBytes >= s.SoftLimitBytes {
+		return []*errors.AppError{errors.NewAppError(errors.ErrStorageSoftLimitReached.Code, errors.ErrStorageSoftLimitReached.Message, s.describe())}
+	}
+	return nil
+}
+
+// describe returns a human readable summary of the usage
+func (s *StorageStatus) describe() string {
+	return fmt.Sprintf("%s is using %d of %d bytes", s.Company, s.UsedBytes, s.QuotaBytes)
+}
+
+// Manager enforces per-company video storage quotas
+type Manager struct {
+	companyService   *database.CompanyService
+	usageService     *database.UsageService
+	defaultQuota     int64
+	softLimitPercent int
+}
+
+// NewManager creates a new quota manager
+func NewManager(companyService *database.CompanyService, usageService *database.UsageService, defaultQuota int64, softLimitPercent int) *Manager {
+	return &Manager{
+		companyService:   companyService,
+		usageService:     usageService,
+		defaultQuota:     defaultQuota,
+		softLimitPercent: softLimitPercent,
+	}
+}
+
+// StorageStatus returns the storage usage of a company against its quota
+func (m *Manager) StorageStatus(company string) (*StorageStatus, error) {
+	quota := m.defaultQuota
+	settings, err := m.companyService.GetCompanyByName(company)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.StorageQuotaBytes != nil {
+		quota = *settings.StorageQuotaBytes
+	}
+
+	used, err := m.usageService.StorageUsedByCompany(company)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StorageStatus{
+		Company:        company,
+		UsedBytes:      used,
+		QuotaBytes:     quota,
+		SoftLimitBytes: quota * int64(m.softLimitPercent) / 100,
+	}, nil
+}
+
+// CheckUpload verifies that a company can store incomingBytes more, given that the upload
+// replaces a file of replacedBytes. incomingBytes is -1 when the upload size isn't known
+// up front. It returns the storage status the upload should be limited against.
+func (m *Manager) CheckUpload(company string, incomingBytes, replacedBytes int64) (*StorageStatus, *errors.AppError) {
+	status, err := m.StorageStatus(company)
+	if err != nil {
+		return nil, errors.WrapError(err, errors.ErrDatabaseQuery)
+	}
+
+	// The replaced file no longer counts once the upload succeeds
+	status.UsedBytes -= replacedBytes
+	if status.Unlimited() {
+		return status, nil
+	}
+
+	if status.RemainingBytes() == 0 || (incomingBytes >= 0 && incomingBytes > status.RemainingBytes()) {
+		return status, errors.NewAppError(errors.ErrStorageQuotaExceeded.Code, errors.ErrStorageQuotaExceeded.Message,
+			fmt.Sprintf("%s has %d of %d bytes left", company, status.RemainingBytes(), status.QuotaBytes))
+	}
+	return status, nil
+}
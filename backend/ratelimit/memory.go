@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// memoryShards spreads buckets over independently locked shards to limit contention
+const memoryShards = 64
+
+// sweepInterval is how often a shard drops buckets that have refilled completely
+const sweepInterval = time.Minute
+
+// bucket is a token bucket
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	rate     float64
+	capacity float64
+}
+
+// full reports whether the bucket has refilled completely by now. A full bucket is
+// the same as no bucket, so it can be dropped without changing any limit.
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.capacity
+}
+
+// shard is a mutex-protected set of buckets
+type shard struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// MemoryStore keeps token buckets in process memory. Limits are per instance.
+type MemoryStore struct {
+	shards [memoryShards]shard
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{}
+	for i := range s.shards {
+		s.shards[i].buckets = make(map[string]*bucket)
+	}
+	return s
+}
+
+// Take removes a token from the bucket at key
+func (s *MemoryStore) Take(_ context.Context, key string, rate float64, burst int, now time.Time) (Result, error) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if now.Sub(sh.lastSweep) > sweepInterval {
+		sh.sweep(now)
+	}
+
+	b, ok := sh.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		sh.buckets[key] = b
+	}
+	b.rate = rate
+	b.capacity = float64(burst)
+
+	var result Result
+	b.tokens, result = bucketState(b.tokens, b.last, now, rate, burst)
+	b.last = now
+	return result, nil
+}
+
+// Len returns the number of buckets held
+func (s *MemoryStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		n += len(s.shards[i].buckets)
+		s.shards[i].mu.Unlock()
+	}
+	return n
+}
+
+// shard returns the shard holding key
+func (s *MemoryStore) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &s.shards[h.Sum32()%memoryShards]
+}
+
+// sweep drops the shard's idle buckets; the caller must hold the lock
+func (sh *shard) sweep(now time.Time) {
+	for key, b := range sh.buckets {
+		if b.full(now) {
+			delete(sh.buckets, key)
+		}
+	}
+	sh.lastSweep = now
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefillAndBurst(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	// 1 token per second, bursts of 3
+	steps := []struct {
+		at        time.Duration
+		allowed   bool
+		remaining int
+	}{
+		{0, true, 2},
+		{0, true, 1},
+		{0, true, 0},
+		{0, false, 0},
+		{500 * time.Millisecond, false, 0},
+		{time.Second, true, 0},
+		{time.Second, false, 0},
+		{3 * time.Second, true, 1},
+		// A long idle period refills up to the burst, not beyond it
+		{time.Hour, true, 2},
+		{time.Hour, true, 1},
+		{time.Hour, true, 0},
+		{time.Hour, false, 0},
+	}
+
+	store := NewMemoryStore()
+	for i, step := range steps {
+		result, err := store.Take(context.Background(), "client", 1, 3, start.Add(step.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != step.allowed || result.Remaining != step.remaining || result.Limit != 3 {
+			t.Errorf("step %d at %v: allowed=%v remaining=%d limit=%d, want allowed=%v remaining=%d limit=3",
+				i, step.at, result.Allowed, result.Remaining, result.Limit, step.allowed, step.remaining)
+		}
+		if !result.Allowed && result.RetryAfter <= 0 {
+			t.Errorf("step %d: rejected without a Retry-After", i)
+		}
+	}
+}
+
+func TestMemoryStoreResult(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		takes      int
+		rate       float64
+		burst      int
+		allowed    bool
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{"first request", 1, 1, 10, true, time.Second, 0},
+		{"half empty", 5, 2, 10, true, 2500 * time.Millisecond, 0},
+		{"empty", 10, 1, 10, true, 10 * time.Second, 0},
+		{"rejected", 11, 1, 10, false, 10 * time.Second, time.Second},
+		{"rejected at a slow rate", 3, 0.5, 2, false, 4 * time.Second, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		store := NewMemoryStore()
+		var result Result
+		for i := 0; i < tt.takes; i++ {
+			result, _ = store.Take(context.Background(), "client", tt.rate, tt.burst, now)
+		}
+		if result.Allowed != tt.allowed || result.Reset != tt.reset || result.RetryAfter != tt.retryAfter {
+			t.Errorf("%s: allowed=%v reset=%v retryAfter=%v, want allowed=%v reset=%v retryAfter=%v",
+				tt.name, result.Allowed, result.Reset, result.RetryAfter, tt.allowed, tt.reset, tt.retryAfter)
+		}
+	}
+}
+
+func TestMemoryStoreShardsKeepKeysApart(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+
+	// Enough keys to land in many shards; each gets a bucket of its own
+	shards := make(map[*shard]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("client-%d", i)
+		shards[store.shard(key)] = true
+		if result, _ := store.Take(context.Background(), key, 1, 1, now); !result.Allowed {
+			t.Fatalf("first request of %s rejected", key)
+		}
+	}
+	if len(shards) < memoryShards/2 {
+		t.Errorf("1000 keys used %d of %d shards", len(shards), memoryShards)
+	}
+	if n := store.Len(); n != 1000 {
+		t.Errorf("Len = %d, want 1000", n)
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("client-%d", i)
+		if result, _ := store.Take(context.Background(), key, 1, 1, now); result.Allowed {
+			t.Fatalf("second request of %s allowed", key)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	for i := 0; i < 100; i++ {
+		store.Take(context.Background(), fmt.Sprintf("client-%d", i), 1, 5, now)
+	}
+
+	// Every bucket has refilled by the time its shard is swept, leaving only the new one
+	later := now.Add(sweepInterval + time.Second)
+	for i := 0; i < memoryShards; i++ {
+		store.shards[i].mu.Lock()
+		store.shards[i].sweep(later)
+		store.shards[i].mu.Unlock()
+	}
+	store.Take(context.Background(), "client-0", 1, 5, later)
+	if n := store.Len(); n != 1 {
+		t.Errorf("Len after sweeping = %d, want 1", n)
+	}
+}
+
+func TestLimiterConcurrentAllow(t *testing.T) {
+	// The bucket barely refills during the test, so exactly its burst gets through
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{
+		PolicyWrite: {Requests: 20, Period: time.Hour},
+	})
+
+	const requests = 200
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _, limited := limiter.Allow(context.Background(), PolicyWrite, "client")
+			if !limited {
+				t.Error("request not limited by the write policy")
+			}
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 20 {
+		t.Errorf("%d requests allowed, want 20", allowed)
+	}
+	if rejected := limiter.Rejected(); rejected != requests-20 {
+		t.Errorf("Rejected = %d, want %d", rejected, requests-20)
+	}
+}
+
+func TestLimiterAllowsWithoutPolicy(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{
+		PolicyRead:  {Requests: 0, Period: time.Minute},
+		PolicyWrite: {Requests: 1, Period: time.Minute},
+	})
+
+	for _, name := range []string{PolicyRead, PolicyAuth} {
+		for i := 0; i < 5; i++ {
+			result, _, limited := limiter.Allow(context.Background(), name, "client")
+			if !result.Allowed || limited {
+				t.Errorf("%s request %d: allowed=%v limited=%v, want unlimited", name, i, result.Allowed, limited)
+			}
+		}
+	}
+
+	// Replacing the policies applies to the next request
+	limiter.Allow(context.Background(), PolicyWrite, "client")
+	limiter.SetPolicies(map[string]Policy{})
+	if result, _, limited := limiter.Allow(context.Background(), PolicyWrite, "client"); !result.Allowed || limited {
+		t.Errorf("after removing the policy: allowed=%v limited=%v, want unlimited", result.Allowed, limited)
+	}
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"job-board/backend/config"
+	"job-board/backend/logger"
+)
+
+// Names of the policies applied to route groups
+const (
+	PolicyGlobal     = "global"
+	PolicyRead       = "read"
+	PolicyWrite      = "write"
+	PolicyJobPosting = "job_posting"
+	PolicyAuth       = "auth"
+)
+
+// Policy limits a route group to Requests per Period for each key, allowing bursts of
+// up to Burst requests. Tokens refill continuously, so a client that stays under the
+// rate is never blocked.
+type Policy struct {
+	Requests int
+	Period   time.Duration
+	// Burst is the bucket capacity; zero means Requests
+	Burst int
+}
+
+// Disabled reports whether the policy imposes no limit
+func (p Policy) Disabled() bool {
+	return p.Requests <= 0 || p.Period <= 0
+}
+
+// rate returns the refill rate in tokens per second
+func (p Policy) rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// burst returns the bucket capacity
+func (p Policy) burst() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Requests
+}
+
+// String formats the policy as a RateLimit-Policy header value, e.g. "100;w=60"
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.burst(), int(math.Ceil(p.Period.Seconds())))
+}
+
+// PoliciesFromConfig builds the named policies from configuration; none apply when rate
+// limiting is disabled
+func PoliciesFromConfig(cfg config.RateLimitConfig) map[string]Policy {
+	if !cfg.Enabled {
+		return map[string]Policy{}
+	}
+	policy := func(p config.RateLimitPolicy) Policy {
+		return Policy{Requests: p.Requests, Period: p.Period, Burst: p.Burst}
+	}
+	return map[string]Policy{
+		PolicyGlobal:     policy(cfg.Global),
+		PolicyRead:       policy(cfg.Read),
+		PolicyWrite:      policy(cfg.Write),
+		PolicyJobPosting: policy(cfg.JobPosting),
+		PolicyAuth:       policy(cfg.Auth),
+	}
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a token is available, set when the request is not allowed
+	RetryAfter time.Duration
+}
+
+// Store holds token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes a token from the bucket at key, refilling it at rate tokens per
+	// second up to burst tokens
+	Take(ctx context.Context, key string, rate float64, burst int, now time.Time) (Result, error)
+}
+
+// Limiter applies named policies to keys. Policies can be replaced while requests are
+// being served.
+type Limiter struct {
+	store    Store
+	policies atomic.Pointer[map[string]Policy]
+	rejected atomic.Int64
+}
+
+// NewLimiter creates a limiter backed by store
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	l := &Limiter{store: store}
+	l.SetPolicies(policies)
+	return l
+}
+
+// SetPolicies atomically replaces the limiter's policies
+func (l *Limiter) SetPolicies(policies map[string]Policy) {
+	copied := make(map[string]Policy, len(policies))
+	for name, policy := range policies {
+		copied[name] = policy
+	}
+	l.policies.Store(&copied)
+}
+
+// Policy returns the named policy and whether it exists and limits anything
+func (l *Limiter) Policy(name string) (Policy, bool) {
+	policy, ok := (*l.policies.Load())[name]
+	return policy, ok && !policy.Disabled()
+}
+
+// Allow takes a token for key under the named policy. Requests are allowed when the
+// policy doesn't exist or the store fails, so the limiter never takes the API down.
+func (l *Limiter) Allow(ctx context.Context, name, key string) (Result, Policy, bool) {
+	policy, ok := l.Policy(name)
+	if !ok {
+		return Result{Allowed: true}, policy, false
+	}
+
+	result, err := l.store.Take(ctx, name+":"+key, policy.rate(), policy.burst(), time.Now())
+	if err != nil {
+		logger.ErrorContext(ctx, "Rate limit store failed, allowing request", "policy", name, "error", err)
+		return Result{Allowed: true}, policy, false
+	}
+	if !result.Allowed {
+		l.rejected.Add(1)
+	}
+	return result, policy, true
+}
+
+// Rejected returns how many requests have been rejected
+func (l *Limiter) Rejected() int64 {
+	return l.rejected.Load()
+}
+
+// bucketState computes a token bucket after refilling from last to now and taking a token
+func bucketState(tokens float64, last, now time.Time, rate float64, burst int) (float64, Result) {
+	capacity := float64(burst)
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	result := Result{Limit: burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = seconds((capacity - tokens) / rate)
+	return tokens, result
+}
+
+// seconds converts fractional seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
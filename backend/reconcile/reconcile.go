@@ -0,0 +1,361 @@
+package reconcile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+	"job-board/backend/streaming"
+)
+
+// QuarantineDirectory is the subdirectory of the video directory orphaned files are moved into
+const QuarantineDirectory = ".orphaned"
+
+// IssueKind identifies the kind of drift found between video files and video records
+type IssueKind string
+
+const (
+	// IssueOrphanedFile is a file in the video directory with no video record
+	IssueOrphanedFile IssueKind = "orphaned_file"
+	// IssueMissingFile is a video record whose file does not exist
+	IssueMissingFile IssueKind = "missing_file"
+	// IssueChecksumMissing is a video record with no recorded checksum
+	IssueChecksumMissing IssueKind = "checksum_missing"
+	// IssueChecksumMismatch is a video file whose contents don't match the recorded checksum
+	IssueChecksumMismatch IssueKind = "checksum_mismatch"
+	// IssueReadError is a video file that couldn't be read to verify its checksum
+	IssueReadError IssueKind = "read_error"
+	// IssueExpiredFile is the file of a video soft-deleted longer than the retention period
+	IssueExpiredFile IssueKind = "expired_file"
+)
+
+// Issue describes a single problem found during reconciliation
+type Issue struct {
+	Kind    IssueKind `json:"kind"`
+	VideoID *uint     `json:"videoId,omitempty"`
+	Path    string    `json:"path"`
+	Details string    `json:"details,omitempty"`
+	Fixed   bool      `json:"fixed"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Report summarizes a reconciliation run
+type Report struct {
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+	Fix           bool      `json:"fix"`
+	FilesScanned  int       `json:"filesScanned"`
+	VideosScanned int       `json:"videosScanned"`
+	Issues        []Issue   `json:"issues"`
+}
+
+// Count returns the number of issues of the given kind
+func (r *Report) Count(kind IssueKind) int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Reconciler keeps the video directory and the video records in sync
+type Reconciler struct {
+	videoService    *database.VideoService
+	videoDirectory  string
+	retentionPeriod time.Duration
+
+	mu      sync.Mutex // serializes runs
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewReconciler creates a new reconciler
+func NewReconciler(videoService *database.VideoService, videoDirectory string, retentionPeriod time.Duration) *Reconciler {
+	return &Reconciler{
+		videoService:    videoService,
+		videoDirectory:  videoDirectory,
+		retentionPeriod: retentionPeriod,
+	}
+}
+
+// Run scans the video directory and the video records and reports any drift between them.
+// When fix is true, orphaned files are moved to the quarantine directory, records pointing
+// at missing files are soft-deleted, missing checksums are recorded and files of videos
+// soft-deleted longer than the retention period are purged. Checksum mismatches are only reported.
+func (r *Reconciler) Run(fix bool) (*Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{StartedAt: time.Now(), Fix: fix, Issues: []Issue{}}
+
+	files, err := r.listVideoFiles()
+	if err != nil {
+		return nil, err
+	}
+	report.FilesScanned = len(files)
+
+	videos, err := r.videoService.GetAllVideosIncludingDeleted(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load videos: %w", err)
+	}
+	report.VideosScanned = len(videos)
+
+	// Files still referenced by an active video must never be purged on behalf of a deleted one
+	active := make(map[string]bool, len(videos))
+	for i := range videos {
+		if key, ok := localVideoKey(&videos[i]); ok && !videos[i].DeletedAt.Valid {
+			active[key] = true
+		}
+	}
+
+	known := make(map[string]bool, len(videos))
+	for i := range videos {
+		video := &videos[i]
+		key, ok := localVideoKey(video)
+		if !ok {
+			continue
+		}
+		known[key] = true
+
+		path, exists := files[key]
+		if video.DeletedAt.Valid {
+			if exists && !active[key] && time.Since(video.DeletedAt.Time) > r.retentionPeriod {
+				report.Issues = append(report.Issues, r.purgeExpired(video, path, fix))
+			}
+			continue
+		}
+
+		if !exists {
+			report.Issues = append(report.Issues, r.handleMissing(video, key, fix))
+			continue
+		}
+
+		if issue := r.verifyChecksum(video, path, fix); issue != nil {
+			report.Issues = append(report.Issues, *issue)
+		}
+	}
+
+	for key, path := range files {
+		if known[key] {
+			continue
+		}
+		report.Issues = append(report.Issues, r.quarantineOrphan(path, fix))
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Start runs the reconciler in the background, once right away and then every interval,
+// until Stop is called
+func (r *Reconciler) Start(interval time.Duration, fix bool) {
+	r.stop = make(chan struct{})
+	r.stopped = make(chan struct{})
+
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			report, err := r.Run(fix)
+			if err != nil {
+				logger.Error("Video reconciliation failed", "error", err)
+			} else {
+				logReport(report)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+
+	logger.Info("Video reconciler started", "interval", interval, "fix", fix)
+}
+
+// Stop stops the periodic reconciler and waits for a run in progress to finish
+func (r *Reconciler) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.stopped
+	r.stop = nil
+}
+
+// listVideoFiles returns the video files in the video directory keyed by file name without extension
+func (r *Reconciler) listVideoFiles() (map[string]string, error) {
+	entries, err := os.ReadDir(r.videoDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video directory: %w", err)
+	}
+
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != streaming.VideoFileExtension {
+			continue
+		}
+		key := strings.TrimSuffix(entry.Name(), streaming.VideoFileExtension)
+		files[key] = filepath.Join(r.videoDirectory, entry.Name())
+	}
+	return files, nil
+}
+
+// purgeExpired removes the file of a video soft-deleted longer than the retention period
+func (r *Reconciler) purgeExpired(video *database.Video, path string, fix bool) Issue {
+	issue := Issue{
+		Kind:    IssueExpiredFile,
+		VideoID: &video.ID,
+		Path:    path,
+		Details: fmt.Sprintf("deleted at %s", video.DeletedAt.Time.Format(time.RFC3339)),
+	}
+	if fix {
+		if err := os.Remove(path); err != nil {
+			issue.Error = err.Error()
+		} else {
+			issue.Fixed = true
+		}
+	}
+	return issue
+}
+
+// handleMissing soft-deletes a video record whose file no longer exists
+func (r *Reconciler) handleMissing(video *database.Video, key string, fix bool) Issue {
+	path := streaming.VideoFilePath(r.videoDirectory, key)
+	issue := Issue{Kind: IssueMissingFile, VideoID: &video.ID, Path: path}
+	if fix {
+		if err := r.videoService.DeleteVideo(context.Background(), video.ID); err != nil {
+			issue.Error = err.Error()
+		} else {
+			issue.Fixed = true
+		}
+	}
+	return issue
+}
+
+// verifyChecksum compares a video file against its recorded checksum, recording it if missing
+func (r *Reconciler) verifyChecksum(video *database.Video, path string, fix bool) *Issue {
+	checksum, err := FileChecksum(path)
+	if err != nil {
+		return &Issue{Kind: IssueReadError, VideoID: &video.ID, Path: path, Error: err.Error()}
+	}
+
+	if video.Checksum == nil || *video.Checksum == "" {
+		issue := &Issue{Kind: IssueChecksumMissing, VideoID: &video.ID, Path: path, Details: checksum}
+		if fix {
+			if err := r.videoService.UpdateVideoChecksum(context.Background(), video.ID, checksum); err != nil {
+				issue.Error = err.Error()
+			} else {
+				issue.Fixed = true
+			}
+		}
+		return issue
+	}
+
+	if *video.Checksum != checksum {
+		return &Issue{
+			Kind:    IssueChecksumMismatch,
+			VideoID: &video.ID,
+			Path:    path,
+			Details: fmt.Sprintf("expected %s, got %s", *video.Checksum, checksum),
+		}
+	}
+	return nil
+}
+
+// quarantineOrphan moves a file without a video record into the quarantine directory
+func (r *Reconciler) quarantineOrphan(path string, fix bool) Issue {
+	issue := Issue{Kind: IssueOrphanedFile, Path: path}
+	if !fix {
+		return issue
+	}
+
+	quarantine := filepath.Join(r.videoDirectory, QuarantineDirectory)
+	if err := os.MkdirAll(quarantine, 0o755); err != nil {
+		issue.Error = err.Error()
+		return issue
+	}
+	target, err := quarantineTarget(quarantine, filepath.Base(path))
+	if err != nil {
+		issue.Error = err.Error()
+		return issue
+	}
+	if err := os.Rename(path, target); err != nil {
+		issue.Error = err.Error()
+		return issue
+	}
+	issue.Details = "moved to " + target
+	issue.Fixed = true
+	return issue
+}
+
+// quarantineTarget returns a path in the quarantine directory for a file named name that
+// doesn't replace a file quarantined before, numbering the name if it's taken
+func quarantineTarget(quarantine, name string) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	target := filepath.Join(quarantine, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			return target, nil
+		} else if err != nil {
+			return "", err
+		}
+		target = filepath.Join(quarantine, fmt.Sprintf("%s.%d%s", stem, i, ext))
+	}
+}
+
+// FileChecksum returns the hex-encoded SHA-256 checksum of a file
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// localVideoKey returns the file key of a video streamed from the video directory
+func localVideoKey(video *database.Video) (string, bool) {
+	return streaming.LocalVideoID(video.URL)
+}
+
+// logReport logs a summary of a reconciliation run
+func logReport(report *Report) {
+	logger.Info("Video reconciliation completed",
+		"files", report.FilesScanned,
+		"videos", report.VideosScanned,
+		"issues", len(report.Issues),
+		"orphaned_files", report.Count(IssueOrphanedFile),
+		"missing_files", report.Count(IssueMissingFile),
+		"checksum_missing", report.Count(IssueChecksumMissing),
+		"checksum_mismatch", report.Count(IssueChecksumMismatch),
+		"read_errors", report.Count(IssueReadError),
+		"expired_files", report.Count(IssueExpiredFile),
+		"duration", report.FinishedAt.Sub(report.StartedAt),
+	)
+	for _, issue := range report.Issues {
+		if issue.Error != "" {
+			logger.Warn("Video reconciliation issue could not be fixed", "kind", issue.Kind, "path", issue.Path, "error", issue.Error)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package reconcile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"job-board/backend/database"
+)
+
+func TestQuarantineOrphanKeepsEarlierFiles(t *testing.T) {
+	dir := t.TempDir()
+	r := NewReconciler(nil, dir, 0)
+
+	for i, contents := range []string{"first", "second", "third"} {
+		path := filepath.Join(dir, "orphan.mp4")
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		issue := r.quarantineOrphan(path, true)
+		if !issue.Fixed || issue.Error != "" {
+			t.Fatalf("quarantine %d: fixed=%v error=%q", i, issue.Fixed, issue.Error)
+		}
+	}
+
+	want := map[string]string{
+		"orphan.mp4":   "first",
+		"orphan.1.mp4": "second",
+		"orphan.2.mp4": "third",
+	}
+	for name, contents := range want {
+		data, err := os.ReadFile(filepath.Join(dir, QuarantineDirectory, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if string(data) != contents {
+			t.Errorf("%s = %q, want %q", name, data, contents)
+		}
+	}
+}
+
+func TestQuarantineOrphanReportOnly(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orphan.mp4")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	issue := NewReconciler(nil, dir, 0).quarantineOrphan(path, false)
+	if issue.Kind != IssueOrphanedFile || issue.Fixed {
+		t.Errorf("issue = %+v, want an unfixed orphaned file", issue)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("orphan was moved without fix: %v", err)
+	}
+}
+
+func TestVerifyChecksumReportsReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	// A directory can be opened but not read as a file
+	path := filepath.Join(dir, "unreadable.mp4")
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	issue := NewReconciler(nil, dir, 0).verifyChecksum(&database.Video{ID: 7}, path, false)
+	if issue == nil || issue.Kind != IssueReadError || issue.Error == "" {
+		t.Fatalf("issue = %+v, want a read error", issue)
+	}
+}
@@ -12,6 +12,7 @@ type Response struct {
 	Success   bool        `json:"success"`
 	Data      interface{} `json:"data,omitempty"`
 	Error     *ErrorInfo  `json:"error,omitempty"`
+	Warnings  []ErrorInfo `json:"warnings,omitempty"`
 	Meta      *MetaInfo   `json:"meta,omitempty"`
 	Timestamp string      `json:"timestamp"`
 }
@@ -63,6 +64,16 @@ func (rb *ResponseBuilder) WithError(code int, message, details string) *Respons
 	return rb
 }
 
+// WithWarning adds a warning to the response without marking it as failed
+func (rb *ResponseBuilder) WithWarning(code int, message, details string) *ResponseBuilder {
+	rb.response.Warnings = append(rb.response.Warnings, ErrorInfo{
+		Code:    code,
+		Message: message,
+		Details: details,
+	})
+	return rb
+}
+
 // WithMeta sets the metadata in the response
 func (rb *ResponseBuilder) WithMeta(page, pageSize int, total int64) *ResponseBuilder {
 	rb.response.Meta = &MetaInfo{
@@ -1,39 +1,128 @@
 package routes
 
 import (
-	"job-board/backend/config"
+	"fmt"
+	"time"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
 	"job-board/backend/handlers"
+	"job-board/backend/logger"
 	"job-board/backend/middleware"
+	"job-board/backend/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes configures all routes for the application
-func SetupRoutes(h *handlers.Handler, cfg *config.Config) *gin.Engine {
-	r := gin.Default()
+// SetupRoutes configures all routes for the application. Client IPs, which rate limits,
+// logs and the audit trail rely on, are only taken from X-Forwarded-For and X-Real-IP
+// when the request comes through one of trustedProxies.
+func SetupRoutes(h *handlers.Handler, authService *auth.Service, limiter *ratelimit.Limiter, cors *middleware.CORS, idempotencyService *database.IdempotencyService, idempotencyKeyTTL time.Duration, trustedProxies []string) (*gin.Engine, error) {
+	// Gin's own messages, such as the routes it registers in debug mode, go through the
+	// logger too; requests are logged by LoggerMiddleware
+	gin.DefaultWriter = logger.Writer(logger.DEBUG)
+	gin.DefaultErrorWriter = logger.Writer(logger.ERROR)
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
 	// Add middleware
 	r.Use(middleware.LoggerMiddleware())
+	r.Use(middleware.TracingMiddleware())
+	r.Use(middleware.MetricsMiddleware())
 	r.Use(middleware.RecoveryMiddleware())
 	r.Use(middleware.RequestIDMiddleware())
 	r.Use(middleware.SecurityMiddleware())
-	r.Use(middleware.RateLimitMiddleware())
-	r.Use(middleware.CORSMiddleware())
+	r.Use(cors.Middleware())
+	r.Use(middleware.RateLimitMiddleware(limiter, ratelimit.PolicyGlobal, middleware.KeyByIP))
+	r.Use(middleware.AuthMiddleware(authService))
+	r.Use(middleware.TenantMiddleware())
+	r.Use(middleware.AuditMiddleware())
 
 	// API routes
 	api := r.Group("/api")
+	api.Use(middleware.RateLimitByMethodMiddleware(limiter, ratelimit.PolicyRead, ratelimit.PolicyWrite, middleware.KeyByPrincipal))
+
+	// Credential endpoints are limited per IP to slow down guessing
+	authLimit := middleware.RateLimitMiddleware(limiter, ratelimit.PolicyAuth, middleware.KeyByIP)
+	// Creates that integrations retry are made safe to repeat with an Idempotency-Key header.
+	// Replays run before the job posting limit so they don't count against it.
+	idempotent := middleware.IdempotencyMiddleware(idempotencyService, idempotencyKeyTTL)
 	{
+		// Auth routes
+		api.POST("/auth/register", authLimit, h.Register)
+		api.POST("/auth/login", authLimit, h.Login)
+		api.POST("/auth/refresh", authLimit, h.RefreshToken)
+		api.POST("/auth/logout", middleware.RequireUser(), h.Logout)
+		api.POST("/auth/logout-all", middleware.RequireUser(), h.LogoutAll)
+		api.GET("/auth/me", middleware.RequireUser(), h.Me)
+		api.POST("/auth/2fa/verify", authLimit, h.VerifyTwoFactor)
+		api.POST("/auth/2fa/enroll", middleware.RequireUser(), h.EnrollTwoFactor)
+		api.POST("/auth/2fa/confirm", middleware.RequireUser(), h.ConfirmTwoFactor)
+		api.POST("/auth/2fa/disable", middleware.RequireUser(), h.DisableTwoFactor)
+		api.POST("/auth/2fa/recovery-codes", middleware.RequireUser(), h.RegenerateRecoveryCodes)
+		api.GET("/auth/sso/:company/login", h.SSOLogin)
+		api.GET("/auth/sso/:company/callback", h.SSOCallback)
+
 		// Job routes
 		api.GET("/jobs", h.GetJobs)
 		api.GET("/jobs/:id", h.GetJob)
-		api.POST("/jobs", h.CreateJob)
-		api.PUT("/jobs/:id", h.UpdateJob)
-		api.DELETE("/jobs/:id", h.DeleteJob)
+		api.POST("/jobs", middleware.RequireAuth(), idempotent, middleware.RateLimitMiddleware(limiter, ratelimit.PolicyJobPosting, middleware.KeyByPrincipal), h.CreateJob)
+		api.PUT("/jobs/:id", middleware.RequireAuth(), h.UpdateJob)
+		api.PATCH("/jobs/:id", middleware.RequireAuth(), h.PatchJob)
+		api.DELETE("/jobs/:id", middleware.RequireAuth(), h.DeleteJob)
+		api.POST("/jobs/:id/applications", middleware.RequireAuth(), h.ApplyToJob)
+		api.GET("/jobs/:id/applications", middleware.RequireAuth(), h.GetJobApplications)
+		api.GET("/jobs/:id/revisions", middleware.RequireAuth(), h.GetJobRevisions)
+		api.GET("/jobs/:id/revisions/diff", middleware.RequireAuth(), h.DiffJobRevisions)
+		api.GET("/jobs/:id/revisions/:version", middleware.RequireAuth(), h.GetJobRevision)
+		api.POST("/jobs/:id/revisions/:version/restore", middleware.RequireAuth(), h.RestoreJobRevision)
+
+		// Application routes
+		api.GET("/applications", middleware.RequireAuth(), h.GetApplications)
+		api.GET("/applications/:id", middleware.RequireAuth(), h.GetApplication)
+		api.PUT("/applications/:id/status", middleware.RequireAuth(), h.UpdateApplicationStatus)
 
 		// Video routes
 		api.GET("/videos", h.GetVideos)
 		api.GET("/videos/:id", h.GetVideo)
-		api.POST("/videos", h.CreateVideo)
+		api.POST("/videos", middleware.RequireAuth(), idempotent, h.CreateVideo)
+		api.PUT("/videos/:id/file", middleware.RequireAuth(), h.UploadVideoFile)
+		api.GET("/videos/:id/usage", h.GetVideoUsage)
+
+		// Streaming routes
+		api.GET("/streaming/stats", middleware.RequireUser(), h.GetStreamingStats)
+
+		// Company usage routes
+		api.GET("/companies/:company/usage", h.GetCompanyUsage)
+		api.PUT("/companies/:company/quota", middleware.RequireAuth(), h.UpdateCompanyQuota)
+		api.GET("/companies/:company/members", middleware.RequireAuth(), h.GetCompanyMembers)
+		api.PUT("/companies/:company/members", middleware.RequireUser(), h.SetCompanyMember)
+		api.PUT("/users/:id/role", middleware.RequireUser(), h.SetUserRole)
+
+		api.PUT("/companies/:company/2fa-policy", middleware.RequireUser(), h.UpdateTwoFactorPolicy)
+
+		// Single sign-on configuration routes
+		api.GET("/companies/:company/sso", middleware.RequireUser(), h.GetSSOProvider)
+		api.PUT("/companies/:company/sso", middleware.RequireUser(), h.UpdateSSOProvider)
+		api.DELETE("/companies/:company/sso", middleware.RequireUser(), h.DeleteSSOProvider)
+
+		// API key routes
+		api.POST("/companies/:company/api-keys", middleware.RequireUser(), h.CreateAPIKey)
+		api.GET("/companies/:company/api-keys", middleware.RequireUser(), h.GetAPIKeys)
+		api.DELETE("/companies/:company/api-keys/:id", middleware.RequireUser(), h.RevokeAPIKey)
+
+		// Audit trail routes
+		api.GET("/admin/audit", middleware.RequireUser(), h.GetAuditEntries)
+		api.GET("/admin/audit/export", middleware.RequireUser(), h.ExportAuditEntries)
+
+		// Trash routes
+		api.GET("/admin/trash", middleware.RequireUser(), h.GetTrash)
+		api.POST("/admin/trash/jobs/:id/restore", middleware.RequireUser(), h.RestoreTrashedJob)
+		api.POST("/admin/trash/videos/:id/restore", middleware.RequireUser(), h.RestoreTrashedVideo)
+		api.DELETE("/admin/trash/jobs/:id", middleware.RequireUser(), h.PurgeTrashedJob)
+		api.DELETE("/admin/trash/videos/:id", middleware.RequireUser(), h.PurgeTrashedVideo)
 	}
 
 	// Video streaming route
@@ -46,5 +135,5 @@ func SetupRoutes(h *handlers.Handler, cfg *config.Config) *gin.Engine {
 		c.File("./frontend/build/index.html")
 	})
 
-	return r
+	return r, nil
 }
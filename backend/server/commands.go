@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"job-board/backend/auth"
+	"job-board/backend/database"
+	"job-board/backend/reconcile"
+)
+
+// Reconcile runs a single video reconciliation pass and prints its report
+func (s *Server) Reconcile(args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "repair the problems found instead of only reporting them")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := database.ConnectDatabase(s.config.Database.URL); err != nil {
+		return err
+	}
+	defer database.CloseDatabase()
+	if err := database.MigrateDatabase(); err != nil {
+		return err
+	}
+	if err := prepareVideoDirectory(s.config.Video.Directory); err != nil {
+		return err
+	}
+
+	reconciler := reconcile.NewReconciler(database.NewVideoService(database.DB), s.config.Video.Directory, s.config.Video.RetentionPeriod)
+	report, err := reconciler.Run(*fix)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("Scanned %d files and %d videos in %s\n", report.FilesScanned, report.VideosScanned, report.FinishedAt.Sub(report.StartedAt))
+	for _, issue := range report.Issues {
+		status := "reported"
+		if issue.Fixed {
+			status = "fixed"
+		} else if issue.Error != "" {
+			status = "error: " + issue.Error
+		}
+		line := fmt.Sprintf("%-18s %s", issue.Kind, issue.Path)
+		if issue.VideoID != nil {
+			line += fmt.Sprintf(" (video %d)", *issue.VideoID)
+		}
+		if issue.Details != "" {
+			line += " " + issue.Details
+		}
+		fmt.Printf("%s [%s]\n", line, status)
+	}
+	fmt.Printf("%d issue(s) found\n", len(report.Issues))
+	return nil
+}
+
+// CreateAdmin creates a site admin account, or promotes an existing account to site admin
+func (s *Server) CreateAdmin(args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the admin account")
+	password := flags.String("password", "", "password for a new account (ignored when promoting)")
+	name := flags.String("name", "Administrator", "name for a new account")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	if err := database.ConnectDatabase(s.config.Database.URL); err != nil {
+		return err
+	}
+	defer database.CloseDatabase()
+	if err := database.MigrateDatabase(); err != nil {
+		return err
+	}
+
+	users := database.NewUserService(database.DB)
+	user, err := users.GetUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		if len(*password) < 8 {
+			return fmt.Errorf("-password of at least 8 characters is required for a new account")
+		}
+		hash, err := auth.HashPassword(*password)
+		if err != nil {
+			return err
+		}
+		user = &database.User{Email: *email, Name: *name, PasswordHash: hash, Role: database.RoleSiteAdmin}
+		if err := users.CreateUser(user); err != nil {
+			return err
+		}
+		fmt.Printf("Created site admin %s (user %d)\n", user.Email, user.ID)
+		return nil
+	}
+
+	if _, err := users.SetRole(user.ID, database.RoleSiteAdmin, ""); err != nil {
+		return err
+	}
+	fmt.Printf("Promoted %s (user %d) to site admin\n", user.Email, user.ID)
+	return nil
+}
+
+// Config runs a config subcommand. "print" writes the effective configuration, with secrets
+// redacted, as a YAML config file.
+func (s *Server) Config(args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return fmt.Errorf("usage: job-board [flags] config print")
+	}
+	return s.config.WriteYAML(os.Stdout)
+}
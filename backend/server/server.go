@@ -1,30 +1,112 @@
 package server
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"job-board/backend/auth"
 	"job-board/backend/config"
 	"job-board/backend/database"
+	"job-board/backend/features"
 	"job-board/backend/handlers"
+	"job-board/backend/health"
+	"job-board/backend/idempotency"
+	"job-board/backend/logger"
+	"job-board/backend/metrics"
+	"job-board/backend/middleware"
+	"job-board/backend/quota"
+	"job-board/backend/ratelimit"
+	"job-board/backend/reconcile"
+	"job-board/backend/response"
 	"job-board/backend/routes"
 	"job-board/backend/streaming"
+	"job-board/backend/tracing"
+	"job-board/backend/trash"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+// configWatchInterval is how often the config file is checked for changes
+const configWatchInterval = 2 * time.Second
+
 // Server represents the application server
 type Server struct {
-	config *config.Config
+	config  *config.Config
+	limiter *ratelimit.Limiter
+	cors    *middleware.CORS
+	// ready is set while the server accepts requests and cleared when it starts shutting down
+	ready  atomic.Bool
+	health *health.Checker
 }
 
 // NewServer creates a new server instance
-func NewServer() *Server {
-	return &Server{
-		config: config.LoadConfig(),
+func NewServer(cfg *config.Config) *Server {
+	s := &Server{
+		config:  cfg,
+		limiter: ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.PoliciesFromConfig(cfg.RateLimit)),
+		cors:    middleware.NewCORS(cfg.CORS),
+	}
+	s.applyConfig(cfg)
+	return s
+}
+
+// prepareVideoDirectory creates the video directory if needed and checks that files can be
+// written to it
+func prepareVideoDirectory(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create video directory: %w", err)
+	}
+	if err := health.WritableDir(dir)(context.Background()); err != nil {
+		return fmt.Errorf("video directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// applyConfig applies the settings that can change while the server runs
+func (s *Server) applyConfig(cfg *config.Config) {
+	if level, err := logger.ParseLevel(cfg.Log.Level); err == nil {
+		logger.SetGlobalLevel(level)
 	}
+	features.Set(cfg.Features.Enabled)
+	s.limiter.SetPolicies(ratelimit.PoliciesFromConfig(cfg.RateLimit))
+	s.cors.Update(cfg.CORS)
 }
 
 // Start initializes and starts the server
 func (s *Server) Start() error {
+	// Initialize tracing
+	tracer, err := tracing.Setup(s.config.Tracing)
+	if err != nil {
+		return err
+	}
+
+	// Background workers are stopped on shutdown, after the last request has finished. Until
+	// the server is serving, a failed step stops those already started, closes the database
+	// and then stops the tracer, so nothing is left running or unflushed.
+	var workers []stopper
+	serving := false
+	defer func() {
+		if serving {
+			return
+		}
+		stopWorkers(workers)
+		if err := database.CloseDatabase(); err != nil {
+			logger.Error("Failed to close database", "error", err)
+		}
+		tracer.Stop()
+	}()
+
 	// Initialize database
 	if err := database.ConnectDatabase(s.config.Database.URL); err != nil {
 		return err
@@ -35,6 +117,11 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	// Created here rather than when the config is validated, which also happens on reloads
+	if err := prepareVideoDirectory(s.config.Video.Directory); err != nil {
+		return err
+	}
+
 	// Seed database with sample data
 	if err := database.SeedDatabase(); err != nil {
 		return err
@@ -43,23 +130,281 @@ func (s *Server) Start() error {
 	// Initialize services
 	jobService := database.NewJobService(database.DB)
 	videoService := database.NewVideoService(database.DB)
-	videoStreamer := streaming.NewVideoStreamer(s.config.Video.Directory)
+	companyService := database.NewCompanyService(database.DB)
+	usageService := database.NewUsageService(database.DB)
+	videoStreamer := streaming.NewVideoStreamer(s.config.Video.Directory, streaming.StreamLimits{
+		BytesPerSecond:       s.config.Video.StreamRateLimitBytes,
+		BurstBytes:           s.config.Video.StreamBurstBytes,
+		MaxConcurrentStreams: s.config.Video.MaxConcurrentStreams,
+		RetryAfter:           s.config.Video.StreamRetryAfter,
+	})
+	quotaManager := quota.NewManager(companyService, usageService, s.config.Video.StorageQuotaBytes, s.config.Video.StorageSoftLimitPercent)
+
+	// Start metering streamed bandwidth
+	usageMeter := quota.NewMeter(usageService)
+	usageMeter.Start(s.config.Video.UsageFlushInterval)
+	workers = append(workers, usageMeter)
+
+	// Initialize authentication
+	jwtSecret, err := s.jwtSecret()
+	if err != nil {
+		return err
+	}
+	userService := database.NewUserService(database.DB)
+	tokenManager := auth.NewTokenManager(jwtSecret, s.config.Auth.JWTIssuer, s.config.Auth.AccessTokenTTL)
+	apiKeyService := database.NewAPIKeyService(database.DB)
+	authService := auth.NewService(userService, database.NewTokenService(database.DB), apiKeyService, companyService, tokenManager, s.config.Auth.RefreshTokenTTL)
+
+	ssoService := database.NewSSOService(database.DB)
+	sso := auth.NewSSO(authService, ssoService, jwtSecret, s.config.Auth.SSOCallbackURL, s.config.Auth.SSOSuccessURL, s.config.Auth.SSOAllowPrivateIssuers)
+
+	// Start the periodic video reconciler
+	if s.config.Video.ReconcileInterval > 0 {
+		reconciler := reconcile.NewReconciler(videoService, s.config.Video.Directory, s.config.Video.RetentionPeriod)
+		reconciler.Start(s.config.Video.ReconcileInterval, s.config.Video.ReconcileFix)
+		workers = append(workers, reconciler)
+	}
+
+	// Purge jobs and videos kept in the trash longer than the retention period
+	trashService := database.NewTrashService(database.DB)
+	purger := trash.NewPurger(trashService, s.config.Video.Directory, s.config.Trash.RetentionPeriod)
+	if s.config.Trash.RetentionPeriod > 0 {
+		purger.Start(s.config.Trash.PurgeInterval)
+		workers = append(workers, purger)
+	}
 
 	// Initialize handlers
-	handler := handlers.NewHandler(jobService, videoService, videoStreamer)
+	handler := handlers.NewHandler(handlers.Services{
+		JobService:         jobService,
+		VideoService:       videoService,
+		CompanyService:     companyService,
+		UsageService:       usageService,
+		UserService:        userService,
+		ApplicationService: database.NewApplicationService(database.DB),
+		APIKeyService:      apiKeyService,
+		SSOService:         ssoService,
+		AuditService:       database.NewAuditService(database.DB),
+		TrashService:       trashService,
+		Purger:             purger,
+		VideoStreamer:      videoStreamer,
+		QuotaManager:       quotaManager,
+		UsageMeter:         usageMeter,
+		AuthService:        authService,
+		SSO:                sso,
+	})
+
+	// Delete expired Idempotency-Key responses
+	idempotencyService := database.NewIdempotencyService(database.DB)
+	idempotencyCleaner := idempotency.NewCleaner(idempotencyService)
+	idempotencyCleaner.Start(s.config.Idempotency.CleanupInterval)
+	workers = append(workers, idempotencyCleaner)
 
 	// Setup routes
-	router := routes.SetupRoutes(handler, s.config)
+	router, err := routes.SetupRoutes(handler, authService, s.limiter, s.cors, idempotencyService, s.config.Idempotency.KeyTTL, s.config.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	// Health checks for the orchestrator
+	s.health = health.NewChecker(s.config.Health.CacheTTL, s.config.Health.CheckTimeout)
+	s.health.Add("database", database.PingDatabase)
+	s.health.Add("migrations", database.MigrationStatus)
+	s.health.Add("video_storage", health.WritableDir(s.config.Video.Directory))
+	s.health.Add("usage_meter", usageMeter.Health)
+	router.GET("/healthz", s.liveness)
+	router.GET("/readyz", s.readiness)
 
-	// Create videos directory if it doesn't exist
-	if err := os.MkdirAll(s.config.Video.Directory, 0o755); err != nil {
-		log.Printf("Warning: Failed to create video directory: %v", err)
+	// Prometheus metrics
+	if err := s.registerMetrics(videoStreamer); err != nil {
+		return err
 	}
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Apply changes to the config file, or on SIGHUP, without a restart
+	watcher := config.NewWatcher(s.config, s.applyConfig)
+	watcher.Start(configWatchInterval)
+	workers = append(workers, watcher)
 
 	// Start server
 	addr := s.config.Server.Host + ":" + s.config.Server.Port
-	log.Printf("Server starting on %s", addr)
-	log.Printf("API available at: http://%s/api", addr)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadTimeout:       s.config.Server.ReadTimeout,
+		ReadHeaderTimeout: s.config.Server.ReadHeaderTimeout,
+		WriteTimeout:      s.config.Server.WriteTimeout,
+		IdleTimeout:       s.config.Server.IdleTimeout,
+		MaxHeaderBytes:    s.config.Server.MaxHeaderBytes,
+	}
+	servers := []*http.Server{srv}
+	scheme := "http"
+
+	if s.config.Server.TLSEnabled() {
+		certs, err := newCertReloader(s.config.Server.TLSCertFile, s.config.Server.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		certs.Start(configWatchInterval)
+		workers = append(workers, certs)
+
+		srv.TLSConfig = tlsConfig(s.config.Server, certs)
+		if !s.config.Server.HTTP2 {
+			// A non-nil, empty map turns off the automatic HTTP/2 support
+			srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+		scheme = "https"
+
+		if port := s.config.Server.RedirectHTTPPort; port != "" {
+			servers = append(servers, &http.Server{
+				Addr:              s.config.Server.Host + ":" + port,
+				Handler:           redirectToHTTPS(s.config.Server.Port),
+				ReadHeaderTimeout: s.config.Server.ReadHeaderTimeout,
+				IdleTimeout:       s.config.Server.IdleTimeout,
+				MaxHeaderBytes:    s.config.Server.MaxHeaderBytes,
+			})
+		}
+	} else if s.config.Server.H2C {
+		srv.Handler = h2c.NewHandler(router, &http2.Server{IdleTimeout: s.config.Server.IdleTimeout})
+	}
 
-	return router.Run(addr)
+	// Export the spans of the last requests once they've finished
+	workers = append(workers, tracer)
+	serving = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, len(servers))
+	go func() {
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+	for _, redirect := range servers[1:] {
+		go func(redirect *http.Server) {
+			serveErr <- redirect.ListenAndServe()
+		}(redirect)
+		logger.Info("Redirecting HTTP to HTTPS", "addr", redirect.Addr)
+	}
+	logger.Info("Server starting", "addr", addr, "api", scheme+"://"+addr+"/api")
+	s.ready.Store(true)
+
+	select {
+	case err := <-serveErr:
+		s.ready.Store(false)
+		for _, srv := range servers {
+			srv.Close()
+		}
+		stopWorkers(workers)
+		database.CloseDatabase()
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+		// A second signal interrupts the drain and kills the process
+		stop()
+		logger.Info("Shutdown signal received")
+	}
+	return s.shutdown(servers, workers)
+}
+
+// stopper is a background worker that is stopped on shutdown
+type stopper interface {
+	Stop()
+}
+
+// stopWorkers stops background workers in order
+func stopWorkers(workers []stopper) {
+	for _, worker := range workers {
+		worker.Stop()
+	}
+}
+
+// shutdown reports the server as not ready, lets in-flight requests and video streams finish
+// within the shutdown timeout, then stops the background workers and closes the database
+func (s *Server) shutdown(servers []*http.Server, workers []stopper) error {
+	s.ready.Store(false)
+	if delay := s.config.Server.DrainDelay; delay > 0 {
+		logger.Info("Waiting before draining so load balancers stop sending requests", "delay", delay)
+		time.Sleep(delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
+	defer cancel()
+	logger.Info("Draining connections", "timeout", s.config.Server.ShutdownTimeout)
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				logger.Warn("Requests still running after the shutdown timeout, closing their connections", "addr", srv.Addr, "error", err)
+				srv.Close()
+			}
+		}(srv)
+	}
+	wg.Wait()
+
+	stopWorkers(workers)
+	if err := database.CloseDatabase(); err != nil {
+		logger.Error("Failed to close database", "error", err)
+	}
+	logger.Info("Server stopped")
+	return nil
+}
+
+// liveness handles GET /healthz; it succeeds as long as the process serves requests
+func (s *Server) liveness(c *gin.Context) {
+	response.SuccessResponse(c, http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// readiness handles GET /readyz with the result of each check. It fails when a check
+// fails and once shutdown has begun.
+func (s *Server) readiness(c *gin.Context) {
+	if !s.ready.Load() {
+		response.ErrorResponse(c, http.StatusServiceUnavailable, "Not ready", "Server is shutting down")
+		return
+	}
+
+	report := s.health.Run(c.Request.Context())
+	if !report.Healthy() {
+		response.NewResponseBuilder().
+			WithData(report).
+			WithError(http.StatusServiceUnavailable, "Not ready", "A readiness check failed").
+			Send(c, http.StatusServiceUnavailable)
+		return
+	}
+	response.SuccessResponse(c, http.StatusOK, report)
+}
+
+// registerMetrics exposes the connection pool, video streamer and rate limiter on /metrics
+func (s *Server) registerMetrics(videoStreamer *streaming.VideoStreamer) error {
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database pool: %w", err)
+	}
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		return fmt.Errorf("failed to register database metrics: %w", err)
+	}
+	if err := metrics.RegisterStreamer(videoStreamer); err != nil {
+		return fmt.Errorf("failed to register streaming metrics: %w", err)
+	}
+	if err := metrics.RegisterLimiter(s.limiter); err != nil {
+		return fmt.Errorf("failed to register rate limit metrics: %w", err)
+	}
+	return nil
+}
+
+// jwtSecret returns the configured JWT secret, generating a random one if none is set
+func (s *Server) jwtSecret() ([]byte, error) {
+	if s.config.Auth.JWTSecret != "" {
+		return []byte(s.config.Auth.JWTSecret), nil
+	}
+
+	logger.Warn("JWT_SECRET is not set, using a random secret; access tokens won't survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate JWT secret: %w", err)
+	}
+	return secret, nil
 }
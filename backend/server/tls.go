@@ -0,0 +1,134 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"job-board/backend/config"
+	"job-board/backend/logger"
+)
+
+// certReloader serves a certificate and key pair, reloading them when either file changes
+// so renewed certificates are picked up without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+	modTime  time.Time
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// newCertReloader loads the certificate and key pair
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate; it's used as tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Start checks the files for changes every interval
+func (r *certReloader) Start(interval time.Duration) {
+	r.stop = make(chan struct{})
+	r.stopped = make(chan struct{})
+
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if r.latestModTime().Equal(r.modTime) {
+					continue
+				}
+				// Keep serving the old certificate if the new files are incomplete or invalid
+				if err := r.load(); err != nil {
+					logger.Error("Failed to reload TLS certificate, keeping the current one", "error", err)
+					continue
+				}
+				logger.Info("Reloaded TLS certificate", "cert_file", r.certFile)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops checking for changes
+func (r *certReloader) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.stopped
+	r.stop = nil
+}
+
+// load reads the certificate and key pair
+func (r *certReloader) load() error {
+	modTime := r.latestModTime()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert.Store(&cert)
+	r.modTime = modTime
+	return nil
+}
+
+// latestModTime returns when the certificate or key file last changed
+func (r *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// tlsConfig builds the TLS configuration for the server's settings
+func tlsConfig(cfg config.ServerConfig, certs *certReloader) *tls.Config {
+	tlsCfg := &tls.Config{
+		MinVersion:     config.TLSVersions[cfg.TLSMinVersion],
+		GetCertificate: certs.GetCertificate,
+	}
+	for _, name := range cfg.TLSCipherSuites {
+		if id, ok := config.CipherSuite(name); ok {
+			tlsCfg.CipherSuites = append(tlsCfg.CipherSuites, id)
+		}
+	}
+	return tlsCfg
+}
+
+// redirectToHTTPS redirects requests to the same URL over HTTPS on httpsPort
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,84 @@
+package streaming
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateWindow is the number of seconds bytes per second is averaged over
+const rateWindow = 10
+
+// StreamStats is a snapshot of the streamer's activity
+type StreamStats struct {
+	ActiveStreams        int64   `json:"activeStreams"`
+	MaxConcurrentStreams int     `json:"maxConcurrentStreams"` // 0 means unlimited
+	TotalStreams         int64   `json:"totalStreams"`
+	RejectedStreams      int64   `json:"rejectedStreams"`
+	BytesServed          int64   `json:"bytesServed"`
+	BytesPerSecond       float64 `json:"bytesPerSecond"` // averaged over the last 10 seconds
+}
+
+// streamStats tracks streaming activity
+type streamStats struct {
+	active   atomic.Int64
+	total    atomic.Int64
+	rejected atomic.Int64
+	bytes    atomic.Int64
+
+	mu      sync.Mutex
+	seconds [rateWindow]int64 // unix second each slot was last written in
+	slots   [rateWindow]int64 // bytes written during that second
+}
+
+// acquire reserves a stream slot, failing if max (when positive) streams are already active
+func (s *streamStats) acquire(max int) bool {
+	for {
+		current := s.active.Load()
+		if max > 0 && current >= int64(max) {
+			s.rejected.Add(1)
+			return false
+		}
+		if s.active.CompareAndSwap(current, current+1) {
+			s.total.Add(1)
+			return true
+		}
+	}
+}
+
+// release frees a stream slot
+func (s *streamStats) release() {
+	s.active.Add(-1)
+}
+
+// addBytes records bytes written to a client
+func (s *streamStats) addBytes(n int64) {
+	s.bytes.Add(n)
+
+	now := time.Now().Unix()
+	slot := now % rateWindow
+
+	s.mu.Lock()
+	if s.seconds[slot] != now {
+		s.seconds[slot] = now
+		s.slots[slot] = 0
+	}
+	s.slots[slot] += n
+	s.mu.Unlock()
+}
+
+// bytesPerSecond returns the average throughput over the rate window
+func (s *streamStats) bytesPerSecond() float64 {
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total int64
+	for i := range s.slots {
+		if now-s.seconds[i] < rateWindow {
+			total += s.slots[i]
+		}
+	}
+	return float64(total) / rateWindow
+}
@@ -1,6 +1,9 @@
 package streaming
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,49 +11,146 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"job-board/backend/logger"
+	"job-board/backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// VideoFileExtension is the extension of video files stored in the video directory
+const VideoFileExtension = ".mp4"
+
+// LocalVideoURLPrefix is the URL prefix of videos streamed from the video directory
+const LocalVideoURLPrefix = "/video/"
+
+var (
+	// ErrUploadTooLarge is returned when an upload exceeds the allowed size
+	ErrUploadTooLarge = errors.New("upload exceeds the allowed size")
+	// ErrTooManyStreams is returned when the concurrent stream limit has been reached
+	ErrTooManyStreams = errors.New("too many concurrent streams")
+)
+
+// VideoFilePath returns the path of the file backing the given video ID
+func VideoFilePath(videoDirectory, videoID string) string {
+	return filepath.Join(videoDirectory, videoID+VideoFileExtension)
+}
+
+// LocalVideoID returns the ID of the file backing a video streamed from the video
+// directory. Videos hosted at an external URL are not backed by a local file.
+func LocalVideoID(url string) (string, bool) {
+	if !strings.HasPrefix(url, LocalVideoURLPrefix) {
+		return "", false
+	}
+	videoID := strings.TrimPrefix(url, LocalVideoURLPrefix)
+	return videoID, videoID != "" && !strings.ContainsAny(videoID, "/\\")
+}
+
+// StreamLimits bounds the bandwidth and concurrency of video streaming
+type StreamLimits struct {
+	// BytesPerSecond is the per-connection rate limit (0 means unlimited)
+	BytesPerSecond int64
+	// BurstBytes is how many bytes a connection may send at once (defaults to BytesPerSecond)
+	BurstBytes int64
+	// MaxConcurrentStreams caps the streams served at the same time (0 means unlimited)
+	MaxConcurrentStreams int
+	// RetryAfter is suggested to clients rejected because of the concurrency cap
+	RetryAfter time.Duration
+}
+
 // VideoStreamer handles video streaming operations
 type VideoStreamer struct {
 	videoDirectory string
+	limits         StreamLimits
+	stats          streamStats
 }
 
 // NewVideoStreamer creates a new video streamer
-func NewVideoStreamer(videoDirectory string) *VideoStreamer {
+func NewVideoStreamer(videoDirectory string, limits StreamLimits) *VideoStreamer {
 	return &VideoStreamer{
 		videoDirectory: videoDirectory,
+		limits:         limits,
+	}
+}
+
+// RetryAfter returns how long clients rejected with ErrTooManyStreams should wait
+func (vs *VideoStreamer) RetryAfter() time.Duration {
+	return vs.limits.RetryAfter
+}
+
+// Stats returns a snapshot of the streamer's activity
+func (vs *VideoStreamer) Stats() StreamStats {
+	return StreamStats{
+		ActiveStreams:        vs.stats.active.Load(),
+		MaxConcurrentStreams: vs.limits.MaxConcurrentStreams,
+		TotalStreams:         vs.stats.total.Load(),
+		RejectedStreams:      vs.stats.rejected.Load(),
+		BytesServed:          vs.stats.bytes.Load(),
+		BytesPerSecond:       vs.stats.bytesPerSecond(),
+	}
+}
+
+// responseWriter wraps w so that writes are metered and throttled to the per-connection limit
+func (vs *VideoStreamer) responseWriter(w http.ResponseWriter, r *http.Request) io.Writer {
+	var out io.Writer = &countingWriter{w: w, stats: &vs.stats}
+	if vs.limits.BytesPerSecond > 0 {
+		out = &throttledWriter{
+			ctx:    r.Context(),
+			w:      out,
+			bucket: newTokenBucket(vs.limits.BytesPerSecond, vs.limits.BurstBytes),
+		}
 	}
+	return out
 }
 
-// StreamVideo streams a video file with proper HTTP headers
-func (vs *VideoStreamer) StreamVideo(w http.ResponseWriter, r *http.Request, videoID string) error {
-	videoPath := filepath.Join(vs.videoDirectory, videoID+".mp4")
+// StreamVideo streams a video file with proper HTTP headers and returns the number of bytes
+// written. Only public videos may be kept by shared caches; the others are only streamed to
+// callers allowed to see them, so they must not be stored at all.
+func (vs *VideoStreamer) StreamVideo(w http.ResponseWriter, r *http.Request, videoID string, public bool) (written int64, err error) {
+	ctx, span := tracing.Start(r.Context(), "VideoStreamer.StreamVideo", trace.WithAttributes(attribute.String("video.id", videoID)))
+	defer func() {
+		endStreamSpan(span, written, err)
+	}()
+	r = r.WithContext(ctx)
+
+	if !vs.stats.acquire(vs.limits.MaxConcurrentStreams) {
+		logger.WarnContext(r.Context(), "Concurrent stream limit reached", "video_id", videoID, "max_streams", vs.limits.MaxConcurrentStreams)
+		return 0, ErrTooManyStreams
+	}
+	defer vs.stats.release()
+
+	videoPath := VideoFilePath(vs.videoDirectory, videoID)
 
 	// Check if video file exists
 	fileInfo, err := os.Stat(videoPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			logger.Warn("Video file not found", "video_id", videoID, "path", videoPath)
-			return fmt.Errorf("video not found")
+			logger.WarnContext(r.Context(), "Video file not found", "video_id", videoID, "path", videoPath)
+			return 0, fmt.Errorf("video not found")
 		}
-		logger.Error("Error checking video file", "video_id", videoID, "error", err)
-		return fmt.Errorf("error accessing video file")
+		logger.ErrorContext(r.Context(), "Error checking video file", "video_id", videoID, "error", err)
+		return 0, fmt.Errorf("error accessing video file")
 	}
 
 	// Open the video file
 	file, err := os.Open(videoPath)
 	if err != nil {
-		logger.Error("Error opening video file", "video_id", videoID, "error", err)
-		return fmt.Errorf("error opening video file")
+		logger.ErrorContext(r.Context(), "Error opening video file", "video_id", videoID, "error", err)
+		return 0, fmt.Errorf("error opening video file")
 	}
 	defer file.Close()
 
 	// Set appropriate headers for video streaming
 	w.Header().Set("Content-Type", "video/mp4")
 	w.Header().Set("Accept-Ranges", "bytes")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if public {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
 	w.Header().Set("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
 
 	// Handle range requests for video seeking
@@ -60,29 +160,34 @@ func (vs *VideoStreamer) StreamVideo(w http.ResponseWriter, r *http.Request, vid
 	}
 
 	// Stream the entire file
-	logger.Info("Streaming video", "video_id", videoID, "size", fileInfo.Size())
-	_, err = io.Copy(w, file)
+	logger.InfoContext(r.Context(), "Streaming video", "video_id", videoID, "size", fileInfo.Size())
+	written, err = io.Copy(vs.responseWriter(w, r), file)
 	if err != nil {
-		logger.Error("Error streaming video", "video_id", videoID, "error", err)
-		return fmt.Errorf("error streaming video")
+		logger.ErrorContext(r.Context(), "Error streaming video", "video_id", videoID, "error", err)
+		return written, fmt.Errorf("error streaming video")
 	}
 
-	return nil
+	return written, nil
 }
 
 // handleRangeRequest handles HTTP range requests for video seeking
-func (vs *VideoStreamer) handleRangeRequest(w http.ResponseWriter, r *http.Request, file *os.File, fileSize int64, rangeHeader string) error {
+func (vs *VideoStreamer) handleRangeRequest(w http.ResponseWriter, r *http.Request, file *os.File, fileSize int64, rangeHeader string) (written int64, err error) {
+	_, span := tracing.Start(r.Context(), "VideoStreamer.RangeRead", trace.WithAttributes(attribute.String("http.request.header.range", rangeHeader)))
+	defer func() {
+		endStreamSpan(span, written, err)
+	}()
+
 	// Parse range header (e.g., "bytes=0-1023")
 	rangeStr := strings.TrimPrefix(rangeHeader, "bytes=")
 	parts := strings.Split(rangeStr, "-")
 
 	if len(parts) != 2 {
-		return fmt.Errorf("invalid range header")
+		return 0, fmt.Errorf("invalid range header")
 	}
 
 	start, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid start range")
+		return 0, fmt.Errorf("invalid start range")
 	}
 
 	var end int64
@@ -91,7 +196,7 @@ func (vs *VideoStreamer) handleRangeRequest(w http.ResponseWriter, r *http.Reque
 	} else {
 		end, err = strconv.ParseInt(parts[1], 10, 64)
 		if err != nil {
-			return fmt.Errorf("invalid end range")
+			return 0, fmt.Errorf("invalid end range")
 		}
 	}
 
@@ -99,11 +204,16 @@ func (vs *VideoStreamer) handleRangeRequest(w http.ResponseWriter, r *http.Reque
 	if start < 0 || end >= fileSize || start > end {
 		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
 		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-		return fmt.Errorf("invalid range")
+		return 0, fmt.Errorf("invalid range")
 	}
 
 	// Set range response headers
 	contentLength := end - start + 1
+	span.SetAttributes(
+		attribute.Int64("video.range.start", start),
+		attribute.Int64("video.range.end", end),
+		attribute.Int64("video.size", fileSize),
+	)
 	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, fileSize))
 	w.Header().Set("Content-Length", strconv.FormatInt(contentLength, 10))
 	w.WriteHeader(http.StatusPartialContent)
@@ -111,21 +221,104 @@ func (vs *VideoStreamer) handleRangeRequest(w http.ResponseWriter, r *http.Reque
 	// Seek to start position
 	_, err = file.Seek(start, 0)
 	if err != nil {
-		return fmt.Errorf("error seeking to position")
+		return 0, fmt.Errorf("error seeking to position")
 	}
 
 	// Stream the requested range
-	_, err = io.CopyN(w, file, contentLength)
+	written, err = io.CopyN(vs.responseWriter(w, r), file, contentLength)
 	if err != nil {
-		return fmt.Errorf("error streaming range")
+		return written, fmt.Errorf("error streaming range")
 	}
 
+	return written, nil
+}
+
+// endStreamSpan records the bytes written and any error on a streaming span and ends it
+func endStreamSpan(span trace.Span, written int64, err error) {
+	span.SetAttributes(attribute.Int64("video.bytes_written", written))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StagedVideo is an uploaded video file written to the video directory but not yet in
+// place, so the upload can still be dropped if its record can't be updated
+type StagedVideo struct {
+	// Size and Checksum are the size and hex-encoded SHA-256 checksum of the file
+	Size     int64
+	Checksum string
+
+	directory string
+	path      string
+}
+
+// StageVideo writes an uploaded video file to a temporary file in the video directory,
+// where Store moves it into place. A failed or oversized upload thus never replaces a
+// good file. When maxBytes is positive, uploads larger than it are rejected with
+// ErrUploadTooLarge. The caller must call Discard once done with the staged file.
+func (vs *VideoStreamer) StageVideo(body io.Reader, maxBytes int64) (*StagedVideo, error) {
+	if err := os.MkdirAll(vs.videoDirectory, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating video directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(vs.videoDirectory, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating upload file: %w", err)
+	}
+	staged := &StagedVideo{directory: vs.videoDirectory, path: tmp.Name()}
+
+	reader := body
+	if maxBytes > 0 {
+		// Read one byte past the limit so oversized uploads can be detected
+		reader = io.LimitReader(body, maxBytes+1)
+	}
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), reader)
+	if err == nil && maxBytes > 0 && written > maxBytes {
+		err = ErrUploadTooLarge
+	} else if err != nil {
+		err = fmt.Errorf("error writing upload: %w", err)
+	}
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing upload: %w", closeErr)
+	}
+	if err != nil {
+		staged.Discard()
+		return nil, err
+	}
+
+	staged.Size = written
+	staged.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return staged, nil
+}
+
+// Store moves the staged file into place as the file of videoID, replacing any existing file
+func (s *StagedVideo) Store(videoID string) error {
+	if err := os.Rename(s.path, VideoFilePath(s.directory, videoID)); err != nil {
+		return fmt.Errorf("error storing upload: %w", err)
+	}
+	s.path = ""
+	logger.Info("Stored video upload", "video_id", videoID, "size", s.Size)
 	return nil
 }
 
+// Discard removes the staged file unless it was stored
+func (s *StagedVideo) Discard() {
+	if s.path == "" {
+		return
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove staged video upload", "path", s.path, "error", err)
+	}
+	s.path = ""
+}
+
 // GetVideoInfo returns information about a video file
 func (vs *VideoStreamer) GetVideoInfo(videoID string) (*VideoInfo, error) {
-	videoPath := filepath.Join(vs.videoDirectory, videoID+".mp4")
+	videoPath := VideoFilePath(vs.videoDirectory, videoID)
 
 	fileInfo, err := os.Stat(videoPath)
 	if err != nil {
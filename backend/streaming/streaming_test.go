@@ -0,0 +1,122 @@
+package streaming
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stagedFiles returns the uploads staged in dir and not yet stored or discarded
+func stagedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, ".upload-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestStageVideoStore(t *testing.T) {
+	dir := t.TempDir()
+	vs := NewVideoStreamer(dir, StreamLimits{})
+	if err := os.WriteFile(VideoFilePath(dir, "1"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	staged, err := vs.StageVideo(strings.NewReader("new video"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer staged.Discard()
+	if staged.Size != 9 || len(staged.Checksum) != 64 {
+		t.Errorf("staged size %d, checksum %q", staged.Size, staged.Checksum)
+	}
+
+	// Until it's stored, the current file is untouched
+	if data, _ := os.ReadFile(VideoFilePath(dir, "1")); string(data) != "old" {
+		t.Errorf("file before Store = %q, want the old file", data)
+	}
+	if err := staged.Store("1"); err != nil {
+		t.Fatal(err)
+	}
+	staged.Discard()
+	if data, _ := os.ReadFile(VideoFilePath(dir, "1")); string(data) != "new video" {
+		t.Errorf("file after Store = %q, want the upload", data)
+	}
+	if files := stagedFiles(t, dir); len(files) != 0 {
+		t.Errorf("staged files left behind: %v", files)
+	}
+}
+
+func TestStageVideoDiscard(t *testing.T) {
+	dir := t.TempDir()
+	vs := NewVideoStreamer(dir, StreamLimits{})
+	if err := os.WriteFile(VideoFilePath(dir, "1"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	staged, err := vs.StageVideo(strings.NewReader("rejected"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staged.Discard()
+	if data, _ := os.ReadFile(VideoFilePath(dir, "1")); string(data) != "old" {
+		t.Errorf("file after Discard = %q, want the old file", data)
+	}
+	if files := stagedFiles(t, dir); len(files) != 0 {
+		t.Errorf("staged files left behind: %v", files)
+	}
+}
+
+func TestStageVideoTooLarge(t *testing.T) {
+	dir := t.TempDir()
+	vs := NewVideoStreamer(dir, StreamLimits{})
+
+	if _, err := vs.StageVideo(strings.NewReader("12345"), 4); err != ErrUploadTooLarge {
+		t.Errorf("StageVideo over the limit = %v, want ErrUploadTooLarge", err)
+	}
+	if files := stagedFiles(t, dir); len(files) != 0 {
+		t.Errorf("staged files left behind: %v", files)
+	}
+
+	staged, err := vs.StageVideo(strings.NewReader("1234"), 4)
+	if err != nil {
+		t.Fatalf("StageVideo at the limit: %v", err)
+	}
+	staged.Discard()
+}
+
+func TestStreamVideoCacheControl(t *testing.T) {
+	dir := t.TempDir()
+	vs := NewVideoStreamer(dir, StreamLimits{})
+	if err := os.WriteFile(VideoFilePath(dir, "1"), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		public bool
+		rng    string
+		want   string
+	}{
+		{true, "", "public, max-age=3600"},
+		{true, "bytes=0-1", "public, max-age=3600"},
+		{false, "", "private, no-store"},
+		{false, "bytes=0-1", "private, no-store"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/video/1", nil)
+		if tt.rng != "" {
+			r.Header.Set("Range", tt.rng)
+		}
+		w := httptest.NewRecorder()
+		if _, err := vs.StreamVideo(w, r, "1", tt.public); err != nil {
+			t.Fatalf("StreamVideo(public=%v, range=%q): %v", tt.public, tt.rng, err)
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("Cache-Control (public=%v, range=%q) = %q, want %q", tt.public, tt.rng, got, tt.want)
+		}
+	}
+}
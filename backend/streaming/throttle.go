@@ -0,0 +1,90 @@
+package streaming
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// tokenBucket is a per-connection token bucket where one token is one byte.
+// It is not safe for concurrent use; each stream gets its own bucket.
+type tokenBucket struct {
+	rate   float64 // tokens added per second
+	burst  float64 // bucket capacity
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket
+func newTokenBucket(bytesPerSecond, burstBytes int64) *tokenBucket {
+	if burstBytes <= 0 {
+		burstBytes = bytesPerSecond
+	}
+	return &tokenBucket{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(burstBytes),
+		tokens: float64(burstBytes),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until n tokens are available and takes them. n must not exceed the burst.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	for {
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+
+		if b.tokens >= float64(n) {
+			b.tokens -= float64(n)
+			return nil
+		}
+
+		deficit := float64(n) - b.tokens
+		timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// throttledWriter limits the rate at which bytes are written to the underlying writer
+type throttledWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	bucket *tokenBucket
+}
+
+// Write writes p in chunks no larger than the bucket's burst, waiting for tokens before each
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := min(len(p), int(tw.bucket.burst))
+		if err := tw.bucket.wait(tw.ctx, chunk); err != nil {
+			return written, err
+		}
+		n, err := tw.w.Write(p[:chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[chunk:]
+	}
+	return written, nil
+}
+
+// countingWriter reports every byte written to the streamer's stats
+type countingWriter struct {
+	w     io.Writer
+	stats *streamStats
+}
+
+// Write writes p to the underlying writer and records the bytes written
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.stats.addBytes(int64(n))
+	return n, err
+}
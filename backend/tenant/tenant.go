@@ -0,0 +1,43 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+)
+
+// contextKey is the request context key the tenant is stored under
+type contextKey struct{}
+
+// Tenant describes whose data a request may see. It is derived from the caller in
+// middleware and applied to every query by the database layer.
+type Tenant struct {
+	// Company is the company the caller acts for; empty for candidates and anonymous callers
+	Company string
+	// UserID is the calling user, who may always see their own applications
+	UserID uint
+	// Unrestricted lets site admins see every company's data
+	Unrestricted bool
+}
+
+// IsCompany reports whether the tenant acts for company
+func (t *Tenant) IsCompany(company string) bool {
+	return t.Company != "" && strings.EqualFold(t.Company, company)
+}
+
+// WithTenant returns a copy of ctx carrying the tenant
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant carried by ctx. Contexts without a tenant, such as
+// those of background jobs and CLI commands, are not restricted.
+func FromContext(ctx context.Context) *Tenant {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(contextKey{}).(*Tenant)
+	if t == nil || t.Unrestricted {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GraphQL is a gqlgen handler extension creating a span for each operation and for each
+// field that runs a resolver. Add it with srv.Use(tracing.GraphQL{}).
+type GraphQL struct{}
+
+var (
+	_ graphql.HandlerExtension     = GraphQL{}
+	_ graphql.OperationInterceptor = GraphQL{}
+	_ graphql.FieldInterceptor     = GraphQL{}
+)
+
+// ExtensionName names the extension
+func (GraphQL) ExtensionName() string {
+	return "Tracing"
+}
+
+// Validate accepts any schema
+func (GraphQL) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation creates a span for the operation
+func (GraphQL) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	name := "graphql"
+	if oc.Operation != nil {
+		name = "graphql " + string(oc.Operation.Operation)
+		if oc.OperationName != "" {
+			name += " " + oc.OperationName
+		}
+	}
+	ctx, span := Start(ctx, name, trace.WithAttributes(attribute.String("graphql.operation.name", oc.OperationName)))
+	handler := next(ctx)
+
+	// Queries and mutations produce a single response; a subscription's span ends with its first
+	ended := false
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if !ended {
+			if resp != nil && len(resp.Errors) > 0 {
+				span.SetStatus(codes.Error, resp.Errors.Error())
+			}
+			span.End()
+			ended = true
+		}
+		return resp
+	}
+}
+
+// InterceptField creates a span for fields resolved by a resolver; plain struct fields
+// are skipped since they'd add a span per value without telling anything
+func (GraphQL) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
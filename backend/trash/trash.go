@@ -0,0 +1,164 @@
+package trash
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/logger"
+	"job-board/backend/streaming"
+)
+
+// Purger permanently deletes jobs and videos in the trash along with their video files,
+// and purges those kept longer than the retention period
+type Purger struct {
+	trashService    *database.TrashService
+	videoDirectory  string
+	retentionPeriod time.Duration
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewPurger creates a new purger. A retention period of 0 keeps the trash until it's
+// purged by hand.
+func NewPurger(trashService *database.TrashService, videoDirectory string, retentionPeriod time.Duration) *Purger {
+	return &Purger{
+		trashService:    trashService,
+		videoDirectory:  videoDirectory,
+		retentionPeriod: retentionPeriod,
+	}
+}
+
+// PurgeAt returns when something deleted at deletedAt will be purged, or nil if the
+// trash is kept until it's purged by hand
+func (p *Purger) PurgeAt(deletedAt time.Time) *time.Time {
+	if p.retentionPeriod <= 0 {
+		return nil
+	}
+	purgeAt := deletedAt.Add(p.retentionPeriod)
+	return &purgeAt
+}
+
+// PurgeJob permanently deletes a job in the trash with its videos and their files
+func (p *Purger) PurgeJob(ctx context.Context, id uint) error {
+	urls, err := p.trashService.PurgeJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	p.removeFiles(ctx, urls)
+	logger.InfoContext(ctx, "Purged job", "id", id)
+	return nil
+}
+
+// PurgeVideo permanently deletes a video in the trash and its file
+func (p *Purger) PurgeVideo(ctx context.Context, id uint) error {
+	urls, err := p.trashService.PurgeVideo(ctx, id)
+	if err != nil {
+		return err
+	}
+	p.removeFiles(ctx, urls)
+	logger.InfoContext(ctx, "Purged video", "id", id)
+	return nil
+}
+
+// PurgeExpired purges the jobs and videos deleted longer than the retention period ago
+// and returns how many of each were purged. It keeps going when one can't be purged and
+// returns the first error.
+func (p *Purger) PurgeExpired(ctx context.Context) (int, int, error) {
+	if p.retentionPeriod <= 0 {
+		return 0, 0, nil
+	}
+	before := time.Now().Add(-p.retentionPeriod)
+	var firstErr error
+	record := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// Jobs go first, taking their videos with them
+	jobIDs, err := p.trashService.GetExpiredJobs(ctx, before)
+	if err != nil {
+		return 0, 0, err
+	}
+	jobs := 0
+	for _, id := range jobIDs {
+		if err := p.PurgeJob(ctx, id); err != nil {
+			record(err)
+			continue
+		}
+		jobs++
+	}
+
+	videoIDs, err := p.trashService.GetExpiredVideos(ctx, before)
+	if err != nil {
+		record(err)
+		return jobs, 0, firstErr
+	}
+	videos := 0
+	for _, id := range videoIDs {
+		if err := p.PurgeVideo(ctx, id); err != nil {
+			record(err)
+			continue
+		}
+		videos++
+	}
+	return jobs, videos, firstErr
+}
+
+// removeFiles removes the files backing the given video URLs. The records are already
+// gone, so a file that can't be removed is only logged; the reconciler reports it as
+// orphaned.
+func (p *Purger) removeFiles(ctx context.Context, urls []string) {
+	for _, url := range urls {
+		videoID, ok := streaming.LocalVideoID(url)
+		if !ok {
+			continue
+		}
+		path := streaming.VideoFilePath(p.videoDirectory, videoID)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.WarnContext(ctx, "Failed to remove purged video file", "path", path, "error", err)
+		}
+	}
+}
+
+// Start purges expired jobs and videos every interval in the background until Stop is called
+func (p *Purger) Start(interval time.Duration) {
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				jobs, videos, err := p.PurgeExpired(context.Background())
+				if err != nil {
+					logger.Error("Trash purge failed", "error", err)
+				}
+				if jobs > 0 || videos > 0 {
+					logger.Info("Purged expired trash", "jobs", jobs, "videos", videos)
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	logger.Info("Trash purger started", "interval", interval, "retention", p.retentionPeriod)
+}
+
+// Stop stops the periodic purge and waits for a run in progress to finish
+func (p *Purger) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+	p.stop = nil
+}
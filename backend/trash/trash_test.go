@@ -0,0 +1,140 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"job-board/backend/database"
+	"job-board/backend/streaming"
+
+	"gorm.io/gorm"
+)
+
+// testDatabase connects to and migrates the PostgreSQL database in TEST_DATABASE_URL,
+// skipping the test when it isn't set
+func testDatabase(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := database.ConnectDatabase(dsn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.CloseDatabase() })
+	if err := database.MigrateDatabase(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurgeAt(t *testing.T) {
+	deletedAt := time.Unix(1700000000, 0)
+	if got := NewPurger(nil, "", 0).PurgeAt(deletedAt); got != nil {
+		t.Errorf("PurgeAt without retention = %v, want nil", got)
+	}
+	got := NewPurger(nil, "", 30*24*time.Hour).PurgeAt(deletedAt)
+	if got == nil || !got.Equal(deletedAt.Add(30*24*time.Hour)) {
+		t.Errorf("PurgeAt with 30 days of retention = %v", got)
+	}
+
+	// Without retention nothing expires, so the trash isn't even queried
+	if jobs, videos, err := NewPurger(nil, "", 0).PurgeExpired(context.Background()); jobs != 0 || videos != 0 || err != nil {
+		t.Errorf("PurgeExpired without retention = %d, %d, %v", jobs, videos, err)
+	}
+}
+
+// TestPurgeJob checks that purging a job removes its rows and only then the files no
+// other video uses. It needs a PostgreSQL database in TEST_DATABASE_URL.
+func TestPurgeJob(t *testing.T) {
+	testDatabase(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	jobs := database.NewJobService(database.DB)
+	purger := NewPurger(database.NewTrashService(database.DB), dir, 0)
+
+	company := fmt.Sprintf("Purge %d", time.Now().UnixNano())
+	newJob := func() *database.Job {
+		job := &database.Job{Title: "Engineer", Company: company, Location: "Remote"}
+		if err := jobs.CreateJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.DB.Unscoped().Delete(&database.Job{}, job.ID) })
+		t.Cleanup(func() { database.DB.Where("job_id = ?", job.ID).Delete(&database.JobRevision{}) })
+		t.Cleanup(func() { database.DB.Unscoped().Where("job_id = ?", job.ID).Delete(&database.Video{}) })
+		return job
+	}
+	newVideo := func(job *database.Job, fileID string) *database.Video {
+		video := &database.Video{JobID: job.ID, Title: "Intro", URL: streaming.LocalVideoURLPrefix + fileID}
+		if err := database.DB.Create(video).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(streaming.VideoFilePath(dir, fileID), []byte("video"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return video
+	}
+	fileExists := func(fileID string) bool {
+		_, err := os.Stat(streaming.VideoFilePath(dir, fileID))
+		return err == nil
+	}
+
+	purged := newJob()
+	own := newVideo(purged, "own")
+	shared := newVideo(purged, "shared")
+	other := newJob()
+	newVideo(other, "shared")
+
+	// A job that isn't in the trash can't be purged, and its files stay
+	if err := purger.PurgeJob(ctx, purged.ID); err == nil {
+		t.Fatal("purging a live job succeeded")
+	}
+	if !fileExists("own") || !fileExists("shared") {
+		t.Fatal("a failed purge removed files")
+	}
+
+	// The files must still be there while the rows are being deleted
+	var filesWhileDeleting []bool
+	err := database.DB.Callback().Delete().Before("gorm:delete").Register("test:files", func(tx *gorm.DB) {
+		if tx.Statement.Table == "videos" {
+			filesWhileDeleting = append(filesWhileDeleting, fileExists("own"), fileExists("shared"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.DB.Callback().Delete().Remove("test:files") })
+
+	if err := jobs.DeleteJob(ctx, purged.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	filesWhileDeleting = nil
+	if err := purger.PurgeJob(ctx, purged.ID); err != nil {
+		t.Fatalf("PurgeJob: %v", err)
+	}
+	if len(filesWhileDeleting) == 0 {
+		t.Error("purge deleted no videos")
+	}
+	for _, exists := range filesWhileDeleting {
+		if !exists {
+			t.Error("a file was removed before its video's row was deleted")
+		}
+	}
+
+	var remaining int64
+	database.DB.Unscoped().Model(&database.Video{}).Where("id IN ?", []uint{own.ID, shared.ID}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("%d of the purged job's videos remain", remaining)
+	}
+	if err := database.DB.Unscoped().First(&database.Job{}, purged.ID).Error; err != gorm.ErrRecordNotFound {
+		t.Errorf("purged job lookup = %v, want it gone", err)
+	}
+	if fileExists("own") {
+		t.Error("the purged video's file remains")
+	}
+	if !fileExists("shared") {
+		t.Error("a file another job's video uses was removed")
+	}
+}
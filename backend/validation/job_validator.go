@@ -55,6 +55,11 @@ func (jv *JobValidator) ValidateJob(job *database.Job) error {
 		return err
 	}
 
+	// Validate status (optional, defaults to published)
+	if job.Status != "" && job.Status != database.JobStatusDraft && job.Status != database.JobStatusPublished {
+		return &ValidationError{Field: "status", Message: "must be draft or published"}
+	}
+
 	// Validate video URL (optional)
 	if job.VideoURL != nil && *job.VideoURL != "" {
 		if err := jv.ValidateURL(*job.VideoURL, "videoUrl", false); err != nil {
@@ -0,0 +1,35 @@
+package validation
+
+// MinPasswordLength is the minimum length of a user's password
+const MinPasswordLength = 8
+
+// UserValidator provides validation for user accounts
+type UserValidator struct {
+	*Validator
+}
+
+// NewUserValidator creates a new user validator
+func NewUserValidator() *UserValidator {
+	return &UserValidator{
+		Validator: NewValidator(),
+	}
+}
+
+// ValidateRegistration validates the fields of a new account
+func (uv *UserValidator) ValidateRegistration(email, password, name string) error {
+	// Validate email
+	if err := uv.ValidateEmail(email, "email", true); err != nil {
+		return err
+	}
+
+	// Validate password
+	if len(password) < MinPasswordLength {
+		return &ValidationError{Field: "password", Message: "must be at least 8 characters"}
+	}
+	if err := uv.ValidateString(password, "password", true, 128); err != nil {
+		return err
+	}
+
+	// Validate name
+	return uv.ValidateString(name, "name", false, 100)
+}
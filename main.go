@@ -1,14 +1,63 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"os"
 
+	"job-board/backend/config"
+	"job-board/backend/logger"
 	"job-board/backend/server"
 )
 
 func main() {
-	s := server.NewServer()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("Failed to load configuration: ", err)
+	}
+
+	s := server.NewServer(cfg)
+
+	// Printing the configuration is how problems with it are investigated, so it runs before validation
+	if len(args) > 0 && args[0] == "config" {
+		if err := s.Config(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// From here on everything, including the standard log package, logs in the configured format
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	if err := logger.Setup(cfg.Log.Format, level); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "reconcile":
+			if err := s.Reconcile(args[1:]); err != nil {
+				logger.Fatal("Video reconciliation failed", "error", err)
+			}
+			return
+		case "create-admin":
+			if err := s.CreateAdmin(args[1:]); err != nil {
+				logger.Fatal("Failed to create admin", "error", err)
+			}
+			return
+		default:
+			logger.Fatal("Unknown command", "command", args[0])
+		}
+	}
+
 	if err := s.Start(); err != nil {
-		log.Fatal("Failed to start server:", err)
+		logger.Fatal("Failed to start server", "error", err)
 	}
 }